@@ -69,12 +69,18 @@ export async function createArticle(payload: ArticlePayload, token: string): Pro
   return handleResponse<Article>(response);
 }
 
-export async function updateArticle(id: number, payload: ArticlePayload, token: string): Promise<Article> {
+export async function updateArticle(
+  id: number,
+  payload: ArticlePayload,
+  token: string,
+  version: number
+): Promise<Article> {
   const response = await fetch(
     `${BASE_URL}/${id}`,
     withAuth(
       {
         method: 'PUT',
+        headers: { 'If-Match': `"${version}"` },
         body: JSON.stringify(payload)
       },
       token
@@ -55,7 +55,7 @@ export async function fetchProfile(token: string): Promise<User> {
 
 export async function updateProfile(token: string, payload: ProfileUpdatePayload): Promise<User> {
   const response = await fetch(`${BASE_URL}/me`, {
-    method: 'PUT',
+    method: 'PATCH',
     headers: {
       'Content-Type': 'application/json',
       Authorization: `Bearer ${token}`
@@ -25,6 +25,8 @@ function ArticleEditorPage({ mode }: ArticleEditorPageProps) {
   const [error, setError] = useState<string | null>(null);
   const [saving, setSaving] = useState(false);
   const [viewMode, setViewMode] = useState<ViewMode>('edit');
+  // Версия, на основе которой идёт редактирование; сервер отклонит сохранение поверх чужих правок
+  const [version, setVersion] = useState(0);
   const { token } = useAuth();
 
   useEffect(() => {
@@ -40,6 +42,7 @@ function ArticleEditorPage({ mode }: ArticleEditorPageProps) {
         setLoading(true);
   const preview = await fetchArticlePreview(articleId, token!);
         setForm({ title: preview.title, content: preview.content });
+        setVersion(preview.version ?? 0);
         setError(null);
       } catch (err) {
         setError(err instanceof Error ? err.message : 'Не удалось загрузить статью для редактирования');
@@ -113,7 +116,8 @@ function ArticleEditorPage({ mode }: ArticleEditorPageProps) {
             title: trimmedTitle,
             content: trimmedContent
           },
-          token!
+          token!,
+          version
         );
         navigate(`/article/${article.id}`);
       }
@@ -16,6 +16,8 @@ export interface Article {
   likes: number;
   viewerLiked: boolean;
   author: string;
+  version?: number;
+  tags?: string[];
   created_at: string;
   updated_at: string;
 }
@@ -1,16 +1,20 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
 	"log"
 	"log/slog"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 
 	"github.com/XRS0/blog/services/api-gateway/internal/client"
+	"github.com/XRS0/blog/services/api-gateway/internal/export"
 	"github.com/XRS0/blog/services/api-gateway/internal/handlers"
 	"github.com/XRS0/blog/services/api-gateway/internal/middleware"
 	sharedLogger "github.com/XRS0/blog/shared/logger"
@@ -34,12 +38,56 @@ func main() {
 	}
 	logger.Logger.Info("connected to microservices")
 
+	// Token validation: "remote" asks auth-service on every request, "local"
+	// verifies signatures against the cached JWKS. In local mode a logout or a
+	// revoked session takes effect only when the access token expires
+	// (ACCESS_TOKEN_TTL, 15m); tokens with moderator or admin roles are still
+	// checked remotely, so losing a role takes effect at once
+	var validator middleware.TokenValidator
+	switch mode := getEnv("TOKEN_VALIDATION", "remote"); mode {
+	case "remote":
+		validator = middleware.NewRemoteValidator(clients.Auth)
+	case "local":
+		validator = middleware.NewLocalValidator(clients.Auth, 5*time.Minute)
+	default:
+		log.Fatalf("unknown TOKEN_VALIDATION %q", mode)
+	}
+
 	// Create handlers
 	authHandler := handlers.NewAuthHandler(clients.Auth, logger.Logger)
-	articleHandler := handlers.NewArticleHandler(clients.Article, clients.Stats, logger.Logger)
+	requireVerified := getEnv("REQUIRE_VERIFIED_EMAIL_TO_PUBLISH", "false") == "true"
+	articleHandler := handlers.NewArticleHandler(clients.Article, clients.Stats, requireVerified, logger.Logger)
+	adminHandler := handlers.NewAdminHandler(clients.Auth, logger.Logger)
+	userHandler := handlers.NewUserHandler(clients.Auth, clients.Article, clients.Stats, logger.Logger)
+	searchHandler := handlers.NewSearchHandler(clients.Article, clients.Auth, logger.Logger)
+
+	// Data exports are stored on disk; instances that share EXPORT_DIR must also share EXPORT_SIGNING_KEY
+	exportKey := []byte(getEnv("EXPORT_SIGNING_KEY", ""))
+	if len(exportKey) == 0 {
+		logger.Logger.Warn("EXPORT_SIGNING_KEY is not set, download links will not survive a restart")
+		exportKey = make([]byte, 32)
+		if _, err := rand.Read(exportKey); err != nil {
+			log.Fatalf("failed to generate export signing key: %v", err)
+		}
+	}
+	exporter, err := export.NewExporter(clients, export.Config{
+		Dir:        getEnv("EXPORT_DIR", filepath.Join(os.TempDir(), "blog-exports")),
+		SigningKey: exportKey,
+		TTL:        getDurationEnv("EXPORT_TTL", 24*time.Hour),
+		Workers:    2,
+	}, logger.Logger)
+	if err != nil {
+		log.Fatalf("failed to configure exports: %v", err)
+	}
+	exporter.StartCleanup(context.Background(), time.Hour)
+	exportHandler := handlers.NewExportHandler(exporter, getDurationEnv("EXPORT_LINK_TTL", 15*time.Minute), logger.Logger)
 
 	// Setup router
 	router := gin.Default()
+	// Client IPs are used for login lockout, so only trust forwarding headers from known proxies
+	if err := router.SetTrustedProxies(splitList(getEnv("TRUSTED_PROXIES", ""))); err != nil {
+		log.Fatalf("invalid TRUSTED_PROXIES: %v", err)
+	}
 	router.Use(corsMiddleware())
 
 	// Health check
@@ -47,6 +95,9 @@ func main() {
 		c.JSON(http.StatusOK, gin.H{"status": "ok", "timestamp": time.Now()})
 	})
 
+	// Public keys for verifying access tokens
+	router.GET("/.well-known/jwks.json", authHandler.JWKS)
+
 	// API routes
 	api := router.Group("/api")
 	{
@@ -55,21 +106,110 @@ func main() {
 		{
 			auth.POST("/register", authHandler.Register)
 			auth.POST("/login", authHandler.Login)
-			auth.GET("/me", middleware.RequireAuth(clients.Auth, logger.Logger), authHandler.GetMe)
+			auth.POST("/mfa/verify", authHandler.VerifyMFA)
+			auth.GET("/oidc/providers", authHandler.ListOIDCProviders)
+			auth.GET("/oidc/:provider", authHandler.StartOIDCLogin)
+			auth.POST("/oidc/:provider/callback", authHandler.CompleteOIDCLogin)
+			auth.POST("/refresh", authHandler.Refresh)
+			auth.POST("/logout", authHandler.Logout)
+			auth.POST("/password/forgot", authHandler.ForgotPassword)
+			auth.POST("/password/reset", authHandler.ResetPassword)
+			auth.POST("/verify-email", authHandler.VerifyEmail)
+			auth.POST("/verify-email/resend", middleware.RequireAuth(validator, logger.Logger), authHandler.ResendVerificationEmail)
+			auth.GET("/me", middleware.RequireAuth(validator, logger.Logger, "profile:read"), authHandler.GetMe)
+			auth.PATCH("/me", middleware.RequireAuth(validator, logger.Logger), authHandler.UpdateProfile)
+			auth.POST("/me/password", middleware.RequireAuth(validator, logger.Logger), authHandler.ChangePassword)
+			auth.DELETE("/me", middleware.RequireAuth(validator, logger.Logger), authHandler.DeleteAccount)
+			auth.POST("/account/restore", authHandler.RestoreAccount)
+
+			auth.POST("/mfa/totp/enroll", middleware.RequireAuth(validator, logger.Logger), authHandler.EnrollTOTP)
+			auth.POST("/mfa/totp/confirm", middleware.RequireAuth(validator, logger.Logger), authHandler.ConfirmTOTP)
+			auth.POST("/mfa/totp/disable", middleware.RequireAuth(validator, logger.Logger), authHandler.DisableTOTP)
+
+			// Personal access tokens can only be managed with a session token
+			auth.GET("/tokens", middleware.RequireAuth(validator, logger.Logger), authHandler.ListTokens)
+			auth.POST("/tokens", middleware.RequireAuth(validator, logger.Logger), authHandler.CreateToken)
+			auth.DELETE("/tokens/:id", middleware.RequireAuth(validator, logger.Logger), authHandler.RevokeToken)
+			auth.GET("/sessions", middleware.RequireAuth(validator, logger.Logger), authHandler.ListSessions)
+			auth.DELETE("/sessions/:id", middleware.RequireAuth(validator, logger.Logger), authHandler.RevokeSession)
+			auth.GET("/invites", middleware.RequireAuth(validator, logger.Logger), authHandler.ListInvites)
+			auth.POST("/invites", middleware.RequireAuth(validator, logger.Logger), authHandler.CreateInvite)
+			auth.DELETE("/invites/:id", middleware.RequireAuth(validator, logger.Logger), authHandler.RevokeInvite)
+			auth.GET("/blocks", middleware.RequireAuth(validator, logger.Logger), userHandler.ListBlocked)
+			auth.GET("/mutes", middleware.RequireAuth(validator, logger.Logger), userHandler.ListMuted)
 		}
 
 		// Article routes
 		articles := api.Group("/articles")
 		{
 			// Public routes (with optional auth)
-			articles.GET("", middleware.OptionalAuth(clients.Auth, logger.Logger), articleHandler.ListArticles)
-			articles.GET("/:id", middleware.OptionalAuth(clients.Auth, logger.Logger), articleHandler.GetArticle)
+			articles.GET("", middleware.OptionalAuth(validator, logger.Logger, "articles:read"), articleHandler.ListArticles)
+			articles.GET("/:id", middleware.OptionalAuth(validator, logger.Logger, "articles:read"), articleHandler.GetArticle)
+			articles.GET("/:id/stats", middleware.OptionalAuth(validator, logger.Logger, "stats:read"), articleHandler.GetArticleStats)
 
 			// Protected routes (require auth)
-			articles.POST("", middleware.RequireAuth(clients.Auth, logger.Logger), articleHandler.CreateArticle)
-			articles.PUT("/:id", middleware.RequireAuth(clients.Auth, logger.Logger), articleHandler.UpdateArticle)
-			articles.DELETE("/:id", middleware.RequireAuth(clients.Auth, logger.Logger), articleHandler.DeleteArticle)
-			articles.POST("/:id/like", middleware.RequireAuth(clients.Auth, logger.Logger), articleHandler.LikeArticle)
+			articles.POST("", middleware.RequireAuth(validator, logger.Logger, "articles:write"), articleHandler.CreateArticle)
+			articles.PUT("/:id", middleware.RequireAuth(validator, logger.Logger, "articles:write"), articleHandler.UpdateArticle)
+			articles.DELETE("/:id", middleware.RequireAuth(validator, logger.Logger, "articles:write"), articleHandler.DeleteArticle)
+			articles.POST("/:id/like", middleware.RequireAuth(validator, logger.Logger), articleHandler.LikeArticle)
+
+			// Revision history (author only)
+			articles.GET("/:id/revisions", middleware.RequireAuth(validator, logger.Logger, "articles:read"), articleHandler.ListRevisions)
+			articles.GET("/:id/revisions/:rev", middleware.RequireAuth(validator, logger.Logger, "articles:read"), articleHandler.GetRevision)
+			articles.GET("/:id/revisions/:rev/diff", middleware.RequireAuth(validator, logger.Logger, "articles:read"), articleHandler.DiffRevisions)
+			articles.POST("/:id/revisions/:rev/restore", middleware.RequireAuth(validator, logger.Logger, "articles:write"), articleHandler.RestoreRevision)
+		}
+
+		// Public author profiles and follows
+		users := api.Group("/users")
+		{
+			users.GET("/:username", middleware.OptionalAuth(validator, logger.Logger, "articles:read"), userHandler.GetProfile)
+			users.GET("/:username/followers", userHandler.ListFollowers)
+			users.GET("/:username/following", userHandler.ListFollowing)
+			users.POST("/:username/follow", middleware.RequireAuth(validator, logger.Logger), userHandler.Follow)
+			users.DELETE("/:username/follow", middleware.RequireAuth(validator, logger.Logger), userHandler.Unfollow)
+			users.POST("/:username/block", middleware.RequireAuth(validator, logger.Logger), userHandler.Block)
+			users.DELETE("/:username/block", middleware.RequireAuth(validator, logger.Logger), userHandler.Unblock)
+			users.POST("/:username/mute", middleware.RequireAuth(validator, logger.Logger), userHandler.Mute)
+			users.DELETE("/:username/mute", middleware.RequireAuth(validator, logger.Logger), userHandler.Unmute)
+		}
+
+		// Tags and the articles filed under them
+		tags := api.Group("/tags")
+		{
+			tags.GET("", articleHandler.ListTags)
+			tags.GET("/:slug/articles", middleware.OptionalAuth(validator, logger.Logger, "articles:read"), articleHandler.ListTagArticles)
+		}
+
+		// Full-text search over articles
+		api.GET("/search", middleware.OptionalAuth(validator, logger.Logger, "articles:read"), searchHandler.Search)
+
+		// Personal feed of followed authors
+		api.GET("/feed", middleware.RequireAuth(validator, logger.Logger, "articles:read"), articleHandler.GetFeed)
+
+		// Data export routes
+		exports := api.Group("/exports")
+		{
+			exports.POST("", middleware.RequireAuth(validator, logger.Logger), exportHandler.CreateExport)
+			exports.GET("/:id", middleware.RequireAuth(validator, logger.Logger), exportHandler.GetExport)
+			exports.GET("/:id/download", exportHandler.DownloadExport)
+		}
+
+		// Moderation routes
+		moderation := api.Group("/moderation", middleware.RequireAuth(validator, logger.Logger), middleware.RequireRole("moderator", "admin"))
+		{
+			moderation.POST("/articles/:id/unpublish", articleHandler.UnpublishArticle)
+			moderation.DELETE("/articles/:id", articleHandler.DeleteArticle)
+		}
+
+		// Admin routes
+		admin := api.Group("/admin", middleware.RequireAuth(validator, logger.Logger), middleware.RequireRole("admin"))
+		{
+			admin.GET("/users", adminHandler.ListUsers)
+			admin.PUT("/users/:id/roles", adminHandler.SetUserRoles)
+			admin.POST("/users/:id/unlock", adminHandler.UnlockUser)
+			admin.POST("/keys/rotate", adminHandler.RotateSigningKey)
+			admin.GET("/auth-events", adminHandler.ListAuthEvents)
 		}
 	}
 
@@ -86,8 +226,9 @@ func corsMiddleware() gin.HandlerFunc {
 
 	return func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", allowedOrigin)
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, If-Match")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "ETag")
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
 
 		if c.Request.Method == http.MethodOptions {
@@ -106,6 +247,29 @@ func getEnv(key, fallback string) string {
 	return fallback
 }
 
+func getDurationEnv(key string, fallback time.Duration) time.Duration {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Fatalf("invalid duration for %s: %v", key, err)
+	}
+	return d
+}
+
+// splitList parses a comma-separated list, dropping empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 func parseLogLevel(value string) slog.Leveler {
 	switch strings.ToLower(strings.TrimSpace(value)) {
 	case "debug":
@@ -7,6 +7,7 @@ replace github.com/XRS0/blog/shared => ../../shared
 require (
 	github.com/XRS0/blog/shared v0.0.0-20251014085611-1de8ea239448
 	github.com/gin-gonic/gin v1.11.0
+	github.com/golang-jwt/jwt/v5 v5.3.0
 	google.golang.org/grpc v1.76.0
 	google.golang.org/protobuf v1.36.10
 )
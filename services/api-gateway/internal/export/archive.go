@@ -0,0 +1,295 @@
+package export
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	articlepb "github.com/XRS0/blog/services/api-gateway/proto/article"
+	authpb "github.com/XRS0/blog/services/api-gateway/proto/auth"
+	statspb "github.com/XRS0/blog/services/api-gateway/proto/stats"
+)
+
+// rpcTimeout bounds each call made while collecting an export.
+const rpcTimeout = 30 * time.Second
+
+// activityPageSize is how many likes or views are fetched per call.
+const activityPageSize = 1000
+
+const readme = `# Your data
+
+This archive contains everything the blog stores about your account.
+
+- profile.json: your account
+- sessions.json: devices you are signed in on
+- personal_access_tokens.json: your API tokens (without the secrets)
+- articles.json: all your articles, including private and link-only ones
+- articles/: every article as a Markdown file
+- likes.json: articles you liked
+- views.json: article views recorded while you were signed in
+`
+
+type profileJSON struct {
+	ID            uint64   `json:"id"`
+	Email         string   `json:"email"`
+	Username      string   `json:"username"`
+	EmailVerified bool     `json:"email_verified"`
+	Roles         []string `json:"roles"`
+	CreatedAt     string   `json:"created_at"`
+	UpdatedAt     string   `json:"updated_at"`
+}
+
+type sessionJSON struct {
+	ID         string `json:"id"`
+	UserAgent  string `json:"user_agent"`
+	IP         string `json:"ip"`
+	CreatedAt  string `json:"created_at"`
+	LastSeenAt string `json:"last_seen_at"`
+}
+
+type tokenJSON struct {
+	Name       string   `json:"name"`
+	Prefix     string   `json:"prefix"`
+	Scopes     []string `json:"scopes"`
+	ExpiresAt  string   `json:"expires_at,omitempty"`
+	LastUsedAt string   `json:"last_used_at,omitempty"`
+	CreatedAt  string   `json:"created_at"`
+}
+
+type articleJSON struct {
+	ID          uint64   `json:"id"`
+	Title       string   `json:"title"`
+	Content     string   `json:"content"`
+	Visibility  string   `json:"visibility"`
+	Status      string   `json:"status"`
+	PublishedAt string   `json:"published_at,omitempty"`
+	ScheduledAt string   `json:"scheduled_at,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	CreatedAt   string   `json:"created_at"`
+	UpdatedAt   string   `json:"updated_at"`
+}
+
+type activityJSON struct {
+	ArticleID uint64 `json:"article_id"`
+	CreatedAt string `json:"created_at"`
+}
+
+// collect writes the user's data from all services into the archive.
+func (e *Exporter) collect(ctx context.Context, userID uint64, zw *zip.Writer) error {
+	if err := writeFile(zw, "README.md", []byte(readme)); err != nil {
+		return err
+	}
+
+	steps := []func(context.Context, uint64, *zip.Writer) error{
+		e.collectProfile,
+		e.collectArticles,
+		e.collectLikes,
+		e.collectViews,
+	}
+	for _, step := range steps {
+		if err := step(ctx, userID, zw); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *Exporter) collectProfile(ctx context.Context, userID uint64, zw *zip.Writer) error {
+	callCtx, cancel := context.WithTimeout(ctx, rpcTimeout)
+	defer cancel()
+
+	userResp, err := e.clients.Auth.GetUserByID(callCtx, &authpb.GetUserByIDRequest{Id: userID})
+	if err := rpcError("profile", err, userResp.GetError()); err != nil {
+		return err
+	}
+	user := userResp.User
+	if err := writeJSON(zw, "profile.json", profileJSON{
+		ID:            user.Id,
+		Email:         user.Email,
+		Username:      user.Username,
+		EmailVerified: user.EmailVerified,
+		Roles:         user.Roles,
+		CreatedAt:     formatTime(user.CreatedAt),
+		UpdatedAt:     formatTime(user.UpdatedAt),
+	}); err != nil {
+		return err
+	}
+
+	sessionsResp, err := e.clients.Auth.ListSessions(callCtx, &authpb.ListSessionsRequest{UserId: userID})
+	if err := rpcError("sessions", err, sessionsResp.GetError()); err != nil {
+		return err
+	}
+	sessions := make([]sessionJSON, len(sessionsResp.Sessions))
+	for i, session := range sessionsResp.Sessions {
+		sessions[i] = sessionJSON{
+			ID:         session.Id,
+			UserAgent:  session.UserAgent,
+			IP:         session.Ip,
+			CreatedAt:  formatTime(session.CreatedAt),
+			LastSeenAt: formatTime(session.LastSeenAt),
+		}
+	}
+	if err := writeJSON(zw, "sessions.json", sessions); err != nil {
+		return err
+	}
+
+	tokensResp, err := e.clients.Auth.ListPersonalAccessTokens(callCtx, &authpb.ListPersonalAccessTokensRequest{UserId: userID})
+	if err := rpcError("personal access tokens", err, tokensResp.GetError()); err != nil {
+		return err
+	}
+	tokens := make([]tokenJSON, len(tokensResp.Tokens))
+	for i, token := range tokensResp.Tokens {
+		tokens[i] = tokenJSON{
+			Name:       token.Name,
+			Prefix:     token.Prefix,
+			Scopes:     token.Scopes,
+			ExpiresAt:  formatTime(token.ExpiresAt),
+			LastUsedAt: formatTime(token.LastUsedAt),
+			CreatedAt:  formatTime(token.CreatedAt),
+		}
+	}
+	return writeJSON(zw, "personal_access_tokens.json", tokens)
+}
+
+func (e *Exporter) collectArticles(ctx context.Context, userID uint64, zw *zip.Writer) error {
+	callCtx, cancel := context.WithTimeout(ctx, rpcTimeout)
+	defer cancel()
+
+	// Viewing as the author returns private and link-only articles too
+	resp, err := e.clients.Article.GetArticlesByUser(callCtx, &articlepb.GetArticlesByUserRequest{
+		UserId:   userID,
+		ViewerId: userID,
+	})
+	if err := rpcError("articles", err, resp.GetError()); err != nil {
+		return err
+	}
+
+	articles := make([]articleJSON, len(resp.Articles))
+	for i, article := range resp.Articles {
+		articles[i] = articleJSON{
+			ID:          article.Id,
+			Title:       article.Title,
+			Content:     article.Content,
+			Visibility:  strings.ToLower(article.Visibility.String()),
+			Status:      strings.ToLower(article.Status.String()),
+			PublishedAt: formatTime(article.PublishedAt),
+			ScheduledAt: formatTime(article.ScheduledAt),
+			Tags:        article.Tags,
+			CreatedAt:   formatTime(article.CreatedAt),
+			UpdatedAt:   formatTime(article.UpdatedAt),
+		}
+
+		if err := writeFile(zw, fmt.Sprintf("articles/%d.md", article.Id), []byte(articleMarkdown(articles[i]))); err != nil {
+			return err
+		}
+	}
+	return writeJSON(zw, "articles.json", articles)
+}
+
+func (e *Exporter) collectLikes(ctx context.Context, userID uint64, zw *zip.Writer) error {
+	likes := []activityJSON{}
+	var afterID uint64
+	for {
+		callCtx, cancel := context.WithTimeout(ctx, rpcTimeout)
+		resp, err := e.clients.Stats.ListUserLikes(callCtx, &statspb.ListUserLikesRequest{
+			UserId:  userID,
+			AfterId: afterID,
+			Limit:   activityPageSize,
+		})
+		cancel()
+		if err := rpcError("likes", err, resp.GetError()); err != nil {
+			return err
+		}
+
+		for _, like := range resp.Likes {
+			likes = append(likes, activityJSON{ArticleID: like.ArticleId, CreatedAt: formatTime(like.CreatedAt)})
+			afterID = like.Id
+		}
+		if len(resp.Likes) < activityPageSize {
+			break
+		}
+	}
+	return writeJSON(zw, "likes.json", likes)
+}
+
+func (e *Exporter) collectViews(ctx context.Context, userID uint64, zw *zip.Writer) error {
+	views := []activityJSON{}
+	var afterID uint64
+	for {
+		callCtx, cancel := context.WithTimeout(ctx, rpcTimeout)
+		resp, err := e.clients.Stats.ListUserViews(callCtx, &statspb.ListUserViewsRequest{
+			UserId:  userID,
+			AfterId: afterID,
+			Limit:   activityPageSize,
+		})
+		cancel()
+		if err := rpcError("views", err, resp.GetError()); err != nil {
+			return err
+		}
+
+		for _, view := range resp.Views {
+			views = append(views, activityJSON{ArticleID: view.ArticleId, CreatedAt: formatTime(view.CreatedAt)})
+			afterID = view.Id
+		}
+		if len(resp.Views) < activityPageSize {
+			break
+		}
+	}
+	return writeJSON(zw, "views.json", views)
+}
+
+func articleMarkdown(article articleJSON) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", article.Title)
+	fmt.Fprintf(&b, "- Visibility: %s\n", article.Visibility)
+	fmt.Fprintf(&b, "- Status: %s\n", article.Status)
+	if len(article.Tags) > 0 {
+		fmt.Fprintf(&b, "- Tags: %s\n", strings.Join(article.Tags, ", "))
+	}
+	fmt.Fprintf(&b, "- Created: %s\n", article.CreatedAt)
+	fmt.Fprintf(&b, "- Updated: %s\n\n", article.UpdatedAt)
+	b.WriteString(article.Content)
+	b.WriteString("\n")
+	return b.String()
+}
+
+func rpcError(what string, err error, respErr string) error {
+	if err != nil {
+		return fmt.Errorf("failed to collect %s: %w", what, err)
+	}
+	if respErr != "" {
+		return fmt.Errorf("failed to collect %s: %s", what, respErr)
+	}
+	return nil
+}
+
+func writeJSON(zw *zip.Writer, name string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", name, err)
+	}
+	return writeFile(zw, name, data)
+}
+
+func writeFile(zw *zip.Writer, name string, data []byte) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to add %s: %w", name, err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("failed to add %s: %w", name, err)
+	}
+	return nil
+}
+
+func formatTime(ts *timestamppb.Timestamp) string {
+	if ts == nil || !ts.IsValid() {
+		return ""
+	}
+	return ts.AsTime().Format(time.RFC3339)
+}
@@ -0,0 +1,372 @@
+package export
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/XRS0/blog/services/api-gateway/internal/client"
+)
+
+// Job states.
+const (
+	StatusPending = "pending"
+	StatusRunning = "running"
+	StatusReady   = "ready"
+	StatusFailed  = "failed"
+)
+
+// ErrNotFound is returned for unknown jobs and for jobs of other users.
+var ErrNotFound = errors.New("export not found")
+
+// ErrInvalidLink is returned for download links that are forged or expired.
+var ErrInvalidLink = errors.New("invalid or expired download link")
+
+// Job is one "download my data" request. Jobs and their archives are kept as
+// files in the export directory, so they survive restarts and can be shared by
+// gateway instances that mount the same directory.
+type Job struct {
+	ID          string    `json:"id"`
+	UserID      uint64    `json:"user_id"`
+	Status      string    `json:"status"`
+	Error       string    `json:"error,omitempty"`
+	Size        int64     `json:"size,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	CompletedAt time.Time `json:"completed_at,omitempty"`
+	// ExpiresAt is when the job and its archive are deleted.
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Config holds export settings.
+type Config struct {
+	// Dir stores job files and archives.
+	Dir string
+	// SigningKey signs download links.
+	SigningKey []byte
+	// TTL is how long a finished archive is kept.
+	TTL time.Duration
+	// Workers limits how many exports run at the same time; the rest wait as pending.
+	Workers int
+}
+
+// Exporter collects a user's data from all services into a zip archive in the background.
+type Exporter struct {
+	clients *client.ServiceClients
+	config  Config
+	logger  *slog.Logger
+
+	mu      sync.Mutex
+	workers chan struct{}
+}
+
+// NewExporter creates the export directory and fails jobs that were interrupted
+// by a restart.
+func NewExporter(clients *client.ServiceClients, config Config, logger *slog.Logger) (*Exporter, error) {
+	if err := os.MkdirAll(config.Dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create export directory: %w", err)
+	}
+	if config.Workers <= 0 {
+		config.Workers = 1
+	}
+
+	e := &Exporter{
+		clients: clients,
+		config:  config,
+		logger:  logger,
+		workers: make(chan struct{}, config.Workers),
+	}
+
+	jobs, err := e.listJobs()
+	if err != nil {
+		return nil, err
+	}
+	for _, job := range jobs {
+		if job.Status == StatusPending || job.Status == StatusRunning {
+			e.finish(job, 0, fmt.Errorf("export was interrupted, please start a new one"))
+		}
+	}
+
+	return e, nil
+}
+
+// Start queues an export for the user. If one is already pending or running,
+// that job is returned instead.
+func (e *Exporter) Start(userID uint64) (*Job, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	jobs, err := e.listJobs()
+	if err != nil {
+		return nil, err
+	}
+	for _, job := range jobs {
+		if job.UserID == userID && (job.Status == StatusPending || job.Status == StatusRunning) {
+			return job, nil
+		}
+	}
+
+	id, err := newJobID()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	job := &Job{
+		ID:        id,
+		UserID:    userID,
+		Status:    StatusPending,
+		CreatedAt: now,
+		ExpiresAt: now.Add(e.config.TTL),
+	}
+	if err := e.save(job); err != nil {
+		return nil, err
+	}
+
+	go e.run(job)
+
+	e.logger.Info("data export started", "export_id", job.ID, "user_id", userID)
+	return job, nil
+}
+
+// Get returns a job of the user.
+func (e *Exporter) Get(userID uint64, id string) (*Job, error) {
+	job, err := e.load(id)
+	if err != nil || job.UserID != userID {
+		return nil, ErrNotFound
+	}
+	return job, nil
+}
+
+// DownloadURL returns a link to the archive of a ready job that works without
+// authentication until it expires after ttl, or earlier with the job.
+func (e *Exporter) DownloadURL(job *Job, ttl time.Duration) string {
+	expires := time.Now().Add(ttl)
+	if job.ExpiresAt.Before(expires) {
+		expires = job.ExpiresAt
+	}
+
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expires.Unix(), 10))
+	query.Set("signature", e.sign(job.ID, expires.Unix()))
+	return fmt.Sprintf("/api/exports/%s/download?%s", job.ID, query.Encode())
+}
+
+// Open checks a download link and returns the job and the path of its archive.
+func (e *Exporter) Open(id, expires, signature string) (*Job, string, error) {
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > unix {
+		return nil, "", ErrInvalidLink
+	}
+	if !hmac.Equal([]byte(signature), []byte(e.sign(id, unix))) {
+		return nil, "", ErrInvalidLink
+	}
+
+	job, err := e.load(id)
+	if err != nil || job.Status != StatusReady {
+		return nil, "", ErrInvalidLink
+	}
+	return job, e.archivePath(id), nil
+}
+
+// StartCleanup periodically deletes expired jobs and their archives.
+func (e *Exporter) StartCleanup(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := e.deleteExpired(); err != nil {
+					e.logger.Error("failed to delete expired exports", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+func (e *Exporter) run(job *Job) {
+	e.workers <- struct{}{}
+	defer func() { <-e.workers }()
+
+	job.Status = StatusRunning
+	if err := e.save(job); err != nil {
+		e.logger.Error("failed to update export", "export_id", job.ID, "error", err)
+	}
+
+	size, err := e.build(job)
+	e.finish(job, size, err)
+}
+
+// build writes the archive to a temporary file and moves it into place once complete.
+func (e *Exporter) build(job *Job) (int64, error) {
+	tmp, err := os.CreateTemp(e.config.Dir, job.ID+"-*.tmp")
+	if err != nil {
+		return 0, fmt.Errorf("failed to create archive: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	zw := zip.NewWriter(tmp)
+	if err := e.collect(context.Background(), job.UserID, zw); err != nil {
+		return 0, err
+	}
+	if err := zw.Close(); err != nil {
+		return 0, fmt.Errorf("failed to write archive: %w", err)
+	}
+
+	info, err := tmp.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("failed to write archive: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return 0, fmt.Errorf("failed to write archive: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), e.archivePath(job.ID)); err != nil {
+		return 0, fmt.Errorf("failed to write archive: %w", err)
+	}
+	return info.Size(), nil
+}
+
+func (e *Exporter) finish(job *Job, size int64, err error) {
+	now := time.Now()
+	job.CompletedAt = now
+	job.ExpiresAt = now.Add(e.config.TTL)
+	if err != nil {
+		job.Status = StatusFailed
+		job.Error = err.Error()
+		e.logger.Error("data export failed", "export_id", job.ID, "user_id", job.UserID, "error", err)
+	} else {
+		job.Status = StatusReady
+		job.Size = size
+		e.logger.Info("data export ready", "export_id", job.ID, "user_id", job.UserID, "size", size)
+	}
+
+	if err := e.save(job); err != nil {
+		e.logger.Error("failed to update export", "export_id", job.ID, "error", err)
+	}
+}
+
+func (e *Exporter) deleteExpired() error {
+	jobs, err := e.listJobs()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, job := range jobs {
+		if job.ExpiresAt.After(now) || job.Status == StatusPending || job.Status == StatusRunning {
+			continue
+		}
+		if err := os.Remove(e.archivePath(job.ID)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if err := os.Remove(e.jobPath(job.ID)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *Exporter) listJobs() ([]*Job, error) {
+	entries, err := os.ReadDir(e.config.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list exports: %w", err)
+	}
+
+	var jobs []*Job
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok {
+			continue
+		}
+		job, err := e.load(id)
+		if err != nil {
+			e.logger.Warn("skipping unreadable export", "export_id", id, "error", err)
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+func (e *Exporter) load(id string) (*Job, error) {
+	if !validJobID(id) {
+		return nil, ErrNotFound
+	}
+
+	data, err := os.ReadFile(e.jobPath(id))
+	if err != nil {
+		return nil, err
+	}
+
+	job := new(Job)
+	if err := json.Unmarshal(data, job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// save replaces the job file atomically, so readers never see a partial write.
+func (e *Exporter) save(job *Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+
+	tmp := e.jobPath(job.ID) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to save export: %w", err)
+	}
+	if err := os.Rename(tmp, e.jobPath(job.ID)); err != nil {
+		return fmt.Errorf("failed to save export: %w", err)
+	}
+	return nil
+}
+
+func (e *Exporter) jobPath(id string) string {
+	return filepath.Join(e.config.Dir, id+".json")
+}
+
+func (e *Exporter) archivePath(id string) string {
+	return filepath.Join(e.config.Dir, id+".zip")
+}
+
+func (e *Exporter) sign(id string, expires int64) string {
+	mac := hmac.New(sha256.New, e.config.SigningKey)
+	fmt.Fprintf(mac, "%s:%d", id, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func newJobID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate export id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// validJobID keeps IDs from the URL from escaping the export directory.
+func validJobID(id string) bool {
+	if len(id) != 32 {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	authpb "github.com/XRS0/blog/services/api-gateway/proto/auth"
+)
+
+type deleteAccountRequest struct {
+	Password       string `json:"password"`
+	DeleteArticles bool   `json:"delete_articles"`
+}
+
+type restoreAccountRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// DeleteAccount schedules the caller's account for deletion and signs them out
+// everywhere. Until purge_at the account can be restored with the emailed link.
+func (h *AuthHandler) DeleteAccount(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	var req deleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp, err := h.authClient.DeleteAccount(context.Background(), &authpb.DeleteAccountRequest{
+		UserId:         userID,
+		Password:       req.Password,
+		DeleteArticles: req.DeleteArticles,
+	})
+	if err != nil {
+		h.logger.Error("delete account failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	if resp.Error != "" {
+		if resp.Error == "invalid current password" {
+			c.JSON(http.StatusForbidden, gin.H{"error": resp.Error})
+		} else {
+			c.JSON(http.StatusBadRequest, gin.H{"error": resp.Error})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"purge_at": timestampToString(resp.PurgeAt),
+	})
+}
+
+// RestoreAccount undoes a deletion with the token from the emailed link. The user
+// has to sign in again afterwards.
+func (h *AuthHandler) RestoreAccount(c *gin.Context) {
+	var req restoreAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp, err := h.authClient.RestoreAccount(context.Background(), &authpb.RestoreAccountRequest{
+		Token: req.Token,
+	})
+	if err != nil {
+		h.logger.Error("restore account failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	if resp.Error != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": resp.Error})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user": gin.H{
+			"id":       resp.User.Id,
+			"email":    resp.User.Email,
+			"username": resp.User.Username,
+		},
+	})
+}
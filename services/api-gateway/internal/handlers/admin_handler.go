@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	authpb "github.com/XRS0/blog/services/api-gateway/proto/auth"
+)
+
+// AdminHandler serves user management routes. Routes must be guarded by RequireRole("admin");
+// auth-service re-checks the role of the acting user.
+type AdminHandler struct {
+	authClient authpb.AuthServiceClient
+	logger     *slog.Logger
+}
+
+func NewAdminHandler(authClient authpb.AuthServiceClient, logger *slog.Logger) *AdminHandler {
+	return &AdminHandler{
+		authClient: authClient,
+		logger:     logger,
+	}
+}
+
+type setRolesRequest struct {
+	Roles []string `json:"roles" binding:"required"`
+}
+
+func adminUserJSON(user *authpb.User) gin.H {
+	return gin.H{
+		"id":             user.Id,
+		"email":          user.Email,
+		"username":       user.Username,
+		"roles":          user.Roles,
+		"email_verified": user.EmailVerified,
+		"invited_by":     user.InvitedBy,
+		"created_at":     timestampToString(user.CreatedAt),
+	}
+}
+
+func (h *AdminHandler) ListUsers(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+	resp, err := h.authClient.ListUsers(context.Background(), &authpb.ListUsersRequest{
+		ActorId: getUserID(c),
+		Limit:   int32(limit),
+		Offset:  int32(offset),
+	})
+	if err != nil {
+		h.logger.Error("list users failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	if resp.Error != "" {
+		if resp.Error == "permission denied" {
+			c.JSON(http.StatusForbidden, gin.H{"error": resp.Error})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": resp.Error})
+		}
+		return
+	}
+
+	users := make([]gin.H, len(resp.Users))
+	for i, user := range resp.Users {
+		users[i] = adminUserJSON(user)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"users": users, "total": resp.Total})
+}
+
+func (h *AdminHandler) SetUserRoles(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	var req setRolesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp, err := h.authClient.SetUserRoles(context.Background(), &authpb.SetUserRolesRequest{
+		ActorId: getUserID(c),
+		UserId:  id,
+		Roles:   req.Roles,
+	})
+	if err != nil {
+		h.logger.Error("set user roles failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	if resp.Error != "" {
+		if resp.Error == "permission denied" {
+			c.JSON(http.StatusForbidden, gin.H{"error": resp.Error})
+		} else {
+			c.JSON(http.StatusBadRequest, gin.H{"error": resp.Error})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, adminUserJSON(resp.User))
+}
+
+func (h *AdminHandler) RotateSigningKey(c *gin.Context) {
+	resp, err := h.authClient.RotateSigningKey(context.Background(), &authpb.RotateSigningKeyRequest{
+		ActorId: getUserID(c),
+	})
+	if err != nil {
+		h.logger.Error("rotate signing key failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	if resp.Error != "" {
+		if resp.Error == "permission denied" {
+			c.JSON(http.StatusForbidden, gin.H{"error": resp.Error})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": resp.Error})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"kid": resp.Kid})
+}
+
+// ListAuthEvents returns the security audit log, newest first. Filters: user_id, type
+// (exact or a prefix such as "auth.login"), ip, and since/until in RFC 3339.
+func (h *AdminHandler) ListAuthEvents(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+	req := &authpb.ListAuthEventsRequest{
+		ActorId: getUserID(c),
+		Type:    c.Query("type"),
+		Ip:      c.Query("ip"),
+		Limit:   int32(limit),
+		Offset:  int32(offset),
+	}
+	if v := c.Query("user_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+			return
+		}
+		req.UserId = id
+	}
+	var ok bool
+	if req.Since, ok = parseTimeQuery(c, "since"); !ok {
+		return
+	}
+	if req.Until, ok = parseTimeQuery(c, "until"); !ok {
+		return
+	}
+
+	resp, err := h.authClient.ListAuthEvents(context.Background(), req)
+	if err != nil {
+		h.logger.Error("list auth events failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	if resp.Error != "" {
+		if resp.Error == "permission denied" {
+			c.JSON(http.StatusForbidden, gin.H{"error": resp.Error})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": resp.Error})
+		}
+		return
+	}
+
+	events := make([]gin.H, len(resp.Events))
+	for i, event := range resp.Events {
+		events[i] = gin.H{
+			"id":         event.Id,
+			"type":       event.Type,
+			"user_id":    event.UserId,
+			"ip":         event.Ip,
+			"user_agent": event.UserAgent,
+			"details":    json.RawMessage(event.Details),
+			"created_at": timestampToString(event.CreatedAt),
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"events": events, "total": resp.Total})
+}
+
+// UnlockUser lifts a login lockout caused by failed attempts.
+func (h *AdminHandler) UnlockUser(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	resp, err := h.authClient.UnlockUser(context.Background(), &authpb.UnlockUserRequest{
+		ActorId: getUserID(c),
+		UserId:  id,
+	})
+	if err != nil {
+		h.logger.Error("unlock user failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	if resp.Error != "" {
+		switch resp.Error {
+		case "permission denied":
+			c.JSON(http.StatusForbidden, gin.H{"error": resp.Error})
+		case "user not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": resp.Error})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": resp.Error})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// parseTimeQuery reads an optional RFC 3339 query parameter. It writes a 400 response
+// and returns false when the value is malformed.
+func parseTimeQuery(c *gin.Context, name string) (*timestamppb.Timestamp, bool) {
+	v := c.Query(name)
+	if v == "" {
+		return nil, true
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + name + ": expected RFC 3339 time"})
+		return nil, false
+	}
+	return timestamppb.New(t), true
+}
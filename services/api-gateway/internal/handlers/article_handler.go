@@ -2,9 +2,12 @@ package handlers
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
@@ -25,30 +28,44 @@ type ArticleHandler struct {
 	articleClient articlepb.ArticleServiceClient
 	statsClient   statspb.StatsServiceClient
 	logger        *slog.Logger
+
+	// requireVerifiedToPublish rejects public articles from users with an unverified email.
+	requireVerifiedToPublish bool
 }
 
 func NewArticleHandler(
 	articleClient articlepb.ArticleServiceClient,
 	statsClient statspb.StatsServiceClient,
+	requireVerifiedToPublish bool,
 	logger *slog.Logger,
 ) *ArticleHandler {
 	return &ArticleHandler{
-		articleClient: articleClient,
-		statsClient:   statsClient,
-		logger:        logger,
+		articleClient:            articleClient,
+		statsClient:              statsClient,
+		logger:                   logger,
+		requireVerifiedToPublish: requireVerifiedToPublish,
 	}
 }
 
 type createArticleRequest struct {
-	Title      string `json:"title" binding:"required,min=3"`
-	Content    string `json:"content" binding:"required,min=10"`
-	Visibility string `json:"visibility"` // "public", "private", "link"
+	Title       string     `json:"title" binding:"required,min=3"`
+	Content     string     `json:"content" binding:"required,min=10"`
+	Visibility  string     `json:"visibility"`   // "public", "private", "link"
+	Status      string     `json:"status"`       // "published" (default), "draft", "scheduled"
+	ScheduledAt *time.Time `json:"scheduled_at"` // Required for "scheduled"
+	Tags        []string   `json:"tags"`
 }
 
 type updateArticleRequest struct {
-	Title      string `json:"title" binding:"required,min=3"`
-	Content    string `json:"content" binding:"required,min=10"`
-	Visibility string `json:"visibility"`
+	Title       string     `json:"title" binding:"required,min=3"`
+	Content     string     `json:"content" binding:"required,min=10"`
+	Visibility  string     `json:"visibility"`
+	Status      string     `json:"status"` // Empty keeps the current status; also "archived"
+	ScheduledAt *time.Time `json:"scheduled_at"`
+	Tags        *[]string  `json:"tags"` // Omitted keeps the current tags; [] removes them
+	// ExpectedVersion is the version the edit is based on, for clients that cannot
+	// send If-Match. One of them is required.
+	ExpectedVersion int32 `json:"expected_version"`
 }
 
 type likeRequest struct {
@@ -63,6 +80,23 @@ func getUserID(c *gin.Context) uint64 {
 	return userID.(uint64)
 }
 
+func getRoles(c *gin.Context) []string {
+	return c.GetStringSlice("roles")
+}
+
+func isEmailVerified(c *gin.Context) bool {
+	return c.GetBool("email_verified")
+}
+
+// canPublish reports whether the current user may make an article with the given visibility public.
+// Drafts and archived articles are never public, so they need no verified email.
+func (h *ArticleHandler) canPublish(c *gin.Context, visibility articlepb.Visibility, status *articlepb.ArticleStatus) bool {
+	if status != nil && (*status == articlepb.ArticleStatus_DRAFT || *status == articlepb.ArticleStatus_ARCHIVED) {
+		return true
+	}
+	return !h.requireVerifiedToPublish || visibility != articlepb.Visibility_PUBLIC || isEmailVerified(c)
+}
+
 func visibilityToProto(v string) articlepb.Visibility {
 	switch v {
 	case "private":
@@ -74,6 +108,130 @@ func visibilityToProto(v string) articlepb.Visibility {
 	}
 }
 
+// statusToProto parses a status from a request. Empty means nil, which leaves the
+// choice to the caller; unknown values are an error.
+func statusToProto(v string) (*articlepb.ArticleStatus, error) {
+	var status articlepb.ArticleStatus
+	switch v {
+	case "":
+		return nil, nil
+	case "published":
+		status = articlepb.ArticleStatus_PUBLISHED
+	case "draft":
+		status = articlepb.ArticleStatus_DRAFT
+	case "scheduled":
+		status = articlepb.ArticleStatus_SCHEDULED
+	case "archived":
+		status = articlepb.ArticleStatus_ARCHIVED
+	default:
+		return nil, fmt.Errorf("invalid status")
+	}
+	return &status, nil
+}
+
+func statusFromProto(v articlepb.ArticleStatus) string {
+	switch v {
+	case articlepb.ArticleStatus_DRAFT:
+		return "draft"
+	case articlepb.ArticleStatus_SCHEDULED:
+		return "scheduled"
+	case articlepb.ArticleStatus_ARCHIVED:
+		return "archived"
+	default:
+		return "published"
+	}
+}
+
+// optionalTimestamp converts an optional time from a request.
+func optionalTimestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
+
+// articleETag formats an article version as a strong entity tag.
+func articleETag(version int32) string {
+	return `"` + strconv.Itoa(int(version)) + `"`
+}
+
+// parseIfMatch reads the version from an If-Match header holding a single entity
+// tag. "*" accepts any version and yields 0.
+func parseIfMatch(header string) (int32, error) {
+	header = strings.TrimSpace(header)
+	if header == "*" {
+		return 0, nil
+	}
+	if len(header) < 2 || header[0] != '"' || header[len(header)-1] != '"' {
+		return 0, fmt.Errorf("invalid If-Match header")
+	}
+	version, err := strconv.ParseInt(header[1:len(header)-1], 10, 32)
+	if err != nil || version < 1 {
+		return 0, fmt.Errorf("invalid If-Match header")
+	}
+	return int32(version), nil
+}
+
+// requiredVersion returns the version a write is based on, from the If-Match
+// header or the expected_version body field, so that concurrent edits do not
+// silently overwrite each other. One of them is required. It writes an error
+// response and returns false when they are missing, malformed or disagree.
+func requiredVersion(c *gin.Context, bodyVersion int32) (int32, bool) {
+	ifMatch := c.GetHeader("If-Match")
+	if ifMatch == "" {
+		if bodyVersion == 0 {
+			c.JSON(http.StatusPreconditionRequired, gin.H{"error": "If-Match header or expected_version required"})
+			return 0, false
+		}
+		return bodyVersion, true
+	}
+
+	version, err := parseIfMatch(ifMatch)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return 0, false
+	}
+	if bodyVersion != 0 && version != bodyVersion {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "If-Match and expected_version disagree"})
+		return 0, false
+	}
+	return version, true
+}
+
+// versionConflict answers a write rejected for a stale version with the current
+// article, so that the editor can merge.
+func versionConflict(c *gin.Context, current *articlepb.Article) {
+	// 412 answers a failed If-Match; a body field is a plain conflict
+	code := http.StatusConflict
+	if c.GetHeader("If-Match") != "" {
+		code = http.StatusPreconditionFailed
+	}
+	c.Header("ETag", articleETag(current.Version))
+	c.JSON(code, gin.H{
+		"error":           "version conflict",
+		"current_version": current.Version,
+		"article": gin.H{
+			"id":         current.Id,
+			"title":      current.Title,
+			"content":    current.Content,
+			"visibility": visibilityFromProto(current.Visibility),
+			"status":     statusFromProto(current.Status),
+			"tags":       articleTags(current),
+			"version":    current.Version,
+			"updated_at": timestampToString(current.UpdatedAt),
+		},
+	})
+}
+
+// articleTags returns the tags of an article, never nil, so that JSON shows
+// an empty list rather than null.
+func articleTags(article *articlepb.Article) []string {
+	if article.Tags == nil {
+		return []string{}
+	}
+	return article.Tags
+}
+
 func visibilityFromProto(v articlepb.Visibility) string {
 	switch v {
 	case articlepb.Visibility_PRIVATE:
@@ -99,12 +257,27 @@ func (h *ArticleHandler) CreateArticle(c *gin.Context) {
 	}
 
 	visibility := visibilityToProto(req.Visibility)
+	if req.Status == "" {
+		req.Status = "published"
+	}
+	status, err := statusToProto(req.Status)
+	if err != nil || *status == articlepb.ArticleStatus_ARCHIVED {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status"})
+		return
+	}
+	if !h.canPublish(c, visibility, status) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "verify your email to publish public articles"})
+		return
+	}
 
 	resp, err := h.articleClient.CreateArticle(context.Background(), &articlepb.CreateArticleRequest{
-		UserId:     userID,
-		Title:      req.Title,
-		Content:    req.Content,
-		Visibility: visibility,
+		UserId:      userID,
+		Title:       req.Title,
+		Content:     req.Content,
+		Visibility:  visibility,
+		Status:      *status,
+		ScheduledAt: optionalTimestamp(req.ScheduledAt),
+		Tags:        req.Tags,
 	})
 	if err != nil {
 		h.logger.Error("create article failed", "error", err)
@@ -119,12 +292,17 @@ func (h *ArticleHandler) CreateArticle(c *gin.Context) {
 
 	article := resp.Article
 	result := gin.H{
-		"id":         article.Id,
-		"title":      article.Title,
-		"content":    article.Content,
-		"visibility": visibilityFromProto(article.Visibility),
-		"created_at": timestampToString(article.CreatedAt),
-		"updated_at": timestampToString(article.UpdatedAt),
+		"id":           article.Id,
+		"title":        article.Title,
+		"content":      article.Content,
+		"visibility":   visibilityFromProto(article.Visibility),
+		"status":       statusFromProto(article.Status),
+		"published_at": timestampToString(article.PublishedAt),
+		"scheduled_at": timestampToString(article.ScheduledAt),
+		"tags":         articleTags(article),
+		"version":      article.Version,
+		"created_at":   timestampToString(article.CreatedAt),
+		"updated_at":   timestampToString(article.UpdatedAt),
 	}
 
 	// Add access token for link visibility
@@ -133,6 +311,7 @@ func (h *ArticleHandler) CreateArticle(c *gin.Context) {
 		result["access_url"] = "/articles/" + strconv.FormatUint(article.Id, 10) + "?access_token=" + article.AccessToken
 	}
 
+	c.Header("ETag", articleETag(article.Version))
 	c.JSON(http.StatusCreated, result)
 }
 
@@ -147,10 +326,12 @@ func (h *ArticleHandler) GetArticle(c *gin.Context) {
 	userID := getUserID(c)
 	accessToken := c.Query("access_token")
 
+	// Authors open their articles with preview=true in the editor; that is not a view
 	resp, err := h.articleClient.GetArticle(context.Background(), &articlepb.GetArticleRequest{
 		Id:          id,
 		ViewerId:    userID,
 		AccessToken: accessToken,
+		Preview:     c.Query("preview") == "true",
 	})
 	if err != nil {
 		h.logger.Error("get article failed", "error", err)
@@ -193,17 +374,23 @@ func (h *ArticleHandler) GetArticle(c *gin.Context) {
 	}
 
 	article := resp.Article
+	c.Header("ETag", articleETag(article.Version))
 	c.JSON(http.StatusOK, gin.H{
-		"id":          article.Id,
-		"title":       article.Title,
-		"content":     article.Content,
-		"visibility":  visibilityFromProto(article.Visibility),
-		"author":      resp.AuthorUsername,
-		"views":       views,
-		"likes":       likes,
-		"viewerLiked": viewerLiked,
-		"created_at":  timestampToString(article.CreatedAt),
-		"updated_at":  timestampToString(article.UpdatedAt),
+		"id":           article.Id,
+		"title":        article.Title,
+		"content":      article.Content,
+		"visibility":   visibilityFromProto(article.Visibility),
+		"author":       resp.AuthorUsername,
+		"views":        views,
+		"likes":        likes,
+		"viewerLiked":  viewerLiked,
+		"status":       statusFromProto(article.Status),
+		"published_at": timestampToString(article.PublishedAt),
+		"scheduled_at": timestampToString(article.ScheduledAt),
+		"tags":         articleTags(article),
+		"version":      article.Version,
+		"created_at":   timestampToString(article.CreatedAt),
+		"updated_at":   timestampToString(article.UpdatedAt),
 	})
 }
 
@@ -226,57 +413,7 @@ func (h *ArticleHandler) ListArticles(c *gin.Context) {
 		return
 	}
 
-	// Get stats for all articles
-	articleIDs := make([]uint64, len(resp.Articles))
-	for i, article := range resp.Articles {
-		articleIDs[i] = article.Id
-	}
-
-	var statsMap map[uint64]*statspb.ArticleStatsWithLike
-	if len(articleIDs) > 0 {
-		statsResp, err := h.statsClient.GetArticlesWithStats(context.Background(), &statspb.GetArticlesWithStatsRequest{
-			ArticleIds: articleIDs,
-			ViewerId:   userID,
-		})
-		if err == nil && statsResp.Error == "" {
-			statsMap = make(map[uint64]*statspb.ArticleStatsWithLike)
-			for _, stat := range statsResp.Stats {
-				statsMap[stat.ArticleId] = stat
-			}
-		}
-	}
-
-	articles := make([]gin.H, len(resp.Articles))
-	for i, article := range resp.Articles {
-		var views, likes uint64
-		var viewerLiked bool
-
-		if stat, ok := statsMap[article.Id]; ok {
-			views = stat.Views
-			likes = stat.Likes
-			viewerLiked = stat.ViewerLiked
-		}
-
-		author := ""
-		if i < len(resp.AuthorUsernames) {
-			author = resp.AuthorUsernames[i]
-		}
-
-		articles[i] = gin.H{
-			"id":          article.Id,
-			"title":       article.Title,
-			"content":     article.Content,
-			"visibility":  visibilityFromProto(article.Visibility),
-			"author":      author,
-			"views":       views,
-			"likes":       likes,
-			"viewerLiked": viewerLiked,
-			"created_at":  timestampToString(article.CreatedAt),
-			"updated_at":  timestampToString(article.UpdatedAt),
-		}
-	}
-
-	c.JSON(http.StatusOK, articles)
+	c.JSON(http.StatusOK, h.articleSummaries(resp.Articles, resp.AuthorUsernames, userID))
 }
 
 func (h *ArticleHandler) UpdateArticle(c *gin.Context) {
@@ -299,14 +436,39 @@ func (h *ArticleHandler) UpdateArticle(c *gin.Context) {
 		return
 	}
 
+	expectedVersion, ok := requiredVersion(c, req.ExpectedVersion)
+	if !ok {
+		return
+	}
+
+	var tags []string
+	if req.Tags != nil {
+		tags = *req.Tags
+	}
+
 	visibility := visibilityToProto(req.Visibility)
+	status, err := statusToProto(req.Status)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !h.canPublish(c, visibility, status) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "verify your email to publish public articles"})
+		return
+	}
 
 	resp, err := h.articleClient.UpdateArticle(context.Background(), &articlepb.UpdateArticleRequest{
-		Id:         id,
-		UserId:     userID,
-		Title:      req.Title,
-		Content:    req.Content,
-		Visibility: visibility,
+		Id:              id,
+		UserId:          userID,
+		Title:           req.Title,
+		Content:         req.Content,
+		Visibility:      visibility,
+		Status:          status,
+		ScheduledAt:     optionalTimestamp(req.ScheduledAt),
+		Tags:            tags,
+		ReplaceTags:     req.Tags != nil,
+		ExpectedVersion: expectedVersion,
+		Roles:           getRoles(c),
 	})
 	if err != nil {
 		h.logger.Error("update article failed", "error", err)
@@ -315,9 +477,16 @@ func (h *ArticleHandler) UpdateArticle(c *gin.Context) {
 	}
 
 	if resp.Error != "" {
-		if resp.Error == "unauthorized" {
+		switch resp.Error {
+		case "unauthorized":
 			c.JSON(http.StatusForbidden, gin.H{"error": "unauthorized"})
-		} else {
+		case "article was archived by a moderator":
+			c.JSON(http.StatusForbidden, gin.H{"error": resp.Error})
+		case "article not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": resp.Error})
+		case "version conflict":
+			versionConflict(c, resp.Article)
+		default:
 			c.JSON(http.StatusBadRequest, gin.H{"error": resp.Error})
 		}
 		return
@@ -325,12 +494,17 @@ func (h *ArticleHandler) UpdateArticle(c *gin.Context) {
 
 	article := resp.Article
 	result := gin.H{
-		"id":         article.Id,
-		"title":      article.Title,
-		"content":    article.Content,
-		"visibility": visibilityFromProto(article.Visibility),
-		"created_at": timestampToString(article.CreatedAt),
-		"updated_at": timestampToString(article.UpdatedAt),
+		"id":           article.Id,
+		"title":        article.Title,
+		"content":      article.Content,
+		"visibility":   visibilityFromProto(article.Visibility),
+		"status":       statusFromProto(article.Status),
+		"published_at": timestampToString(article.PublishedAt),
+		"scheduled_at": timestampToString(article.ScheduledAt),
+		"tags":         articleTags(article),
+		"version":      article.Version,
+		"created_at":   timestampToString(article.CreatedAt),
+		"updated_at":   timestampToString(article.UpdatedAt),
 	}
 
 	// Add access token for link visibility
@@ -339,6 +513,7 @@ func (h *ArticleHandler) UpdateArticle(c *gin.Context) {
 		result["access_url"] = "/articles/" + strconv.FormatUint(article.Id, 10) + "?access_token=" + article.AccessToken
 	}
 
+	c.Header("ETag", articleETag(article.Version))
 	c.JSON(http.StatusOK, result)
 }
 
@@ -359,6 +534,7 @@ func (h *ArticleHandler) DeleteArticle(c *gin.Context) {
 	resp, err := h.articleClient.DeleteArticle(context.Background(), &articlepb.DeleteArticleRequest{
 		Id:     id,
 		UserId: userID,
+		Roles:  getRoles(c),
 	})
 	if err != nil {
 		h.logger.Error("delete article failed", "error", err)
@@ -378,6 +554,46 @@ func (h *ArticleHandler) DeleteArticle(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"success": true})
 }
 
+// UnpublishArticle archives any article, hiding it from everyone but its author. Moderators only.
+func (h *ArticleHandler) UnpublishArticle(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid article id"})
+		return
+	}
+
+	resp, err := h.articleClient.UnpublishArticle(context.Background(), &articlepb.UnpublishArticleRequest{
+		Id:     id,
+		UserId: getUserID(c),
+		Roles:  getRoles(c),
+	})
+	if err != nil {
+		h.logger.Error("unpublish article failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	if resp.Error != "" {
+		switch resp.Error {
+		case "unauthorized":
+			c.JSON(http.StatusForbidden, gin.H{"error": "unauthorized"})
+		case "article not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": "article not found"})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": resp.Error})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":         resp.Article.Id,
+		"visibility": visibilityFromProto(resp.Article.Visibility),
+		"status":     statusFromProto(resp.Article.Status),
+		"updated_at": timestampToString(resp.Article.UpdatedAt),
+	})
+}
+
 func (h *ArticleHandler) LikeArticle(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 64)
@@ -404,11 +620,20 @@ func (h *ArticleHandler) LikeArticle(c *gin.Context) {
 			ArticleId: id,
 			UserId:    userID,
 		})
-		if err != nil || !resp.Success {
+		if err != nil {
 			h.logger.Error("record like failed", "error", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
 			return
 		}
+		if resp.Error == "blocked by the author" {
+			c.JSON(http.StatusForbidden, gin.H{"error": resp.Error})
+			return
+		}
+		if !resp.Success {
+			h.logger.Error("record like failed", "response_error", resp.Error)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+			return
+		}
 	} else {
 		// Remove like
 		resp, err := h.statsClient.RemoveLike(context.Background(), &statspb.RemoveLikeRequest{
@@ -436,3 +661,136 @@ func (h *ArticleHandler) LikeArticle(c *gin.Context) {
 		"likes":   statsResp.Stats.Likes,
 	})
 }
+
+// GetArticleStats returns view and like counters without recording a view.
+func (h *ArticleHandler) GetArticleStats(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid article id"})
+		return
+	}
+
+	accessResp, err := h.articleClient.CheckArticleAccess(context.Background(), &articlepb.CheckArticleAccessRequest{
+		ArticleId:   id,
+		ViewerId:    getUserID(c),
+		AccessToken: c.Query("access_token"),
+	})
+	if err != nil {
+		h.logger.Error("check article access failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+	if !accessResp.HasAccess {
+		c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+		return
+	}
+
+	statsResp, err := h.statsClient.GetArticleStats(context.Background(), &statspb.GetArticleStatsRequest{
+		ArticleId: id,
+	})
+	if err != nil || statsResp.Error != "" {
+		h.logger.Error("get article stats failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"article_id": id,
+		"views":      statsResp.Stats.Views,
+		"likes":      statsResp.Stats.Likes,
+	})
+}
+
+// GetFeed returns new public articles of the authors the user follows, newest
+// first. Pass next_before from the response as before to get the next page.
+func (h *ArticleHandler) GetFeed(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	before, _ := strconv.ParseUint(c.DefaultQuery("before", "0"), 10, 64)
+
+	resp, err := h.articleClient.GetFeed(context.Background(), &articlepb.GetFeedRequest{
+		UserId:   userID,
+		BeforeId: before,
+		Limit:    int32(limit),
+	})
+	if err != nil {
+		h.logger.Error("get feed failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	if resp.Error != "" {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": resp.Error})
+		return
+	}
+
+	result := gin.H{"articles": h.articleSummaries(resp.Articles, resp.AuthorUsernames, userID)}
+	if resp.NextBeforeId != 0 {
+		result["next_before"] = resp.NextBeforeId
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// articleSummaries renders a page of articles with their stats for the viewer.
+// usernames holds the author of each article by index. Stats are left at zero
+// if stats-service is unavailable.
+func (h *ArticleHandler) articleSummaries(list []*articlepb.Article, usernames []string, viewerID uint64) []gin.H {
+	articleIDs := make([]uint64, len(list))
+	for i, article := range list {
+		articleIDs[i] = article.Id
+	}
+
+	var statsMap map[uint64]*statspb.ArticleStatsWithLike
+	if len(articleIDs) > 0 {
+		statsResp, err := h.statsClient.GetArticlesWithStats(context.Background(), &statspb.GetArticlesWithStatsRequest{
+			ArticleIds: articleIDs,
+			ViewerId:   viewerID,
+		})
+		if err == nil && statsResp.Error == "" {
+			statsMap = make(map[uint64]*statspb.ArticleStatsWithLike)
+			for _, stat := range statsResp.Stats {
+				statsMap[stat.ArticleId] = stat
+			}
+		}
+	}
+
+	articles := make([]gin.H, len(list))
+	for i, article := range list {
+		var views, likes uint64
+		var viewerLiked bool
+
+		if stat, ok := statsMap[article.Id]; ok {
+			views = stat.Views
+			likes = stat.Likes
+			viewerLiked = stat.ViewerLiked
+		}
+
+		author := ""
+		if i < len(usernames) {
+			author = usernames[i]
+		}
+
+		articles[i] = gin.H{
+			"id":           article.Id,
+			"title":        article.Title,
+			"content":      article.Content,
+			"visibility":   visibilityFromProto(article.Visibility),
+			"author":       author,
+			"views":        views,
+			"likes":        likes,
+			"viewerLiked":  viewerLiked,
+			"status":       statusFromProto(article.Status),
+			"tags":         articleTags(article),
+			"published_at": timestampToString(article.PublishedAt),
+			"created_at":   timestampToString(article.CreatedAt),
+			"updated_at":   timestampToString(article.UpdatedAt),
+		}
+	}
+	return articles
+}
@@ -4,9 +4,12 @@ import (
 	"context"
 	"log/slog"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/XRS0/blog/services/api-gateway/internal/middleware"
 	authpb "github.com/XRS0/blog/services/api-gateway/proto/auth"
 )
 
@@ -25,7 +28,9 @@ func NewAuthHandler(authClient authpb.AuthServiceClient, logger *slog.Logger) *A
 type registerRequest struct {
 	Email    string `json:"email" binding:"required,email"`
 	Username string `json:"username" binding:"required,min=3"`
-	Password string `json:"password" binding:"required,min=6"`
+	Password string `json:"password" binding:"required"` // Length and strength are checked by auth-service
+	// InviteCode is required when registration is invite-only
+	InviteCode string `json:"invite_code"`
 }
 
 type loginRequest struct {
@@ -33,6 +38,40 @@ type loginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type logoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type updateProfileRequest struct {
+	Username        string  `json:"username" binding:"omitempty,min=3"`
+	Email           string  `json:"email" binding:"omitempty,email"`
+	CurrentPassword string  `json:"current_password"`
+	Bio             *string `json:"bio"`        // Empty string clears it
+	AvatarURL       *string `json:"avatar_url"` // Empty string removes it
+}
+
+type forgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type resetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+type verifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type changePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
 func (h *AuthHandler) Register(c *gin.Context) {
 	var req registerRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -40,10 +79,11 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
-	resp, err := h.authClient.Register(context.Background(), &authpb.RegisterRequest{
-		Email:    req.Email,
-		Username: req.Username,
-		Password: req.Password,
+	resp, err := h.authClient.Register(clientContext(c), &authpb.RegisterRequest{
+		Email:      req.Email,
+		Username:   req.Username,
+		Password:   req.Password,
+		InviteCode: req.InviteCode,
 	})
 	if err != nil {
 		h.logger.Error("register failed", "error", err)
@@ -52,7 +92,14 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	}
 
 	if resp.Error != "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": resp.Error})
+		switch resp.Error {
+		case "username already taken":
+			c.JSON(http.StatusConflict, gin.H{"error": resp.Error})
+		case "registration is closed", "invite code required", "invalid invite code":
+			c.JSON(http.StatusForbidden, gin.H{"error": resp.Error})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": resp.Error})
+		}
 		return
 	}
 
@@ -62,7 +109,8 @@ func (h *AuthHandler) Register(c *gin.Context) {
 			"email":    resp.User.Email,
 			"username": resp.User.Username,
 		},
-		"token": resp.Token,
+		"token":         resp.Token,
+		"refresh_token": resp.RefreshToken,
 	})
 }
 
@@ -73,7 +121,7 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	resp, err := h.authClient.Login(context.Background(), &authpb.LoginRequest{
+	resp, err := h.authClient.Login(clientContext(c), &authpb.LoginRequest{
 		Email:    req.Email,
 		Password: req.Password,
 	})
@@ -83,19 +131,86 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
+	if resp.RetryAfterSeconds > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(resp.RetryAfterSeconds)))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": resp.Error, "retry_after": resp.RetryAfterSeconds})
+		return
+	}
+
 	if resp.Error != "" {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": resp.Error})
 		return
 	}
 
+	// The password was right, but the user must also pass POST /auth/mfa/verify
+	if resp.MfaRequired {
+		c.JSON(http.StatusOK, gin.H{
+			"mfa_required": true,
+			"mfa_token":    resp.MfaToken,
+		})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"user": gin.H{
 			"id":       resp.User.Id,
 			"email":    resp.User.Email,
 			"username": resp.User.Username,
 		},
-		"token": resp.Token,
+		"token":         resp.Token,
+		"refresh_token": resp.RefreshToken,
+	})
+}
+
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req refreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp, err := h.authClient.RefreshToken(clientContext(c), &authpb.RefreshTokenRequest{
+		RefreshToken: req.RefreshToken,
+	})
+	if err != nil {
+		h.logger.Error("refresh token failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	if resp.Error != "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": resp.Error})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"token":         resp.Token,
+		"refresh_token": resp.RefreshToken,
+	})
+}
+
+// Logout revokes the bearer access token (if any) and the refresh token from the body.
+// It does not require a valid access token so that clients can log out after it expired.
+func (h *AuthHandler) Logout(c *gin.Context) {
+	var req logoutRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	resp, err := h.authClient.Logout(context.Background(), &authpb.LogoutRequest{
+		Token:        strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "),
+		RefreshToken: req.RefreshToken,
 	})
+	if err != nil || !resp.Success {
+		h.logger.Error("logout failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
 }
 
 func (h *AuthHandler) GetMe(c *gin.Context) {
@@ -115,8 +230,216 @@ func (h *AuthHandler) GetMe(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"id":       resp.User.Id,
-		"email":    resp.User.Email,
-		"username": resp.User.Username,
+		"id":             resp.User.Id,
+		"email":          resp.User.Email,
+		"username":       resp.User.Username,
+		"email_verified": resp.User.EmailVerified,
+		"bio":            resp.User.Bio,
+		"avatar_url":     resp.User.AvatarUrl,
+	})
+}
+
+func (h *AuthHandler) UpdateProfile(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	var req updateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp, err := h.authClient.UpdateProfile(context.Background(), &authpb.UpdateProfileRequest{
+		UserId:          userID,
+		Username:        req.Username,
+		Email:           req.Email,
+		CurrentPassword: req.CurrentPassword,
+		Bio:             req.Bio,
+		AvatarUrl:       req.AvatarURL,
+	})
+	if err != nil {
+		h.logger.Error("update profile failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	if resp.Error != "" {
+		switch resp.Error {
+		case "invalid current password":
+			c.JSON(http.StatusForbidden, gin.H{"error": resp.Error})
+		case "email already registered", "username already taken":
+			c.JSON(http.StatusConflict, gin.H{"error": resp.Error})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": resp.Error})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user": gin.H{
+			"id":         resp.User.Id,
+			"email":      resp.User.Email,
+			"username":   resp.User.Username,
+			"bio":        resp.User.Bio,
+			"avatar_url": resp.User.AvatarUrl,
+		},
+	})
+}
+
+// ChangePassword revokes all existing sessions of the user and returns a new token pair.
+func (h *AuthHandler) ChangePassword(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	var req changePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp, err := h.authClient.ChangePassword(clientContext(c), &authpb.ChangePasswordRequest{
+		UserId:          userID,
+		CurrentPassword: req.CurrentPassword,
+		NewPassword:     req.NewPassword,
+	})
+	if err != nil {
+		h.logger.Error("change password failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	if resp.Error != "" {
+		if resp.Error == "invalid current password" {
+			c.JSON(http.StatusForbidden, gin.H{"error": resp.Error})
+		} else {
+			c.JSON(http.StatusBadRequest, gin.H{"error": resp.Error})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"token":         resp.Token,
+		"refresh_token": resp.RefreshToken,
+	})
+}
+
+// ForgotPassword always answers 200 so that registered emails cannot be enumerated.
+func (h *AuthHandler) ForgotPassword(c *gin.Context) {
+	var req forgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp, err := h.authClient.RequestPasswordReset(context.Background(), &authpb.RequestPasswordResetRequest{
+		Email: req.Email,
+	})
+	if err != nil || !resp.Success {
+		h.logger.Error("request password reset failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var req resetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp, err := h.authClient.ResetPassword(context.Background(), &authpb.ResetPasswordRequest{
+		Token:       req.Token,
+		NewPassword: req.NewPassword,
+	})
+	if err != nil {
+		h.logger.Error("reset password failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	if resp.Error != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": resp.Error})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// VerifyEmail confirms the address from the emailed link. Clients should call
+// /auth/refresh afterwards to get an access token with the updated claim.
+func (h *AuthHandler) VerifyEmail(c *gin.Context) {
+	var req verifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp, err := h.authClient.VerifyEmail(context.Background(), &authpb.VerifyEmailRequest{
+		Token: req.Token,
+	})
+	if err != nil {
+		h.logger.Error("verify email failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	if resp.Error != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": resp.Error})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user": gin.H{
+			"id":             resp.User.Id,
+			"email":          resp.User.Email,
+			"username":       resp.User.Username,
+			"email_verified": resp.User.EmailVerified,
+		},
+	})
+}
+
+func (h *AuthHandler) ResendVerificationEmail(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	resp, err := h.authClient.ResendVerificationEmail(context.Background(), &authpb.ResendVerificationEmailRequest{
+		UserId: userID,
 	})
+	if err != nil {
+		h.logger.Error("resend verification email failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	if resp.Error != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": resp.Error})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// JWKS serves the public keys that verify access tokens.
+func (h *AuthHandler) JWKS(c *gin.Context) {
+	resp, err := h.authClient.GetJWKS(context.Background(), &authpb.GetJWKSRequest{})
+	if err != nil || resp.Error != "" {
+		h.logger.Error("get jwks failed", "error", err, "response_error", resp.GetError())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, middleware.JWKSFromProto(resp.Keys))
 }
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/XRS0/blog/services/api-gateway/internal/export"
+)
+
+type ExportHandler struct {
+	exporter *export.Exporter
+	// linkTTL is how long a download link from the status endpoint works.
+	linkTTL time.Duration
+	logger  *slog.Logger
+}
+
+func NewExportHandler(exporter *export.Exporter, linkTTL time.Duration, logger *slog.Logger) *ExportHandler {
+	return &ExportHandler{
+		exporter: exporter,
+		linkTTL:  linkTTL,
+		logger:   logger,
+	}
+}
+
+func (h *ExportHandler) exportJSON(job *export.Job) gin.H {
+	resp := gin.H{
+		"id":         job.ID,
+		"status":     job.Status,
+		"created_at": job.CreatedAt.Format(time.RFC3339),
+		"expires_at": job.ExpiresAt.Format(time.RFC3339),
+	}
+	if !job.CompletedAt.IsZero() {
+		resp["completed_at"] = job.CompletedAt.Format(time.RFC3339)
+	}
+	if job.Error != "" {
+		resp["error"] = job.Error
+	}
+	if job.Status == export.StatusReady {
+		resp["size"] = job.Size
+		resp["download_url"] = h.exporter.DownloadURL(job, h.linkTTL)
+	}
+	return resp
+}
+
+// CreateExport starts collecting the caller's data. Poll GetExport until the
+// status is "ready" to get the download link.
+func (h *ExportHandler) CreateExport(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	job, err := h.exporter.Start(userID)
+	if err != nil {
+		h.logger.Error("start export failed", "user_id", userID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, h.exportJSON(job))
+}
+
+// GetExport reports the status of an export and, once ready, a fresh download link.
+func (h *ExportHandler) GetExport(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	job, err := h.exporter.Get(userID, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, h.exportJSON(job))
+}
+
+// DownloadExport serves the archive. The signed link is the only credential, so
+// it can be opened directly in a browser.
+func (h *ExportHandler) DownloadExport(c *gin.Context) {
+	job, path, err := h.exporter.Open(c.Param("id"), c.Query("expires"), c.Query("signature"))
+	if errors.Is(err, export.ErrInvalidLink) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		h.logger.Error("open export failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	c.FileAttachment(path, fmt.Sprintf("blog-export-%s.zip", job.CreatedAt.Format("2006-01-02")))
+}
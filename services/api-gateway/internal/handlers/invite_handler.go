@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	authpb "github.com/XRS0/blog/services/api-gateway/proto/auth"
+)
+
+type createInviteRequest struct {
+	MaxUses       int32 `json:"max_uses" binding:"min=0"`
+	ExpiresInDays int32 `json:"expires_in_days" binding:"min=0"`
+}
+
+func inviteJSON(invite *authpb.InviteCode) gin.H {
+	return gin.H{
+		"id":         invite.Id,
+		"prefix":     invite.Prefix,
+		"max_uses":   invite.MaxUses,
+		"uses":       invite.Uses,
+		"expires_at": timestampToString(invite.ExpiresAt),
+		"created_at": timestampToString(invite.CreatedAt),
+	}
+}
+
+// CreateInvite issues an invite code. The code is returned only once.
+func (h *AuthHandler) CreateInvite(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	var req createInviteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp, err := h.authClient.CreateInvite(context.Background(), &authpb.CreateInviteRequest{
+		UserId:        userID,
+		MaxUses:       req.MaxUses,
+		ExpiresInDays: req.ExpiresInDays,
+	})
+	if err != nil {
+		h.logger.Error("create invite failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	if resp.Error != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": resp.Error})
+		return
+	}
+
+	invite := inviteJSON(resp.Invite)
+	invite["code"] = resp.Code
+	c.JSON(http.StatusCreated, invite)
+}
+
+func (h *AuthHandler) ListInvites(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	resp, err := h.authClient.ListInvites(context.Background(), &authpb.ListInvitesRequest{
+		UserId: userID,
+	})
+	if err != nil || resp.Error != "" {
+		h.logger.Error("list invites failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	invites := make([]gin.H, len(resp.Invites))
+	for i, invite := range resp.Invites {
+		invites[i] = inviteJSON(invite)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"invites": invites})
+}
+
+func (h *AuthHandler) RevokeInvite(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid invite id"})
+		return
+	}
+
+	resp, err := h.authClient.RevokeInvite(context.Background(), &authpb.RevokeInviteRequest{
+		UserId: userID,
+		Id:     id,
+	})
+	if err != nil {
+		h.logger.Error("revoke invite failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	if resp.Error != "" {
+		if resp.Error == "invite not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": resp.Error})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": resp.Error})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc/metadata"
+)
+
+// Metadata keys must match the keys auth-service reads the end-user address and
+// user agent from.
+const (
+	clientIPMetadataKey        = "x-client-ip"
+	clientUserAgentMetadataKey = "x-client-user-agent"
+)
+
+// clientContext returns a context that forwards the end-user address and user agent
+// to backend services. The address comes from gin's ClientIP and therefore honors
+// the trusted proxy settings.
+func clientContext(c *gin.Context) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(),
+		clientIPMetadataKey, c.ClientIP(),
+		clientUserAgentMetadataKey, c.Request.UserAgent(),
+	)
+}
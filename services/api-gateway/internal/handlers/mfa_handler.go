@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	authpb "github.com/XRS0/blog/services/api-gateway/proto/auth"
+)
+
+type verifyMFARequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+type totpCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type disableTOTPRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// VerifyMFA completes a login that answered with mfa_required.
+func (h *AuthHandler) VerifyMFA(c *gin.Context) {
+	var req verifyMFARequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp, err := h.authClient.VerifyMFA(clientContext(c), &authpb.VerifyMFARequest{
+		MfaToken: req.MFAToken,
+		Code:     req.Code,
+	})
+	if err != nil {
+		h.logger.Error("verify mfa failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	if resp.RetryAfterSeconds > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(resp.RetryAfterSeconds)))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": resp.Error, "retry_after": resp.RetryAfterSeconds})
+		return
+	}
+
+	if resp.Error != "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": resp.Error})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user": gin.H{
+			"id":       resp.User.Id,
+			"email":    resp.User.Email,
+			"username": resp.User.Username,
+		},
+		"token":         resp.Token,
+		"refresh_token": resp.RefreshToken,
+	})
+}
+
+// EnrollTOTP returns a new secret and otpauth URI. 2FA is enabled only after ConfirmTOTP.
+func (h *AuthHandler) EnrollTOTP(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	resp, err := h.authClient.EnrollTOTP(context.Background(), &authpb.EnrollTOTPRequest{
+		UserId: userID,
+	})
+	if err != nil {
+		h.logger.Error("enroll totp failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	if resp.Error != "" {
+		if resp.Error == "two-factor authentication already enabled" {
+			c.JSON(http.StatusConflict, gin.H{"error": resp.Error})
+		} else {
+			c.JSON(http.StatusBadRequest, gin.H{"error": resp.Error})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"secret":      resp.Secret,
+		"otpauth_uri": resp.OtpauthUri,
+	})
+}
+
+// ConfirmTOTP enables 2FA and returns the recovery codes, which are shown only once.
+func (h *AuthHandler) ConfirmTOTP(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	var req totpCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp, err := h.authClient.ConfirmTOTP(context.Background(), &authpb.ConfirmTOTPRequest{
+		UserId: userID,
+		Code:   req.Code,
+	})
+	if err != nil {
+		h.logger.Error("confirm totp failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	if resp.Error != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": resp.Error})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": resp.RecoveryCodes})
+}
+
+func (h *AuthHandler) DisableTOTP(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	var req disableTOTPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp, err := h.authClient.DisableTOTP(context.Background(), &authpb.DisableTOTPRequest{
+		UserId:   userID,
+		Password: req.Password,
+		Code:     req.Code,
+	})
+	if err != nil {
+		h.logger.Error("disable totp failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	if resp.Error != "" {
+		if resp.Error == "invalid current password" || resp.Error == "invalid code" {
+			c.JSON(http.StatusForbidden, gin.H{"error": resp.Error})
+		} else {
+			c.JSON(http.StatusBadRequest, gin.H{"error": resp.Error})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	authpb "github.com/XRS0/blog/services/api-gateway/proto/auth"
+)
+
+type oidcCallbackRequest struct {
+	Code  string `json:"code" binding:"required"`
+	State string `json:"state" binding:"required"`
+}
+
+func (h *AuthHandler) ListOIDCProviders(c *gin.Context) {
+	resp, err := h.authClient.ListOIDCProviders(context.Background(), &authpb.ListOIDCProvidersRequest{})
+	if err != nil {
+		h.logger.Error("list oidc providers failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"providers": resp.Providers})
+}
+
+// StartOIDCLogin redirects the browser to the provider's login page.
+func (h *AuthHandler) StartOIDCLogin(c *gin.Context) {
+	resp, err := h.authClient.StartOIDCLogin(context.Background(), &authpb.StartOIDCLoginRequest{
+		Provider: c.Param("provider"),
+	})
+	if err != nil {
+		h.logger.Error("start oidc login failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	if resp.Error != "" {
+		if resp.Error == "unknown provider" {
+			c.JSON(http.StatusNotFound, gin.H{"error": resp.Error})
+		} else {
+			c.JSON(http.StatusBadGateway, gin.H{"error": resp.Error})
+		}
+		return
+	}
+
+	c.Redirect(http.StatusFound, resp.AuthorizationUrl)
+}
+
+// CompleteOIDCLogin is called by the frontend callback page with the code and state
+// it received from the provider.
+func (h *AuthHandler) CompleteOIDCLogin(c *gin.Context) {
+	var req oidcCallbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp, err := h.authClient.CompleteOIDCLogin(clientContext(c), &authpb.CompleteOIDCLoginRequest{
+		Provider: c.Param("provider"),
+		Code:     req.Code,
+		State:    req.State,
+	})
+	if err != nil {
+		h.logger.Error("complete oidc login failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	if resp.Error != "" {
+		switch resp.Error {
+		case "unknown provider":
+			c.JSON(http.StatusNotFound, gin.H{"error": resp.Error})
+		case "registration is closed", "registration requires an invite code":
+			c.JSON(http.StatusForbidden, gin.H{"error": resp.Error})
+		case "an account with this email exists but its email is not verified":
+			c.JSON(http.StatusConflict, gin.H{"error": resp.Error})
+		default:
+			c.JSON(http.StatusUnauthorized, gin.H{"error": resp.Error})
+		}
+		return
+	}
+
+	if resp.MfaRequired {
+		c.JSON(http.StatusOK, gin.H{
+			"mfa_required": true,
+			"mfa_token":    resp.MfaToken,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user": gin.H{
+			"id":       resp.User.Id,
+			"email":    resp.User.Email,
+			"username": resp.User.Username,
+		},
+		"token":         resp.Token,
+		"refresh_token": resp.RefreshToken,
+	})
+}
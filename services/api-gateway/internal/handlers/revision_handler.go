@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	articlepb "github.com/XRS0/blog/services/api-gateway/proto/article"
+)
+
+type restoreRevisionRequest struct {
+	// ExpectedVersion is the version of the article the restore is based on, for
+	// clients that cannot send If-Match. One of them is required.
+	ExpectedVersion int32 `json:"expected_version"`
+}
+
+func revisionJSON(revision *articlepb.ArticleRevision) gin.H {
+	result := gin.H{
+		"number":     revision.Number,
+		"author_id":  revision.AuthorId,
+		"title":      revision.Title,
+		"created_at": timestampToString(revision.CreatedAt),
+	}
+	if revision.RestoredFrom != 0 {
+		result["restored_from"] = revision.RestoredFrom
+	}
+	return result
+}
+
+// revisionParams reads the article ID and the revision number from the path.
+// It writes a 400 response and returns false when they are malformed.
+func revisionParams(c *gin.Context) (uint64, int32, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid article id"})
+		return 0, 0, false
+	}
+	number, err := strconv.ParseInt(c.Param("rev"), 10, 32)
+	if err != nil || number < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid revision number"})
+		return 0, 0, false
+	}
+	return id, int32(number), true
+}
+
+func revisionError(c *gin.Context, message string) {
+	switch message {
+	case "unauthorized":
+		c.JSON(http.StatusForbidden, gin.H{"error": message})
+	case "article not found", "revision not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": message})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
+
+// ListRevisions returns the saved versions of an article, newest first. Authors only.
+func (h *ArticleHandler) ListRevisions(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid article id"})
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+	resp, err := h.articleClient.ListRevisions(context.Background(), &articlepb.ListRevisionsRequest{
+		ArticleId: id,
+		UserId:    getUserID(c),
+		Limit:     int32(limit),
+		Offset:    int32(offset),
+	})
+	if err != nil {
+		h.logger.Error("list revisions failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	if resp.Error != "" {
+		revisionError(c, resp.Error)
+		return
+	}
+
+	revisions := make([]gin.H, len(resp.Revisions))
+	for i, revision := range resp.Revisions {
+		revisions[i] = revisionJSON(revision)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"revisions": revisions, "total": resp.Total})
+}
+
+func (h *ArticleHandler) GetRevision(c *gin.Context) {
+	id, number, ok := revisionParams(c)
+	if !ok {
+		return
+	}
+
+	resp, err := h.articleClient.GetRevision(context.Background(), &articlepb.GetRevisionRequest{
+		ArticleId: id,
+		UserId:    getUserID(c),
+		Number:    number,
+	})
+	if err != nil {
+		h.logger.Error("get revision failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	if resp.Error != "" {
+		revisionError(c, resp.Error)
+		return
+	}
+
+	result := revisionJSON(resp.Revision)
+	result["content"] = resp.Revision.Content
+	c.JSON(http.StatusOK, result)
+}
+
+// DiffRevisions returns a unified diff of the text of a revision against the
+// revision given by from, by default the previous one (0 is an empty article).
+func (h *ArticleHandler) DiffRevisions(c *gin.Context) {
+	id, number, ok := revisionParams(c)
+	if !ok {
+		return
+	}
+
+	from := number - 1
+	if v := c.Query("from"); v != "" {
+		parsed, err := strconv.ParseInt(v, 10, 32)
+		if err != nil || parsed < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid revision number"})
+			return
+		}
+		from = int32(parsed)
+	}
+
+	resp, err := h.articleClient.DiffRevisions(context.Background(), &articlepb.DiffRevisionsRequest{
+		ArticleId: id,
+		UserId:    getUserID(c),
+		From:      from,
+		To:        number,
+	})
+	if err != nil {
+		h.logger.Error("diff revisions failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	if resp.Error != "" {
+		revisionError(c, resp.Error)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"from": from, "to": number, "diff": resp.Diff})
+}
+
+// RestoreRevision brings back the title and content of a revision, saved as a new revision.
+func (h *ArticleHandler) RestoreRevision(c *gin.Context) {
+	id, number, ok := revisionParams(c)
+	if !ok {
+		return
+	}
+
+	// The body is optional when If-Match is sent
+	var req restoreRevisionRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	expectedVersion, ok := requiredVersion(c, req.ExpectedVersion)
+	if !ok {
+		return
+	}
+
+	resp, err := h.articleClient.RestoreRevision(context.Background(), &articlepb.RestoreRevisionRequest{
+		ArticleId:       id,
+		UserId:          getUserID(c),
+		Number:          number,
+		ExpectedVersion: expectedVersion,
+	})
+	if err != nil {
+		h.logger.Error("restore revision failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	if resp.Error == "version conflict" {
+		versionConflict(c, resp.Article)
+		return
+	}
+	if resp.Error != "" {
+		revisionError(c, resp.Error)
+		return
+	}
+
+	article := resp.Article
+	c.Header("ETag", articleETag(article.Version))
+	c.JSON(http.StatusOK, gin.H{
+		"id":           article.Id,
+		"title":        article.Title,
+		"content":      article.Content,
+		"visibility":   visibilityFromProto(article.Visibility),
+		"status":       statusFromProto(article.Status),
+		"published_at": timestampToString(article.PublishedAt),
+		"scheduled_at": timestampToString(article.ScheduledAt),
+		"tags":         articleTags(article),
+		"version":      article.Version,
+		"created_at":   timestampToString(article.CreatedAt),
+		"updated_at":   timestampToString(article.UpdatedAt),
+	})
+}
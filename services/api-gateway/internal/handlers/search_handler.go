@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	articlepb "github.com/XRS0/blog/services/api-gateway/proto/article"
+	authpb "github.com/XRS0/blog/services/api-gateway/proto/auth"
+)
+
+// SearchHandler serves full-text search over articles.
+type SearchHandler struct {
+	articleClient articlepb.ArticleServiceClient
+	authClient    authpb.AuthServiceClient
+	logger        *slog.Logger
+}
+
+func NewSearchHandler(
+	articleClient articlepb.ArticleServiceClient,
+	authClient authpb.AuthServiceClient,
+	logger *slog.Logger,
+) *SearchHandler {
+	return &SearchHandler{
+		articleClient: articleClient,
+		authClient:    authClient,
+		logger:        logger,
+	}
+}
+
+// Search finds articles by the words in their title and content. Optional
+// filters: author (username), tag, and since/until (RFC 3339) on the
+// publication time.
+func (h *SearchHandler) Search(c *gin.Context) {
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "q is required"})
+		return
+	}
+
+	since, ok := parseTimeQuery(c, "since")
+	if !ok {
+		return
+	}
+	until, ok := parseTimeQuery(c, "until")
+	if !ok {
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+	req := &articlepb.SearchArticlesRequest{
+		Query:          query,
+		ViewerId:       getUserID(c),
+		Tag:            c.Query("tag"),
+		PublishedSince: since,
+		PublishedUntil: until,
+		Limit:          int32(limit),
+		Offset:         int32(offset),
+	}
+
+	if username := c.Query("author"); username != "" {
+		userResp, err := h.authClient.GetUserByUsername(context.Background(), &authpb.GetUserByUsernameRequest{
+			Username: username,
+		})
+		if err != nil {
+			h.logger.Error("get user by username failed", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+			return
+		}
+		if userResp.Error == "user not found" {
+			// Nobody by that name wrote anything
+			c.JSON(http.StatusOK, gin.H{"results": []gin.H{}, "total": 0})
+			return
+		}
+		if userResp.Error != "" {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": userResp.Error})
+			return
+		}
+		req.AuthorId = userResp.User.Id
+	}
+
+	resp, err := h.articleClient.SearchArticles(context.Background(), req)
+	if err != nil {
+		h.logger.Error("search articles failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	if resp.Error != "" {
+		if resp.Error == "invalid tag" || strings.HasPrefix(resp.Error, "query ") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": resp.Error})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": resp.Error})
+		}
+		return
+	}
+
+	results := make([]gin.H, len(resp.Results))
+	for i, result := range resp.Results {
+		article := result.Article
+		results[i] = gin.H{
+			"id":              article.Id,
+			"title":           article.Title,
+			"title_highlight": result.TitleHighlight,
+			"snippet":         result.Snippet,
+			"author":          result.AuthorUsername,
+			"visibility":      visibilityFromProto(article.Visibility),
+			"status":          statusFromProto(article.Status),
+			"tags":            articleTags(article),
+			"rank":            result.Rank,
+			"published_at":    timestampToString(article.PublishedAt),
+			"updated_at":      timestampToString(article.UpdatedAt),
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"results": results, "total": resp.Total})
+}
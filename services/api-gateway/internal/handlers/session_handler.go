@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	authpb "github.com/XRS0/blog/services/api-gateway/proto/auth"
+)
+
+// ListSessions returns the caller's active logins; the one making the request is
+// marked as current.
+func (h *AuthHandler) ListSessions(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	resp, err := h.authClient.ListSessions(context.Background(), &authpb.ListSessionsRequest{
+		UserId:           userID,
+		CurrentSessionId: c.GetString("session_id"),
+	})
+	if err != nil || resp.Error != "" {
+		h.logger.Error("list sessions failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	sessions := make([]gin.H, len(resp.Sessions))
+	for i, session := range resp.Sessions {
+		sessions[i] = gin.H{
+			"id":           session.Id,
+			"user_agent":   session.UserAgent,
+			"ip":           session.Ip,
+			"created_at":   timestampToString(session.CreatedAt),
+			"last_seen_at": timestampToString(session.LastSeenAt),
+			"current":      session.Current,
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sessions": sessions})
+}
+
+// RevokeSession logs the caller out of one of their sessions.
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	resp, err := h.authClient.RevokeSession(context.Background(), &authpb.RevokeSessionRequest{
+		UserId:    userID,
+		SessionId: c.Param("id"),
+	})
+	if err != nil {
+		h.logger.Error("revoke session failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	if resp.Error != "" {
+		if resp.Error == "session not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": resp.Error})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": resp.Error})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	articlepb "github.com/XRS0/blog/services/api-gateway/proto/article"
+)
+
+// ListTags returns the tags of published public articles with their article
+// counts, most used first.
+func (h *ArticleHandler) ListTags(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+	resp, err := h.articleClient.ListTags(context.Background(), &articlepb.ListTagsRequest{
+		Limit:  int32(limit),
+		Offset: int32(offset),
+	})
+	if err != nil {
+		h.logger.Error("list tags failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	if resp.Error != "" {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": resp.Error})
+		return
+	}
+
+	tags := make([]gin.H, len(resp.Tags))
+	for i, tag := range resp.Tags {
+		tags[i] = gin.H{"slug": tag.Slug, "articles": tag.Articles}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tags": tags})
+}
+
+// ListTagArticles returns the published public articles with a tag, like ListArticles.
+func (h *ArticleHandler) ListTagArticles(c *gin.Context) {
+	userID := getUserID(c)
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+	resp, err := h.articleClient.ListArticlesByTag(context.Background(), &articlepb.ListArticlesByTagRequest{
+		Tag:      c.Param("slug"),
+		ViewerId: userID,
+		Limit:    int32(limit),
+		Offset:   int32(offset),
+	})
+	if err != nil {
+		h.logger.Error("list articles by tag failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	if resp.Error != "" {
+		if resp.Error == "invalid tag" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": resp.Error})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": resp.Error})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, h.articleSummaries(resp.Articles, resp.AuthorUsernames, userID))
+}
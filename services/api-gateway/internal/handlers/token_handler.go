@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	authpb "github.com/XRS0/blog/services/api-gateway/proto/auth"
+)
+
+type createTokenRequest struct {
+	Name          string   `json:"name" binding:"required"`
+	Scopes        []string `json:"scopes" binding:"required"`
+	ExpiresInDays int32    `json:"expires_in_days" binding:"min=0"`
+}
+
+func personalAccessTokenJSON(token *authpb.PersonalAccessToken) gin.H {
+	return gin.H{
+		"id":           token.Id,
+		"name":         token.Name,
+		"prefix":       token.Prefix,
+		"scopes":       token.Scopes,
+		"expires_at":   timestampToString(token.ExpiresAt),
+		"last_used_at": timestampToString(token.LastUsedAt),
+		"created_at":   timestampToString(token.CreatedAt),
+	}
+}
+
+// CreateToken issues a personal access token. The token is returned only once.
+func (h *AuthHandler) CreateToken(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	var req createTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp, err := h.authClient.CreatePersonalAccessToken(context.Background(), &authpb.CreatePersonalAccessTokenRequest{
+		UserId:        userID,
+		Name:          req.Name,
+		Scopes:        req.Scopes,
+		ExpiresInDays: req.ExpiresInDays,
+	})
+	if err != nil {
+		h.logger.Error("create token failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	if resp.Error != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": resp.Error})
+		return
+	}
+
+	token := personalAccessTokenJSON(resp.PersonalAccessToken)
+	token["token"] = resp.Token
+	c.JSON(http.StatusCreated, token)
+}
+
+func (h *AuthHandler) ListTokens(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	resp, err := h.authClient.ListPersonalAccessTokens(context.Background(), &authpb.ListPersonalAccessTokensRequest{
+		UserId: userID,
+	})
+	if err != nil || resp.Error != "" {
+		h.logger.Error("list tokens failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	tokens := make([]gin.H, len(resp.Tokens))
+	for i, token := range resp.Tokens {
+		tokens[i] = personalAccessTokenJSON(token)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tokens": tokens})
+}
+
+func (h *AuthHandler) RevokeToken(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid token id"})
+		return
+	}
+
+	resp, err := h.authClient.RevokePersonalAccessToken(context.Background(), &authpb.RevokePersonalAccessTokenRequest{
+		UserId: userID,
+		Id:     id,
+	})
+	if err != nil {
+		h.logger.Error("revoke token failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	if resp.Error != "" {
+		if resp.Error == "token not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": resp.Error})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": resp.Error})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
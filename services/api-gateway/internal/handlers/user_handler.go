@@ -0,0 +1,363 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+
+	articlepb "github.com/XRS0/blog/services/api-gateway/proto/article"
+	authpb "github.com/XRS0/blog/services/api-gateway/proto/auth"
+	statspb "github.com/XRS0/blog/services/api-gateway/proto/stats"
+)
+
+// UserHandler serves public author profiles, follows, blocks and mutes between users.
+type UserHandler struct {
+	authClient    authpb.AuthServiceClient
+	articleClient articlepb.ArticleServiceClient
+	statsClient   statspb.StatsServiceClient
+	logger        *slog.Logger
+}
+
+func NewUserHandler(
+	authClient authpb.AuthServiceClient,
+	articleClient articlepb.ArticleServiceClient,
+	statsClient statspb.StatsServiceClient,
+	logger *slog.Logger,
+) *UserHandler {
+	return &UserHandler{
+		authClient:    authClient,
+		articleClient: articleClient,
+		statsClient:   statsClient,
+		logger:        logger,
+	}
+}
+
+// lookupUser resolves the :username parameter. On failure it writes the
+// response and returns nil.
+func (h *UserHandler) lookupUser(c *gin.Context) *authpb.User {
+	resp, err := h.authClient.GetUserByUsername(context.Background(), &authpb.GetUserByUsernameRequest{
+		Username: c.Param("username"),
+	})
+	if err != nil {
+		h.logger.Error("get user by username failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return nil
+	}
+	if resp.Error != "" {
+		if resp.Error == "user not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": resp.Error})
+			return nil
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": resp.Error})
+		return nil
+	}
+	return resp.User
+}
+
+// GetProfile returns the public profile of an author with their public
+// articles and the total views and likes those articles received.
+func (h *UserHandler) GetProfile(c *gin.Context) {
+	user := h.lookupUser(c)
+	if user == nil {
+		return
+	}
+
+	// Profiles list public articles only, even for the author; other viewers are
+	// passed on so that authors who blocked them show no articles
+	viewerID := getUserID(c)
+	if viewerID == user.Id {
+		viewerID = 0
+	}
+	articlesResp, err := h.articleClient.GetArticlesByUser(context.Background(), &articlepb.GetArticlesByUserRequest{
+		UserId:   user.Id,
+		ViewerId: viewerID,
+	})
+	if err != nil {
+		h.logger.Error("get user articles failed", "user_id", user.Id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+	if articlesResp.Error != "" {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": articlesResp.Error})
+		return
+	}
+
+	articleIDs := make([]uint64, len(articlesResp.Articles))
+	for i, article := range articlesResp.Articles {
+		articleIDs[i] = article.Id
+	}
+
+	var statsMap map[uint64]*statspb.ArticleStatsWithLike
+	if len(articleIDs) > 0 {
+		statsResp, err := h.statsClient.GetArticlesWithStats(context.Background(), &statspb.GetArticlesWithStatsRequest{
+			ArticleIds: articleIDs,
+			ViewerId:   getUserID(c),
+		})
+		if err == nil && statsResp.Error == "" {
+			statsMap = make(map[uint64]*statspb.ArticleStatsWithLike)
+			for _, stat := range statsResp.Stats {
+				statsMap[stat.ArticleId] = stat
+			}
+		} else {
+			h.logger.Error("get user article stats failed", "user_id", user.Id, "error", err, "response_error", statsResp.GetError())
+		}
+	}
+
+	var totalViews, totalLikes uint64
+	articles := make([]gin.H, len(articlesResp.Articles))
+	for i, article := range articlesResp.Articles {
+		var views, likes uint64
+		var viewerLiked bool
+
+		if stat, ok := statsMap[article.Id]; ok {
+			views = stat.Views
+			likes = stat.Likes
+			viewerLiked = stat.ViewerLiked
+		}
+		totalViews += views
+		totalLikes += likes
+
+		articles[i] = gin.H{
+			"id":           article.Id,
+			"title":        article.Title,
+			"content":      article.Content,
+			"visibility":   visibilityFromProto(article.Visibility),
+			"author":       user.Username,
+			"views":        views,
+			"likes":        likes,
+			"viewerLiked":  viewerLiked,
+			"status":       statusFromProto(article.Status),
+			"tags":         articleTags(article),
+			"published_at": timestampToString(article.PublishedAt),
+			"created_at":   timestampToString(article.CreatedAt),
+			"updated_at":   timestampToString(article.UpdatedAt),
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"username":   user.Username,
+		"bio":        user.Bio,
+		"avatar_url": user.AvatarUrl,
+		"joined_at":  timestampToString(user.CreatedAt),
+		"stats": gin.H{
+			"articles": len(articles),
+			"views":    totalViews,
+			"likes":    totalLikes,
+		},
+		"articles": articles,
+	})
+}
+
+func (h *UserHandler) Follow(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	user := h.lookupUser(c)
+	if user == nil {
+		return
+	}
+
+	resp, err := h.articleClient.FollowUser(context.Background(), &articlepb.FollowUserRequest{
+		FollowerId: userID,
+		FolloweeId: user.Id,
+	})
+	if err != nil {
+		h.logger.Error("follow user failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	if resp.Error != "" {
+		if resp.Error == "user not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": resp.Error})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": resp.Error})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+func (h *UserHandler) Unfollow(c *gin.Context) {
+	userID := getUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	user := h.lookupUser(c)
+	if user == nil {
+		return
+	}
+
+	resp, err := h.articleClient.UnfollowUser(context.Background(), &articlepb.UnfollowUserRequest{
+		FollowerId: userID,
+		FolloweeId: user.Id,
+	})
+	if err != nil {
+		h.logger.Error("unfollow user failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	if resp.Error != "" {
+		if resp.Error == "not following" {
+			c.JSON(http.StatusNotFound, gin.H{"error": resp.Error})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": resp.Error})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+func (h *UserHandler) ListFollowers(c *gin.Context) {
+	h.listFollows(c, h.articleClient.ListFollowers)
+}
+
+func (h *UserHandler) ListFollowing(c *gin.Context) {
+	h.listFollows(c, h.articleClient.ListFollowing)
+}
+
+func (h *UserHandler) listFollows(c *gin.Context, list func(context.Context, *articlepb.ListFollowsRequest, ...grpc.CallOption) (*articlepb.ListFollowsResponse, error)) {
+	user := h.lookupUser(c)
+	if user == nil {
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+	resp, err := list(context.Background(), &articlepb.ListFollowsRequest{
+		UserId: user.Id,
+		Limit:  int32(limit),
+		Offset: int32(offset),
+	})
+	if err != nil {
+		h.logger.Error("list follows failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	if resp.Error != "" {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": resp.Error})
+		return
+	}
+
+	users := make([]gin.H, len(resp.Users))
+	for i, followed := range resp.Users {
+		users[i] = gin.H{
+			"username":    followed.Username,
+			"followed_at": timestampToString(followed.FollowedAt),
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"users": users,
+		"total": resp.Total,
+	})
+}
+
+func (h *UserHandler) Block(c *gin.Context) {
+	h.changeRelation(c, "block user", h.authClient.BlockUser)
+}
+
+func (h *UserHandler) Unblock(c *gin.Context) {
+	h.changeRelation(c, "unblock user", h.authClient.UnblockUser)
+}
+
+func (h *UserHandler) Mute(c *gin.Context) {
+	h.changeRelation(c, "mute user", h.authClient.MuteUser)
+}
+
+func (h *UserHandler) Unmute(c *gin.Context) {
+	h.changeRelation(c, "unmute user", h.authClient.UnmuteUser)
+}
+
+// changeRelation blocks, mutes or undoes either for the :username parameter.
+func (h *UserHandler) changeRelation(c *gin.Context, action string, change func(context.Context, *authpb.UserRelationRequest, ...grpc.CallOption) (*authpb.UserRelationResponse, error)) {
+	userID := getUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	user := h.lookupUser(c)
+	if user == nil {
+		return
+	}
+
+	resp, err := change(context.Background(), &authpb.UserRelationRequest{
+		UserId:   userID,
+		TargetId: user.Id,
+	})
+	if err != nil {
+		h.logger.Error(action+" failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	if resp.Error != "" {
+		switch resp.Error {
+		case "user not found", "block not found", "mute not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": resp.Error})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": resp.Error})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// ListBlocked returns the users the current user has blocked.
+func (h *UserHandler) ListBlocked(c *gin.Context) {
+	h.listRelations(c, false)
+}
+
+// ListMuted returns the users the current user has muted.
+func (h *UserHandler) ListMuted(c *gin.Context) {
+	h.listRelations(c, true)
+}
+
+func (h *UserHandler) listRelations(c *gin.Context, muted bool) {
+	userID := getUserID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated"})
+		return
+	}
+
+	resp, err := h.authClient.ListBlockedUsers(context.Background(), &authpb.ListBlockedUsersRequest{
+		UserId: userID,
+		Muted:  muted,
+	})
+	if err != nil {
+		h.logger.Error("list blocked users failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	if resp.Error != "" {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": resp.Error})
+		return
+	}
+
+	users := make([]gin.H, len(resp.Users))
+	for i, blocked := range resp.Users {
+		users[i] = gin.H{
+			"username":   blocked.Username,
+			"created_at": timestampToString(blocked.CreatedAt),
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"users": users})
+}
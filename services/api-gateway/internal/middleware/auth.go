@@ -1,17 +1,17 @@
 package middleware
 
 import (
-	"context"
 	"log/slog"
 	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
-
-	authpb "github.com/XRS0/blog/services/api-gateway/proto/auth"
 )
 
-func AuthMiddleware(authClient authpb.AuthServiceClient, logger *slog.Logger, required bool) gin.HandlerFunc {
+// AuthMiddleware authenticates the request with a session JWT or a personal access
+// token. Personal access tokens are accepted only on routes that list scopes, and
+// must carry all of them; optional routes without scopes treat them as anonymous.
+func AuthMiddleware(validator TokenValidator, logger *slog.Logger, required bool, scopes []string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Extract token from Authorization header
 		authHeader := c.GetHeader("Authorization")
@@ -39,11 +39,9 @@ func AuthMiddleware(authClient authpb.AuthServiceClient, logger *slog.Logger, re
 			return
 		}
 
-		// Validate token with auth service
-		resp, err := authClient.ValidateToken(context.Background(), &authpb.ValidateTokenRequest{
-			Token: token,
-		})
-		if err != nil || !resp.Valid {
+		// Validate token
+		identity, err := validator.Validate(c.Request.Context(), token)
+		if err != nil {
 			logger.Error("token validation failed", "error", err)
 			if required {
 				c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
@@ -55,16 +53,70 @@ func AuthMiddleware(authClient authpb.AuthServiceClient, logger *slog.Logger, re
 			return
 		}
 
+		if identity.PersonalAccessToken {
+			if len(scopes) == 0 {
+				if !required {
+					c.Set("user_id", uint64(0))
+					c.Next()
+					return
+				}
+				c.JSON(http.StatusForbidden, gin.H{"error": "personal access tokens are not accepted for this route"})
+				c.Abort()
+				return
+			}
+			for _, scope := range scopes {
+				if !hasScope(identity.Scopes, scope) {
+					c.JSON(http.StatusForbidden, gin.H{"error": "insufficient scope", "required_scopes": scopes})
+					c.Abort()
+					return
+				}
+			}
+		}
+
 		// Set user ID in context
-		c.Set("user_id", resp.UserId)
+		c.Set("user_id", identity.UserID)
+		c.Set("email_verified", identity.EmailVerified)
+		// Personal access tokens act only within their scopes, never with the user's roles
+		if !identity.PersonalAccessToken {
+			c.Set("roles", identity.Roles)
+			c.Set("session_id", identity.SessionID)
+		}
+		c.Set("scopes", identity.Scopes)
 		c.Next()
 	}
 }
 
-func RequireAuth(authClient authpb.AuthServiceClient, logger *slog.Logger) gin.HandlerFunc {
-	return AuthMiddleware(authClient, logger, true)
+func RequireAuth(validator TokenValidator, logger *slog.Logger, scopes ...string) gin.HandlerFunc {
+	return AuthMiddleware(validator, logger, true, scopes)
+}
+
+func OptionalAuth(validator TokenValidator, logger *slog.Logger, scopes ...string) gin.HandlerFunc {
+	return AuthMiddleware(validator, logger, false, scopes)
 }
 
-func OptionalAuth(authClient authpb.AuthServiceClient, logger *slog.Logger) gin.HandlerFunc {
-	return AuthMiddleware(authClient, logger, false)
+func hasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// RequireRole allows the request if the authenticated user has any of the given roles.
+// It must run after RequireAuth.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, have := range c.GetStringSlice("roles") {
+			for _, want := range roles {
+				if have == want {
+					c.Next()
+					return
+				}
+			}
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		c.Abort()
+	}
 }
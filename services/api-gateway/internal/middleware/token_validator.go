@@ -0,0 +1,205 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	authpb "github.com/XRS0/blog/services/api-gateway/proto/auth"
+	"github.com/XRS0/blog/shared/jwks"
+)
+
+// accessTokenAudience must match the audience auth-service puts into access tokens.
+const accessTokenAudience = "blog:access"
+
+// personalAccessTokenPrefix must match the prefix of tokens issued by auth-service.
+const personalAccessTokenPrefix = "blog_pat_"
+
+// Identity is the authenticated caller as seen by the gateway.
+type Identity struct {
+	UserID        uint64
+	EmailVerified bool
+	Roles         []string
+	// Scopes limit what a personal access token may do. Session tokens are not scoped.
+	Scopes              []string
+	PersonalAccessToken bool
+	// SessionID identifies the login a session token belongs to.
+	SessionID string
+}
+
+// TokenValidator checks an access token and returns its bearer.
+type TokenValidator interface {
+	Validate(ctx context.Context, token string) (*Identity, error)
+}
+
+// RemoteValidator asks auth-service about every token, so logout and
+// revocation take effect immediately.
+type RemoteValidator struct {
+	authClient authpb.AuthServiceClient
+}
+
+func NewRemoteValidator(authClient authpb.AuthServiceClient) *RemoteValidator {
+	return &RemoteValidator{authClient: authClient}
+}
+
+func (v *RemoteValidator) Validate(ctx context.Context, token string) (*Identity, error) {
+	resp, err := v.authClient.ValidateToken(ctx, &authpb.ValidateTokenRequest{Token: token})
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Valid {
+		return nil, fmt.Errorf("invalid token")
+	}
+
+	return &Identity{
+		UserID:        resp.UserId,
+		EmailVerified: resp.EmailVerified,
+		Roles:         resp.Roles,
+
+		Scopes:              resp.Scopes,
+		PersonalAccessToken: resp.PersonalAccessToken,
+		SessionID:           resp.SessionId,
+	}, nil
+}
+
+// LocalValidator verifies token signatures against the auth-service JWKS
+// without a round trip per request. Tokens of revoked sessions stay valid until they expire.
+// Personal access tokens are opaque and tokens with roles beyond user grant
+// moderation or admin rights, so both are always checked by auth-service.
+type LocalValidator struct {
+	authClient authpb.AuthServiceClient
+	remote     *RemoteValidator
+	refresh    time.Duration
+
+	mu        sync.Mutex
+	set       *jwks.Set
+	fetchedAt time.Time
+}
+
+// NewLocalValidator creates a validator that refetches the key set every refresh
+// interval, or earlier when a token names an unknown kid.
+func NewLocalValidator(authClient authpb.AuthServiceClient, refresh time.Duration) *LocalValidator {
+	return &LocalValidator{
+		authClient: authClient,
+		remote:     NewRemoteValidator(authClient),
+		refresh:    refresh,
+	}
+}
+
+type accessClaims struct {
+	jwt.RegisteredClaims
+	EmailVerified bool     `json:"email_verified"`
+	Roles         []string `json:"roles,omitempty"`
+	SessionID     string   `json:"sid,omitempty"`
+}
+
+func (v *LocalValidator) Validate(ctx context.Context, token string) (*Identity, error) {
+	if strings.HasPrefix(token, personalAccessTokenPrefix) {
+		return v.remote.Validate(ctx, token)
+	}
+
+	claims := &accessClaims{}
+	parsed, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		key, err := v.key(ctx, kid)
+		if err != nil {
+			return nil, err
+		}
+		if key.Alg != "" && key.Alg != t.Method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method %s", t.Method.Alg())
+		}
+		return key.PublicKey()
+	}, jwt.WithValidMethods([]string{"RS256", "EdDSA"}), jwt.WithAudience(accessTokenAudience))
+	if err != nil || !parsed.Valid {
+		return nil, fmt.Errorf("invalid token")
+	}
+
+	// A revoked session or a removed role must not keep elevated rights until
+	// the token expires
+	if hasPrivilegedRole(claims.Roles) {
+		return v.remote.Validate(ctx, token)
+	}
+
+	userID, err := strconv.ParseUint(claims.Subject, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid token subject")
+	}
+
+	return &Identity{
+		UserID:        userID,
+		EmailVerified: claims.EmailVerified,
+		Roles:         claims.Roles,
+		SessionID:     claims.SessionID,
+	}, nil
+}
+
+// hasPrivilegedRole reports whether roles include any role beyond user.
+func hasPrivilegedRole(roles []string) bool {
+	for _, role := range roles {
+		if role != "user" {
+			return true
+		}
+	}
+	return false
+}
+
+// key returns the JWK for kid, refetching the set when it is stale or does not
+// contain kid. Refetches for unknown kids are limited to one per second.
+func (v *LocalValidator) key(ctx context.Context, kid string) (jwks.Key, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.set != nil && time.Since(v.fetchedAt) < v.refresh {
+		if key, ok := v.set.Find(kid); ok {
+			return key, nil
+		}
+		if time.Since(v.fetchedAt) < time.Second {
+			return jwks.Key{}, fmt.Errorf("unknown kid %q", kid)
+		}
+	}
+
+	if err := v.fetch(ctx); err != nil {
+		return jwks.Key{}, err
+	}
+	if key, ok := v.set.Find(kid); ok {
+		return key, nil
+	}
+	return jwks.Key{}, fmt.Errorf("unknown kid %q", kid)
+}
+
+func (v *LocalValidator) fetch(ctx context.Context) error {
+	resp, err := v.authClient.GetJWKS(ctx, &authpb.GetJWKSRequest{})
+	if err != nil {
+		return fmt.Errorf("failed to fetch jwks: %w", err)
+	}
+	if resp.Error != "" {
+		return fmt.Errorf("failed to fetch jwks: %s", resp.Error)
+	}
+
+	v.set = JWKSFromProto(resp.Keys)
+	v.fetchedAt = time.Now()
+	return nil
+}
+
+// JWKSFromProto converts the keys returned by GetJWKS.
+func JWKSFromProto(keys []*authpb.JWK) *jwks.Set {
+	set := &jwks.Set{Keys: make([]jwks.Key, 0, len(keys))}
+	for _, key := range keys {
+		set.Keys = append(set.Keys, jwks.Key{
+			Kty: key.Kty,
+			Kid: key.Kid,
+			Use: key.Use,
+			Alg: key.Alg,
+			N:   key.N,
+			E:   key.E,
+			Crv: key.Crv,
+			X:   key.X,
+		})
+	}
+	return set
+}
@@ -0,0 +1,274 @@
+// Package diff computes line-level unified diffs of article text.
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+type opKind int
+
+const (
+	opEqual opKind = iota
+	opDelete
+	opInsert
+)
+
+// op is one step of an edit script. a and b are the positions in the old and
+// new lines before the step.
+type op struct {
+	kind opKind
+	a, b int
+}
+
+// Unified returns the differences between two texts in unified diff format with
+// the given number of context lines, or an empty string if they are equal.
+func Unified(fromName, toName, from, to string, context int) string {
+	a, b := splitLines(from), splitLines(to)
+	ops := edits(a, b)
+
+	var out strings.Builder
+	for _, h := range hunks(ops, context) {
+		if out.Len() == 0 {
+			fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromName, toName)
+		}
+		writeHunk(&out, ops[h[0]:h[1]], a, b)
+	}
+	return out.String()
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// maxEditCost bounds the search for a shortest edit script. Past it, the rest of
+// the texts is diffed as a plain replacement, which keeps diffs of large,
+// heavily rewritten texts cheap at the cost of a longer diff.
+const maxEditCost = 4096
+
+// edits returns an edit script turning a into b. It is the shortest one unless
+// finding it costs more than maxEditCost.
+func edits(a, b []string) []op {
+	d := &differ{a: a, b: b}
+	size := len(a) + len(b) + 2
+	d.vf = make([]int, size)
+	d.vb = make([]int, size)
+	d.compare(0, len(a), 0, len(b))
+	return d.ops
+}
+
+// differ finds edit scripts with the linear space variant of Myers' algorithm,
+// which splits the texts at a point of a shortest path found by searching from
+// both ends at once.
+type differ struct {
+	a, b   []string
+	vf, vb []int // Furthest x per diagonal of the forward and backward searches
+	ops    []op
+}
+
+// compare appends the edit script turning a[aLo:aHi] into b[bLo:bHi].
+func (d *differ) compare(aLo, aHi, bLo, bHi int) {
+	for aLo < aHi && bLo < bHi && d.a[aLo] == d.b[bLo] {
+		d.ops = append(d.ops, op{kind: opEqual, a: aLo, b: bLo})
+		aLo++
+		bLo++
+	}
+	suffix := 0
+	for aLo < aHi-suffix && bLo < bHi-suffix && d.a[aHi-suffix-1] == d.b[bHi-suffix-1] {
+		suffix++
+	}
+	aHi -= suffix
+	bHi -= suffix
+
+	if aLo < aHi && bLo < bHi {
+		if x, y, ok := d.split(aLo, aHi, bLo, bHi); ok {
+			d.compare(aLo, x, bLo, y)
+			d.compare(x, aHi, y, bHi)
+		} else {
+			d.replace(aLo, aHi, bLo, bHi)
+		}
+	} else {
+		d.replace(aLo, aHi, bLo, bHi)
+	}
+
+	for i := 0; i < suffix; i++ {
+		d.ops = append(d.ops, op{kind: opEqual, a: aHi + i, b: bHi + i})
+	}
+}
+
+// replace appends the deletion of a[aLo:aHi] and the insertion of b[bLo:bHi].
+func (d *differ) replace(aLo, aHi, bLo, bHi int) {
+	for x := aLo; x < aHi; x++ {
+		d.ops = append(d.ops, op{kind: opDelete, a: x, b: bLo})
+	}
+	for y := bLo; y < bHi; y++ {
+		d.ops = append(d.ops, op{kind: opInsert, a: aHi, b: y})
+	}
+}
+
+// split returns a point on a shortest path through a[aLo:aHi] and b[bLo:bHi],
+// which must differ in their first and last lines. It reports false if the
+// texts have nothing in common or the search costs more than maxEditCost.
+func (d *differ) split(aLo, aHi, bLo, bHi int) (int, int, bool) {
+	n, m := aHi-aLo, bHi-bLo
+	maxD := (n + m + 1) / 2
+	offset := maxD
+	vf, vb := d.vf[:2*maxD+1], d.vb[:2*maxD+1]
+	for i := range vf {
+		vf[i], vb[i] = -1, -1
+	}
+	vf[offset+1], vb[offset+1] = 0, 0
+
+	delta := n - m
+	// With an odd delta the paths meet while extending forward, otherwise backward
+	front := delta%2 != 0
+	// Diagonals that ran off the grid are not extended any further
+	var kfStart, kfEnd, kbStart, kbEnd int
+
+	for step := 0; step < maxD && step <= maxEditCost; step++ {
+		for k := -step + kfStart; k <= step-kfEnd; k += 2 {
+			i := offset + k
+			var x int
+			if k == -step || (k != step && vf[i-1] < vf[i+1]) {
+				x = vf[i+1]
+			} else {
+				x = vf[i-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && d.a[aLo+x] == d.b[bLo+y] {
+				x++
+				y++
+			}
+			vf[i] = x
+
+			switch {
+			case x > n:
+				kfEnd += 2
+			case y > m:
+				kfStart += 2
+			case front:
+				j := offset + delta - k
+				if j >= 0 && j < len(vb) && vb[j] != -1 && x >= n-vb[j] {
+					return aLo + x, bLo + y, true
+				}
+			}
+		}
+
+		for k := -step + kbStart; k <= step-kbEnd; k += 2 {
+			i := offset + k
+			var x int
+			if k == -step || (k != step && vb[i-1] < vb[i+1]) {
+				x = vb[i+1]
+			} else {
+				x = vb[i-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && d.a[aHi-x-1] == d.b[bHi-y-1] {
+				x++
+				y++
+			}
+			vb[i] = x
+
+			switch {
+			case x > n:
+				kbEnd += 2
+			case y > m:
+				kbStart += 2
+			case !front:
+				j := offset + delta - k
+				if j >= 0 && j < len(vf) && vf[j] != -1 {
+					fx := vf[j]
+					fy := fx - (j - offset)
+					if fx >= n-x {
+						return aLo + fx, bLo + fy, true
+					}
+				}
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// hunks groups changes that are at most 2*context equal lines apart and returns
+// the [start, end) range of ops covered by each group with its context.
+func hunks(ops []op, context int) [][2]int {
+	var result [][2]int
+	prevEnd := 0
+
+	for i := 0; i < len(ops); {
+		if ops[i].kind == opEqual {
+			i++
+			continue
+		}
+
+		start := i - context
+		if start < prevEnd {
+			start = prevEnd
+		}
+
+		end := i
+		for j := i; j < len(ops); {
+			if ops[j].kind != opEqual {
+				j++
+				end = j
+				continue
+			}
+			k := j
+			for k < len(ops) && ops[k].kind == opEqual {
+				k++
+			}
+			if k == len(ops) || k-j > 2*context {
+				break
+			}
+			j = k
+		}
+
+		end += context
+		if end > len(ops) {
+			end = len(ops)
+		}
+		result = append(result, [2]int{start, end})
+		prevEnd, i = end, end
+	}
+	return result
+}
+
+func writeHunk(out *strings.Builder, ops []op, a, b []string) {
+	var fromCount, toCount int
+	for _, o := range ops {
+		if o.kind != opInsert {
+			fromCount++
+		}
+		if o.kind != opDelete {
+			toCount++
+		}
+	}
+
+	fmt.Fprintf(out, "@@ -%s +%s @@\n", hunkRange(ops[0].a, fromCount), hunkRange(ops[0].b, toCount))
+	for _, o := range ops {
+		switch o.kind {
+		case opEqual:
+			out.WriteString(" " + a[o.a] + "\n")
+		case opDelete:
+			out.WriteString("-" + a[o.a] + "\n")
+		case opInsert:
+			out.WriteString("+" + b[o.b] + "\n")
+		}
+	}
+}
+
+// hunkRange formats the 0-based start and line count of a hunk side as diff does.
+func hunkRange(start, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", start)
+	case 1:
+		return fmt.Sprintf("%d", start+1)
+	default:
+		return fmt.Sprintf("%d,%d", start+1, count)
+	}
+}
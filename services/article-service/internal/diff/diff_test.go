@@ -0,0 +1,112 @@
+package diff
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to string
+		want     string
+	}{
+		{
+			name: "identical",
+			from: "a\nb\nc\n",
+			to:   "a\nb\nc\n",
+			want: "",
+		},
+		{
+			name: "both empty",
+			want: "",
+		},
+		{
+			name: "from empty",
+			to:   "a\nb\n",
+			want: "--- old\n+++ new\n@@ -0,0 +1,2 @@\n+a\n+b\n",
+		},
+		{
+			name: "to empty",
+			from: "a\nb\n",
+			want: "--- old\n+++ new\n@@ -1,2 +0,0 @@\n-a\n-b\n",
+		},
+		{
+			name: "append only",
+			from: "a\nb\nc\nd\n",
+			to:   "a\nb\nc\nd\ne\nf\n",
+			want: "--- old\n+++ new\n@@ -2,3 +2,5 @@\n b\n c\n d\n+e\n+f\n",
+		},
+		{
+			name: "fully rewritten",
+			from: "a\nb\n",
+			to:   "c\nd\ne\n",
+			want: "--- old\n+++ new\n@@ -1,2 +1,3 @@\n-a\n-b\n+c\n+d\n+e\n",
+		},
+		{
+			name: "separate hunks",
+			from: "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n",
+			to:   "0\n2\n3\n4\n5\n6\n7\n8\n9\nX\n",
+			want: "--- old\n+++ new\n@@ -1,4 +1,4 @@\n-1\n+0\n 2\n 3\n 4\n@@ -7,4 +7,4 @@\n 7\n 8\n 9\n-10\n+X\n",
+		},
+		{
+			name: "crlf and missing final newline",
+			from: "a\r\nb",
+			to:   "a\nc\n",
+			want: "--- old\n+++ new\n@@ -1,2 +1,2 @@\n a\n-b\n+c\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Unified("old", "new", tt.from, tt.to, 3); got != tt.want {
+				t.Errorf("Unified() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestUnifiedLargeRewrite checks that diffing a long, fully rewritten text stays
+// within linear memory.
+func TestUnifiedLargeRewrite(t *testing.T) {
+	const lines = 8000
+	var from, to strings.Builder
+	for i := 0; i < lines; i++ {
+		fmt.Fprintf(&from, "old line %d\n", i)
+		fmt.Fprintf(&to, "new line %d\n", i)
+	}
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	got := Unified("old", "new", from.String(), to.String(), 3)
+	runtime.ReadMemStats(&after)
+
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 64<<20 {
+		t.Errorf("allocated %d MB", allocated>>20)
+	}
+	if want := 3 + 2*lines; strings.Count(got, "\n") != want {
+		t.Errorf("got %d lines, want %d", strings.Count(got, "\n"), want)
+	}
+	if !strings.HasPrefix(got, fmt.Sprintf("--- old\n+++ new\n@@ -1,%d +1,%d @@\n", lines, lines)) {
+		t.Errorf("unexpected header: %q", got[:60])
+	}
+}
+
+// TestEditsShortest checks that interleaved changes give a shortest script.
+func TestEditsShortest(t *testing.T) {
+	a := strings.Split("a b c a b b a", " ")
+	b := strings.Split("c b a b a c", " ")
+
+	changes := 0
+	for _, o := range edits(a, b) {
+		if o.kind != opEqual {
+			changes++
+		}
+	}
+	// The example from Myers' paper has an edit distance of 5
+	if changes != 5 {
+		t.Errorf("got %d changes, want 5", changes)
+	}
+}
@@ -2,6 +2,8 @@ package repository
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"time"
 
@@ -9,6 +11,10 @@ import (
 	"github.com/uptrace/bun"
 )
 
+// ErrVersionConflict means the article changed since the version the caller
+// based its update on.
+var ErrVersionConflict = errors.New("version conflict")
+
 type Visibility string
 
 const (
@@ -17,6 +23,17 @@ const (
 	VisibilityLink    Visibility = "link"
 )
 
+// Status is the publication state of an article. Only published articles are
+// shown to anyone but their author, whatever their visibility.
+type Status string
+
+const (
+	StatusDraft     Status = "draft"
+	StatusScheduled Status = "scheduled"
+	StatusPublished Status = "published"
+	StatusArchived  Status = "archived"
+)
+
 type Article struct {
 	bun.BaseModel `bun:"table:articles,alias:a"`
 
@@ -26,8 +43,61 @@ type Article struct {
 	Content     string     `bun:"content,notnull"`
 	Visibility  Visibility `bun:"visibility,notnull,default:'public'"`
 	AccessToken string     `bun:"access_token"`
+	Status      Status     `bun:"status,notnull,default:'published'"`
+	PublishedAt time.Time  `bun:"published_at,nullzero"`     // Set when the article first goes live
+	ScheduledAt time.Time  `bun:"scheduled_at,nullzero"`     // Only for scheduled articles
+	Version     int        `bun:"version,notnull,default:1"` // Incremented on every write
 	CreatedAt   time.Time  `bun:"created_at,nullzero,notnull,default:current_timestamp"`
 	UpdatedAt   time.Time  `bun:"updated_at,nullzero,notnull,default:current_timestamp"`
+
+	// Set while the article stays archived by a moderator, who alone may change its status then
+	ArchivedByModerator bool `bun:"archived_by_moderator,notnull,default:false"`
+
+	Tags []string `bun:"-"` // Slugs, kept in article_tags
+}
+
+// OwnedBy reports whether the user wrote the article. Articles of deleted
+// accounts are kept with user ID 0, which is also the ID of anonymous viewers,
+// so they are owned by nobody.
+func (a *Article) OwnedBy(userID uint64) bool {
+	return userID != 0 && a.UserID == userID
+}
+
+// applyStatus moves the article to status, or keeps the current one if status is
+// empty, and reports whether the article went live for the first time.
+// scheduledAt is only used by the scheduled status and must be in the future.
+func applyStatus(article *Article, status Status, scheduledAt, now time.Time) (bool, error) {
+	if status == "" {
+		if scheduledAt.IsZero() {
+			return false, nil
+		}
+		status = article.Status
+	}
+
+	switch status {
+	case StatusScheduled:
+		if !article.PublishedAt.IsZero() {
+			return false, fmt.Errorf("article is already published")
+		}
+		if scheduledAt.IsZero() {
+			return false, fmt.Errorf("scheduled_at is required for scheduled articles")
+		}
+		if !scheduledAt.After(now) {
+			return false, fmt.Errorf("scheduled_at must be in the future")
+		}
+		article.ScheduledAt = scheduledAt
+	case StatusDraft, StatusPublished, StatusArchived:
+		article.ScheduledAt = time.Time{}
+	default:
+		return false, fmt.Errorf("invalid status")
+	}
+
+	article.Status = status
+	if status == StatusPublished && article.PublishedAt.IsZero() {
+		article.PublishedAt = now
+		return true, nil
+	}
+	return false, nil
 }
 
 type ArticleRepository struct {
@@ -38,23 +108,82 @@ func NewArticleRepository(db *bun.DB) *ArticleRepository {
 	return &ArticleRepository{db: db}
 }
 
-func (r *ArticleRepository) Create(userID uint64, title, content string, visibility Visibility) (*Article, error) {
+// EnsureIndexes creates the index behind the list of published articles and the
+// full-text search index.
+func (r *ArticleRepository) EnsureIndexes() error {
 	ctx := context.Background()
 
+	_, err := r.db.ExecContext(ctx, "CREATE INDEX IF NOT EXISTS articles_status_published_at_idx ON articles (status, published_at)")
+	if err != nil {
+		return fmt.Errorf("failed to create article index: %w", err)
+	}
+
+	_, err = r.db.ExecContext(ctx, "CREATE INDEX IF NOT EXISTS articles_search_vector_idx ON articles USING GIN (search_vector)")
+	if err != nil {
+		return fmt.Errorf("failed to create search index: %w", err)
+	}
+	return nil
+}
+
+// BackfillPublishedAt dates the articles published before publication times
+// were tracked by their creation.
+func (r *ArticleRepository) BackfillPublishedAt() error {
+	ctx := context.Background()
+
+	_, err := r.db.NewUpdate().
+		Model((*Article)(nil)).
+		Set("published_at = created_at").
+		Where("status = ? AND published_at IS NULL", StatusPublished).
+		Exec(ctx)
+
+	if err != nil {
+		return fmt.Errorf("failed to backfill published_at: %w", err)
+	}
+	return nil
+}
+
+// Create saves a new article with the given tags. An empty status publishes it
+// right away.
+func (r *ArticleRepository) Create(userID uint64, title, content string, visibility Visibility, status Status, scheduledAt time.Time, tags []string) (*Article, error) {
+	ctx := context.Background()
+	now := time.Now()
+
+	slugs, err := normalizeTags(tags)
+	if err != nil {
+		return nil, err
+	}
+
 	article := &Article{
 		UserID:     userID,
 		Title:      title,
 		Content:    content,
 		Visibility: visibility,
-		CreatedAt:  time.Now(),
-		UpdatedAt:  time.Now(),
+		Version:    1,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+		Tags:       slugs,
 	}
 
 	if visibility == VisibilityLink {
 		article.AccessToken = uuid.New().String()
 	}
 
-	_, err := r.db.NewInsert().Model(article).Exec(ctx)
+	if status == "" {
+		status = StatusPublished
+	}
+	if _, err := applyStatus(article, status, scheduledAt, now); err != nil {
+		return nil, err
+	}
+
+	err = r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if _, err := tx.NewInsert().Model(article).Exec(ctx); err != nil {
+			return err
+		}
+		if err := setTags(ctx, tx, article.ID, slugs); err != nil {
+			return err
+		}
+		return addRevision(ctx, tx, article, nil, userID, 0)
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create article: %w", err)
 	}
@@ -75,92 +204,365 @@ func (r *ArticleRepository) GetByID(id uint64) (*Article, error) {
 		return nil, fmt.Errorf("article not found: %w", err)
 	}
 
+	if err := loadTags(ctx, r.db, article); err != nil {
+		return nil, err
+	}
 	return article, nil
 }
 
-func (r *ArticleRepository) Update(id, userID uint64, title, content string, visibility Visibility) (*Article, error) {
+// GetByIDs returns the articles with the given IDs. Missing ones are skipped.
+func (r *ArticleRepository) GetByIDs(ids []uint64) ([]*Article, error) {
 	ctx := context.Background()
+	var articles []*Article
+
+	if len(ids) == 0 {
+		return articles, nil
+	}
+
+	err := r.db.NewSelect().
+		Model(&articles).
+		Where("id IN (?)", bun.In(ids)).
+		Scan(ctx)
 
-	// Get existing article to check access token
-	existing, err := r.GetByID(id)
 	if err != nil {
+		return nil, fmt.Errorf("failed to get articles: %w", err)
+	}
+
+	if err := loadTags(ctx, r.db, articles...); err != nil {
 		return nil, err
 	}
+	return articles, nil
+}
+
+// lockOwned loads an article of the user and locks it until the end of the
+// transaction, so that concurrent saves get consecutive revisions.
+func lockOwned(ctx context.Context, tx bun.Tx, id, userID uint64) (*Article, error) {
+	article := new(Article)
+
+	err := tx.NewSelect().
+		Model(article).
+		Where("id = ?", id).
+		For("UPDATE").
+		Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("article not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get article: %w", err)
+	}
 
 	// Check ownership
-	if existing.UserID != userID {
+	if !article.OwnedBy(userID) {
 		return nil, fmt.Errorf("unauthorized")
 	}
+	return article, nil
+}
 
-	// Generate new access token if changing to link visibility
-	accessToken := existing.AccessToken
-	if visibility == VisibilityLink && existing.Visibility != VisibilityLink {
-		accessToken = uuid.New().String()
-	} else if visibility != VisibilityLink {
-		accessToken = ""
+// Update changes an article of the user and records the new text as a revision.
+// An empty status keeps the current one, and the tags are only changed if
+// replaceTags is set. Unless expectedVersion is 0, the article
+// must still be at that version; otherwise ErrVersionConflict is returned along
+// with the current article. An article archived by a moderator keeps its status
+// unless moderator is set. It reports whether the article went live for the
+// first time.
+func (r *ArticleRepository) Update(id, userID uint64, title, content string, visibility Visibility, status Status, scheduledAt time.Time, tags []string, replaceTags bool, expectedVersion int, moderator bool) (*Article, bool, error) {
+	ctx := context.Background()
+	var (
+		existing  *Article
+		published bool
+		slugs     []string
+	)
+
+	if replaceTags {
+		var err error
+		if slugs, err = normalizeTags(tags); err != nil {
+			return nil, false, err
+		}
 	}
 
-	existing.Title = title
-	existing.Content = content
-	existing.Visibility = visibility
-	existing.AccessToken = accessToken
-	existing.UpdatedAt = time.Now()
+	err := r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		var err error
+		existing, err = lockOwned(ctx, tx, id, userID)
+		if err != nil {
+			return err
+		}
+		if expectedVersion != 0 && existing.Version != expectedVersion {
+			return ErrVersionConflict
+		}
+		before := *existing
+
+		now := time.Now()
+		published, err = applyStatus(existing, status, scheduledAt, now)
+		if err != nil {
+			return err
+		}
+		if existing.ArchivedByModerator && existing.Status != StatusArchived {
+			if !moderator {
+				return fmt.Errorf("article was archived by a moderator")
+			}
+			existing.ArchivedByModerator = false
+		}
+
+		// Generate new access token if changing to link visibility
+		accessToken := existing.AccessToken
+		if visibility == VisibilityLink && existing.Visibility != VisibilityLink {
+			accessToken = uuid.New().String()
+		} else if visibility != VisibilityLink {
+			accessToken = ""
+		}
+
+		existing.Title = title
+		existing.Content = content
+		existing.Visibility = visibility
+		existing.AccessToken = accessToken
+		existing.Version++
+		existing.UpdatedAt = now
+
+		if _, err := tx.NewUpdate().Model(existing).WherePK().Exec(ctx); err != nil {
+			return fmt.Errorf("failed to update article: %w", err)
+		}
+		if replaceTags {
+			if err := setTags(ctx, tx, existing.ID, slugs); err != nil {
+				return err
+			}
+			existing.Tags = slugs
+		} else if err := loadTags(ctx, tx, existing); err != nil {
+			return err
+		}
+		return addRevision(ctx, tx, existing, &before, userID, 0)
+	})
+	if errors.Is(err, ErrVersionConflict) {
+		if err := loadTags(ctx, r.db, existing); err != nil {
+			return nil, false, err
+		}
+		return existing, false, ErrVersionConflict
+	}
+	if err != nil {
+		return nil, false, err
+	}
 
-	_, err = r.db.NewUpdate().
-		Model(existing).
-		WherePK().
-		Exec(ctx)
+	return existing, published, nil
+}
 
+// Restore brings the title and content of an article of the user back to those
+// of a revision, which is recorded as a new revision. Like Update, it returns
+// ErrVersionConflict with the current article unless expectedVersion is 0 or
+// the version of the article.
+func (r *ArticleRepository) Restore(id, userID uint64, number, expectedVersion int) (*Article, error) {
+	ctx := context.Background()
+	var article *Article
+
+	err := r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		var err error
+		article, err = lockOwned(ctx, tx, id, userID)
+		if err != nil {
+			return err
+		}
+		if expectedVersion != 0 && article.Version != expectedVersion {
+			return ErrVersionConflict
+		}
+
+		revision := new(ArticleRevision)
+		err = tx.NewSelect().
+			Model(revision).
+			Where("article_id = ? AND number = ?", id, number).
+			Scan(ctx)
+		if err != nil {
+			return fmt.Errorf("revision not found")
+		}
+
+		article.Title = revision.Title
+		article.Content = revision.Content
+		article.Version++
+		article.UpdatedAt = time.Now()
+
+		_, err = tx.NewUpdate().
+			Model(article).
+			Column("title", "content", "version", "updated_at").
+			WherePK().
+			Exec(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to restore article: %w", err)
+		}
+		if err := loadTags(ctx, tx, article); err != nil {
+			return err
+		}
+		return addRevision(ctx, tx, article, nil, userID, number)
+	})
+	if errors.Is(err, ErrVersionConflict) {
+		if err := loadTags(ctx, r.db, article); err != nil {
+			return nil, err
+		}
+		return article, ErrVersionConflict
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to update article: %w", err)
+		return nil, err
 	}
 
-	return existing, nil
+	return article, nil
 }
 
 func (r *ArticleRepository) Delete(id, userID uint64) error {
 	ctx := context.Background()
 
-	result, err := r.db.NewDelete().
-		Model((*Article)(nil)).
-		Where("id = ? AND user_id = ?", id, userID).
-		Exec(ctx)
+	return r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		result, err := tx.NewDelete().
+			Model((*Article)(nil)).
+			Where("id = ? AND user_id = ?", id, userID).
+			Exec(ctx)
+
+		if err != nil {
+			return fmt.Errorf("failed to delete article: %w", err)
+		}
+
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get affected rows: %w", err)
+		}
+
+		if rows == 0 {
+			return fmt.Errorf("article not found or unauthorized")
+		}
+
+		if err := deleteArticleTags(ctx, tx, []uint64{id}); err != nil {
+			return err
+		}
+		return deleteRevisions(ctx, tx, []uint64{id})
+	})
+}
 
-	if err != nil {
-		return fmt.Errorf("failed to delete article: %w", err)
-	}
+// DeleteAny deletes an article regardless of its owner. Used for moderation.
+func (r *ArticleRepository) DeleteAny(id uint64) error {
+	ctx := context.Background()
 
-	rows, err := result.RowsAffected()
+	return r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		result, err := tx.NewDelete().
+			Model((*Article)(nil)).
+			Where("id = ?", id).
+			Exec(ctx)
+
+		if err != nil {
+			return fmt.Errorf("failed to delete article: %w", err)
+		}
+
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get affected rows: %w", err)
+		}
+
+		if rows == 0 {
+			return fmt.Errorf("article not found")
+		}
+
+		if err := deleteArticleTags(ctx, tx, []uint64{id}); err != nil {
+			return err
+		}
+		return deleteRevisions(ctx, tx, []uint64{id})
+	})
+}
+
+// SetStatus changes the status regardless of the owner and marks an archived
+// article as archived by a moderator. Used for moderation.
+func (r *ArticleRepository) SetStatus(id uint64, status Status) (*Article, error) {
+	ctx := context.Background()
+	article := &Article{ID: id}
+
+	err := r.db.NewUpdate().
+		Model(article).
+		Set("status = ?", status).
+		Set("scheduled_at = NULL").
+		Set("archived_by_moderator = ?", status == StatusArchived).
+		Set("version = version + 1").
+		Set("updated_at = ?", time.Now()).
+		WherePK().
+		Returning("?TableColumns").
+		Scan(ctx)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("article not found")
+	}
 	if err != nil {
-		return fmt.Errorf("failed to get affected rows: %w", err)
+		return nil, fmt.Errorf("failed to set article status: %w", err)
 	}
 
-	if rows == 0 {
-		return fmt.Errorf("article not found or unauthorized")
+	if err := loadTags(ctx, r.db, article); err != nil {
+		return nil, err
 	}
-
-	return nil
+	return article, nil
 }
 
-func (r *ArticleRepository) List(limit, offset int) ([]*Article, error) {
+// PublishDue publishes the scheduled articles whose time has come and returns
+// them. Every article is returned once, even when several instances run this.
+func (r *ArticleRepository) PublishDue(now time.Time) ([]*Article, error) {
 	ctx := context.Background()
 	var articles []*Article
 
-	err := r.db.NewSelect().
-		Model(&articles).
-		Where("visibility = ?", VisibilityPublic).
-		Order("created_at DESC").
-		Limit(limit).
-		Offset(offset).
-		Scan(ctx)
+	err := r.db.NewUpdate().
+		Model((*Article)(nil)).
+		Set("status = ?", StatusPublished).
+		Set("published_at = ?", now).
+		Set("scheduled_at = NULL").
+		Set("version = version + 1").
+		Set("updated_at = ?", now).
+		Where("status = ? AND scheduled_at <= ?", StatusScheduled, now).
+		Returning("?TableColumns").
+		Scan(ctx, &articles)
 
 	if err != nil {
+		return nil, fmt.Errorf("failed to publish scheduled articles: %w", err)
+	}
+	return articles, nil
+}
+
+// List returns published public articles, most recently published first, without
+// those of authors the viewer has blocked or muted or who blocked the viewer.
+func (r *ArticleRepository) List(viewerID uint64, limit, offset int) ([]*Article, error) {
+	ctx := context.Background()
+	var articles []*Article
+
+	query := r.listQuery(&articles, viewerID, limit, offset)
+	if err := query.Scan(ctx); err != nil {
 		return nil, fmt.Errorf("failed to list articles: %w", err)
 	}
 
+	if err := loadTags(ctx, r.db, articles...); err != nil {
+		return nil, err
+	}
+	return articles, nil
+}
+
+// ListByTag is List narrowed down to the articles with the tag.
+func (r *ArticleRepository) ListByTag(slug string, viewerID uint64, limit, offset int) ([]*Article, error) {
+	ctx := context.Background()
+	var articles []*Article
+
+	query := r.listQuery(&articles, viewerID, limit, offset).
+		Where("a.id IN (SELECT at.article_id FROM article_tags AS at JOIN tags AS t ON t.id = at.tag_id WHERE t.slug = ?)", slug)
+	if err := query.Scan(ctx); err != nil {
+		return nil, fmt.Errorf("failed to list articles by tag: %w", err)
+	}
+
+	if err := loadTags(ctx, r.db, articles...); err != nil {
+		return nil, err
+	}
 	return articles, nil
 }
 
+// listQuery selects the page of published public articles the viewer may see.
+func (r *ArticleRepository) listQuery(articles *[]*Article, viewerID uint64, limit, offset int) *bun.SelectQuery {
+	query := r.db.NewSelect().
+		Model(articles).
+		Where("status = ?", StatusPublished).
+		Where("visibility = ?", VisibilityPublic).
+		Order("published_at DESC", "id DESC").
+		Limit(limit).
+		Offset(offset)
+
+	return hideBlocked(query, "a.user_id", viewerID)
+}
+
+// GetByUser returns the articles of a user. The author also sees unpublished
+// ones, which come first.
 func (r *ArticleRepository) GetByUser(userID, viewerID uint64) ([]*Article, error) {
 	ctx := context.Background()
 	var articles []*Article
@@ -168,11 +570,13 @@ func (r *ArticleRepository) GetByUser(userID, viewerID uint64) ([]*Article, erro
 	query := r.db.NewSelect().
 		Model(&articles).
 		Where("user_id = ?", userID).
-		Order("created_at DESC")
+		OrderExpr("published_at DESC NULLS FIRST, created_at DESC")
 
-	if userID != viewerID {
-		// Other user viewing - show only public
-		query = query.Where("visibility = ?", VisibilityPublic)
+	if userID == 0 || userID != viewerID {
+		// Other user viewing - show only published public
+		query = query.
+			Where("status = ?", StatusPublished).
+			Where("visibility = ?", VisibilityPublic)
 	}
 
 	err := query.Scan(ctx)
@@ -180,6 +584,9 @@ func (r *ArticleRepository) GetByUser(userID, viewerID uint64) ([]*Article, erro
 		return nil, fmt.Errorf("failed to get user articles: %w", err)
 	}
 
+	if err := loadTags(ctx, r.db, articles...); err != nil {
+		return nil, err
+	}
 	return articles, nil
 }
 
@@ -189,6 +596,11 @@ func (r *ArticleRepository) CheckAccess(articleID, viewerID uint64, accessToken
 		return false, err
 	}
 
+	// Unpublished articles are only accessible to the owner
+	if article.Status != StatusPublished {
+		return article.OwnedBy(viewerID), nil
+	}
+
 	// Public articles are accessible to everyone
 	if article.Visibility == VisibilityPublic {
 		return true, nil
@@ -196,13 +608,79 @@ func (r *ArticleRepository) CheckAccess(articleID, viewerID uint64, accessToken
 
 	// Private articles only accessible to owner
 	if article.Visibility == VisibilityPrivate {
-		return article.UserID == viewerID, nil
+		return article.OwnedBy(viewerID), nil
 	}
 
 	// Link articles accessible with token or to owner
 	if article.Visibility == VisibilityLink {
-		return article.UserID == viewerID || article.AccessToken == accessToken, nil
+		return article.OwnedBy(viewerID) || article.AccessToken == accessToken, nil
 	}
 
 	return false, nil
 }
+
+// DeleteByUser deletes every article of a user with its history and returns
+// their IDs.
+func (r *ArticleRepository) DeleteByUser(userID uint64) ([]uint64, error) {
+	ctx := context.Background()
+	var ids []uint64
+
+	err := r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if err := tx.NewDelete().
+			Model((*Article)(nil)).
+			Where("user_id = ?", userID).
+			Returning("id").
+			Scan(ctx, &ids); err != nil {
+			return err
+		}
+		if err := deleteArticleTags(ctx, tx, ids); err != nil {
+			return err
+		}
+		return deleteRevisions(ctx, tx, ids)
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete user articles: %w", err)
+	}
+	return ids, nil
+}
+
+// AnonymizeByUser detaches the published public articles of a user from their account and
+// deletes the rest, which nobody but the author could read. The history of all of
+// them is dropped. It returns the IDs of the deleted articles.
+func (r *ArticleRepository) AnonymizeByUser(userID uint64) ([]uint64, error) {
+	ctx := context.Background()
+	var ids []uint64
+
+	err := r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if _, err := tx.NewDelete().
+			Model((*ArticleRevision)(nil)).
+			Where("article_id IN (?)", tx.NewSelect().Model((*Article)(nil)).Column("id").Where("user_id = ?", userID)).
+			Exec(ctx); err != nil {
+			return err
+		}
+
+		if err := tx.NewDelete().
+			Model((*Article)(nil)).
+			Where("user_id = ? AND (visibility != ? OR status != ?)", userID, VisibilityPublic, StatusPublished).
+			Returning("id").
+			Scan(ctx, &ids); err != nil {
+			return err
+		}
+		if err := deleteArticleTags(ctx, tx, ids); err != nil {
+			return err
+		}
+
+		_, err := tx.NewUpdate().
+			Model((*Article)(nil)).
+			Set("user_id = 0").
+			Set("version = version + 1").
+			Where("user_id = ?", userID).
+			Exec(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to anonymize user articles: %w", err)
+	}
+	return ids, nil
+}
@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// Kinds of blocks, as in auth-service.
+const (
+	BlockKindBlock = "block"
+	BlockKindMute  = "mute"
+)
+
+// Block is a copy of a block or mute owned by auth-service, kept up to date from
+// user events so that lists can be filtered without calling auth-service.
+type Block struct {
+	bun.BaseModel `bun:"table:article_blocks,alias:ab"`
+
+	BlockerID uint64    `bun:"blocker_id,pk"`
+	BlockedID uint64    `bun:"blocked_id,pk"`
+	Kind      string    `bun:"kind,pk"`
+	CreatedAt time.Time `bun:"created_at,nullzero,notnull,default:current_timestamp"`
+}
+
+type BlockRepository struct {
+	db *bun.DB
+}
+
+func NewBlockRepository(db *bun.DB) *BlockRepository {
+	return &BlockRepository{db: db}
+}
+
+func (r *BlockRepository) Add(blockerID, blockedID uint64, kind string) error {
+	ctx := context.Background()
+
+	block := &Block{
+		BlockerID: blockerID,
+		BlockedID: blockedID,
+		Kind:      kind,
+		CreatedAt: time.Now(),
+	}
+	_, err := r.db.NewInsert().
+		Model(block).
+		On("CONFLICT DO NOTHING").
+		Exec(ctx)
+
+	if err != nil {
+		return fmt.Errorf("failed to save %s: %w", kind, err)
+	}
+	return nil
+}
+
+func (r *BlockRepository) Remove(blockerID, blockedID uint64, kind string) error {
+	ctx := context.Background()
+
+	_, err := r.db.NewDelete().
+		Model((*Block)(nil)).
+		Where("blocker_id = ? AND blocked_id = ? AND kind = ?", blockerID, blockedID, kind).
+		Exec(ctx)
+
+	if err != nil {
+		return fmt.Errorf("failed to remove %s: %w", kind, err)
+	}
+	return nil
+}
+
+// IsBlocked reports whether blockerID has blocked blockedID.
+func (r *BlockRepository) IsBlocked(blockerID, blockedID uint64) (bool, error) {
+	ctx := context.Background()
+
+	exists, err := r.db.NewSelect().
+		Model((*Block)(nil)).
+		Where("blocker_id = ? AND blocked_id = ? AND kind = ?", blockerID, blockedID, BlockKindBlock).
+		Exists(ctx)
+
+	if err != nil {
+		return false, fmt.Errorf("failed to check block: %w", err)
+	}
+	return exists, nil
+}
+
+// RemoveUser drops every block and mute of and against the user.
+func (r *BlockRepository) RemoveUser(userID uint64) error {
+	ctx := context.Background()
+
+	_, err := r.db.NewDelete().
+		Model((*Block)(nil)).
+		Where("blocker_id = ? OR blocked_id = ?", userID, userID).
+		Exec(ctx)
+
+	if err != nil {
+		return fmt.Errorf("failed to remove blocks: %w", err)
+	}
+	return nil
+}
+
+// hideBlocked filters out rows whose author (in column) has blocked the viewer,
+// or was blocked or muted by the viewer. Anonymous viewers see everything.
+func hideBlocked(query *bun.SelectQuery, column string, viewerID uint64) *bun.SelectQuery {
+	if viewerID == 0 {
+		return query
+	}
+
+	return query.
+		Where("? NOT IN (SELECT blocker_id FROM article_blocks WHERE blocked_id = ? AND kind = ?)", bun.Ident(column), viewerID, BlockKindBlock).
+		Where("? NOT IN (SELECT blocked_id FROM article_blocks WHERE blocker_id = ?)", bun.Ident(column), viewerID)
+}
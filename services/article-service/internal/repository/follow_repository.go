@@ -0,0 +1,204 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// Follow means FollowerID sees the new public articles of FolloweeID in their feed.
+type Follow struct {
+	bun.BaseModel `bun:"table:follows,alias:f"`
+
+	FollowerID uint64    `bun:"follower_id,pk"`
+	FolloweeID uint64    `bun:"followee_id,pk"`
+	CreatedAt  time.Time `bun:"created_at,nullzero,notnull,default:current_timestamp"`
+}
+
+// FeedItem is an article delivered to a reader's feed when it was published.
+type FeedItem struct {
+	bun.BaseModel `bun:"table:feed_items,alias:fi"`
+
+	ID        uint64    `bun:"id,pk,autoincrement"`
+	UserID    uint64    `bun:"user_id,notnull,unique:feed_items_user_article"`
+	ArticleID uint64    `bun:"article_id,notnull,unique:feed_items_user_article"`
+	AuthorID  uint64    `bun:"author_id,notnull"`
+	CreatedAt time.Time `bun:"created_at,nullzero,notnull,default:current_timestamp"`
+}
+
+type FollowRepository struct {
+	db *bun.DB
+}
+
+func NewFollowRepository(db *bun.DB) *FollowRepository {
+	return &FollowRepository{db: db}
+}
+
+// Follow records the follow. Following someone twice is not an error.
+func (r *FollowRepository) Follow(followerID, followeeID uint64) error {
+	ctx := context.Background()
+
+	follow := &Follow{
+		FollowerID: followerID,
+		FolloweeID: followeeID,
+		CreatedAt:  time.Now(),
+	}
+	_, err := r.db.NewInsert().
+		Model(follow).
+		On("CONFLICT DO NOTHING").
+		Exec(ctx)
+
+	if err != nil {
+		return fmt.Errorf("failed to follow user: %w", err)
+	}
+	return nil
+}
+
+// Unfollow removes the follow and the followee's articles from the follower's feed.
+// It reports whether the follow existed.
+func (r *FollowRepository) Unfollow(followerID, followeeID uint64) (bool, error) {
+	ctx := context.Background()
+	var removed bool
+
+	err := r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		res, err := tx.NewDelete().
+			Model((*Follow)(nil)).
+			Where("follower_id = ? AND followee_id = ?", followerID, followeeID).
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		removed = rows > 0
+
+		_, err = tx.NewDelete().
+			Model((*FeedItem)(nil)).
+			Where("user_id = ? AND author_id = ?", followerID, followeeID).
+			Exec(ctx)
+		return err
+	})
+
+	if err != nil {
+		return false, fmt.Errorf("failed to unfollow user: %w", err)
+	}
+	return removed, nil
+}
+
+// ListFollowers returns who follows the user, newest first, and their total count.
+func (r *FollowRepository) ListFollowers(userID uint64, limit, offset int) ([]*Follow, int, error) {
+	ctx := context.Background()
+	var follows []*Follow
+
+	total, err := r.db.NewSelect().
+		Model(&follows).
+		Where("followee_id = ?", userID).
+		Order("created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		ScanAndCount(ctx)
+
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list followers: %w", err)
+	}
+	return follows, total, nil
+}
+
+// ListFollowing returns whom the user follows, newest first, and their total count.
+func (r *FollowRepository) ListFollowing(userID uint64, limit, offset int) ([]*Follow, int, error) {
+	ctx := context.Background()
+	var follows []*Follow
+
+	total, err := r.db.NewSelect().
+		Model(&follows).
+		Where("follower_id = ?", userID).
+		Order("created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		ScanAndCount(ctx)
+
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list following: %w", err)
+	}
+	return follows, total, nil
+}
+
+// FanOut delivers an article to the feeds of everyone following its author.
+// Redelivered events do not create duplicates.
+func (r *FollowRepository) FanOut(articleID, authorID uint64, publishedAt time.Time) (int64, error) {
+	ctx := context.Background()
+
+	res, err := r.db.NewRaw(`
+		INSERT INTO feed_items (user_id, article_id, author_id, created_at)
+		SELECT follower_id, ?, ?, ? FROM follows WHERE followee_id = ?
+		ON CONFLICT DO NOTHING
+	`, articleID, authorID, publishedAt, authorID).Exec(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fan out article: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// Feed returns the newest feed items of the user with an ID below beforeID
+// (0 starts from the newest). Items of authors muted or blocked since are skipped.
+func (r *FollowRepository) Feed(userID, beforeID uint64, limit int) ([]*FeedItem, error) {
+	ctx := context.Background()
+	var items []*FeedItem
+
+	query := r.db.NewSelect().
+		Model(&items).
+		Where("user_id = ?", userID).
+		Order("id DESC").
+		Limit(limit)
+	if beforeID > 0 {
+		query = query.Where("id < ?", beforeID)
+	}
+
+	if err := hideBlocked(query, "fi.author_id", userID).Scan(ctx); err != nil {
+		return nil, fmt.Errorf("failed to get feed: %w", err)
+	}
+	return items, nil
+}
+
+// RemoveArticle takes a deleted article out of every feed.
+func (r *FollowRepository) RemoveArticle(articleID uint64) error {
+	ctx := context.Background()
+
+	_, err := r.db.NewDelete().
+		Model((*FeedItem)(nil)).
+		Where("article_id = ?", articleID).
+		Exec(ctx)
+
+	if err != nil {
+		return fmt.Errorf("failed to remove article from feeds: %w", err)
+	}
+	return nil
+}
+
+// RemoveUser drops every follow of and by the user and their feed.
+func (r *FollowRepository) RemoveUser(userID uint64) error {
+	ctx := context.Background()
+
+	err := r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if _, err := tx.NewDelete().
+			Model((*Follow)(nil)).
+			Where("follower_id = ? OR followee_id = ?", userID, userID).
+			Exec(ctx); err != nil {
+			return err
+		}
+		_, err := tx.NewDelete().
+			Model((*FeedItem)(nil)).
+			Where("user_id = ? OR author_id = ?", userID, userID).
+			Exec(ctx)
+		return err
+	})
+
+	if err != nil {
+		return fmt.Errorf("failed to remove follows: %w", err)
+	}
+	return nil
+}
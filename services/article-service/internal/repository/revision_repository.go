@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// ArticleRevision is the title and content of an article as saved at one point.
+// Every save adds a revision; revisions are numbered from 1 per article.
+type ArticleRevision struct {
+	bun.BaseModel `bun:"table:article_revisions,alias:ar"`
+
+	ID           uint64    `bun:"id,pk,autoincrement"`
+	ArticleID    uint64    `bun:"article_id,notnull,unique:article_revisions_article_number"`
+	Number       int       `bun:"number,notnull,unique:article_revisions_article_number"`
+	AuthorID     uint64    `bun:"author_id,notnull"`
+	Title        string    `bun:"title,notnull"`
+	Content      string    `bun:"content,notnull"`
+	RestoredFrom int       `bun:"restored_from,nullzero"` // Revision this one was restored from
+	CreatedAt    time.Time `bun:"created_at,nullzero,notnull,default:current_timestamp"`
+}
+
+type RevisionRepository struct {
+	db *bun.DB
+}
+
+func NewRevisionRepository(db *bun.DB) *RevisionRepository {
+	return &RevisionRepository{db: db}
+}
+
+// List returns the revisions of an article without their content, newest first,
+// and their total count.
+func (r *RevisionRepository) List(articleID uint64, limit, offset int) ([]*ArticleRevision, int, error) {
+	ctx := context.Background()
+	var revisions []*ArticleRevision
+
+	total, err := r.db.NewSelect().
+		Model(&revisions).
+		ExcludeColumn("content").
+		Where("article_id = ?", articleID).
+		Order("number DESC").
+		Limit(limit).
+		Offset(offset).
+		ScanAndCount(ctx)
+
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list revisions: %w", err)
+	}
+	return revisions, total, nil
+}
+
+func (r *RevisionRepository) Get(articleID uint64, number int) (*ArticleRevision, error) {
+	ctx := context.Background()
+	revision := new(ArticleRevision)
+
+	err := r.db.NewSelect().
+		Model(revision).
+		Where("article_id = ? AND number = ?", articleID, number).
+		Scan(ctx)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("revision not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get revision: %w", err)
+	}
+	return revision, nil
+}
+
+// addRevision records the current title and content of the article as its next
+// revision. Articles saved before revisions existed first get their previous
+// state, passed as before, recorded as revision 1, so that the first update
+// does not lose it. The caller must hold a lock on the article row.
+func addRevision(ctx context.Context, db bun.IDB, article, before *Article, authorID uint64, restoredFrom int) error {
+	var last int
+	err := db.NewSelect().
+		Model((*ArticleRevision)(nil)).
+		ColumnExpr("COALESCE(MAX(number), 0)").
+		Where("article_id = ?", article.ID).
+		Scan(ctx, &last)
+	if err != nil {
+		return fmt.Errorf("failed to number revision: %w", err)
+	}
+
+	var revisions []*ArticleRevision
+	if last == 0 && before != nil {
+		last++
+		revisions = append(revisions, &ArticleRevision{
+			ArticleID: before.ID,
+			Number:    last,
+			AuthorID:  before.UserID,
+			Title:     before.Title,
+			Content:   before.Content,
+			CreatedAt: before.UpdatedAt,
+		})
+	}
+	revisions = append(revisions, &ArticleRevision{
+		ArticleID:    article.ID,
+		Number:       last + 1,
+		AuthorID:     authorID,
+		Title:        article.Title,
+		Content:      article.Content,
+		RestoredFrom: restoredFrom,
+		CreatedAt:    article.UpdatedAt,
+	})
+
+	if _, err := db.NewInsert().Model(&revisions).Exec(ctx); err != nil {
+		return fmt.Errorf("failed to save revision: %w", err)
+	}
+	return nil
+}
+
+// deleteRevisions drops the history of the given articles.
+func deleteRevisions(ctx context.Context, db bun.IDB, articleIDs []uint64) error {
+	if len(articleIDs) == 0 {
+		return nil
+	}
+
+	_, err := db.NewDelete().
+		Model((*ArticleRevision)(nil)).
+		Where("article_id IN (?)", bun.In(articleIDs)).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to delete revisions: %w", err)
+	}
+	return nil
+}
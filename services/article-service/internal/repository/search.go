@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// SearchConfig is the text search configuration of the search index. Besides
+// Russian stemming it stems Latin words as English.
+const SearchConfig = "russian"
+
+// SearchVectorColumn defines the generated articles.search_vector column, with
+// titles weighted above content. The column is not part of Article, so queries
+// returning articles must name their columns rather than use RETURNING *.
+const SearchVectorColumn = "tsvector GENERATED ALWAYS AS (" +
+	"setweight(to_tsvector('" + SearchConfig + "', coalesce(title, '')), 'A') || " +
+	"setweight(to_tsvector('" + SearchConfig + "', coalesce(content, '')), 'B')) STORED"
+
+// Highlight markers put around matches in snippets. Control characters do not
+// occur in article text, so callers can escape the snippet and then replace them.
+const (
+	HighlightStart = "\x02"
+	HighlightStop  = "\x03"
+)
+
+// SearchFilter narrows down a search. Zero values do not filter.
+type SearchFilter struct {
+	AuthorID uint64
+	Tag      string    // Slug
+	Since    time.Time // Published at or after
+	Until    time.Time // Published before
+}
+
+// SearchResult is an article matching a search with its rank and the parts of
+// its title and content that matched.
+type SearchResult struct {
+	Article `bun:",extend"`
+
+	Rank           float64 `bun:"rank,scanonly"`
+	TitleHighlight string  `bun:"title_highlight,scanonly"`
+	Snippet        string  `bun:"snippet,scanonly"`
+}
+
+// Search returns a page of the articles matching a web search style query (quoted
+// phrases, "or", "-" to exclude), best matches first, and their total count.
+// Like CheckAccess, it finds published public articles and the viewer's own
+// articles; authors who blocked the viewer or whom the viewer blocked or muted
+// are left out, as in List.
+func (r *ArticleRepository) Search(query string, viewerID uint64, filter SearchFilter, limit, offset int) ([]*SearchResult, int, error) {
+	ctx := context.Background()
+	var results []*SearchResult
+
+	headline := fmt.Sprintf("StartSel=%s, StopSel=%s", HighlightStart, HighlightStop)
+
+	q := r.db.NewSelect().
+		Model(&results).
+		ColumnExpr("?TableColumns").
+		ColumnExpr("ts_rank(a.search_vector, q.query) AS rank").
+		ColumnExpr("ts_headline(?, a.title, q.query, ?) AS title_highlight", SearchConfig, headline+", HighlightAll=true").
+		ColumnExpr("ts_headline(?, a.content, q.query, ?) AS snippet", SearchConfig, headline+", MaxFragments=2, MaxWords=30, MinWords=10").
+		Join("CROSS JOIN websearch_to_tsquery(?, ?) AS q(query)", SearchConfig, query).
+		Where("a.search_vector @@ q.query").
+		OrderExpr("rank DESC, a.published_at DESC NULLS LAST, a.id DESC").
+		Limit(limit).
+		Offset(offset)
+
+	if viewerID != 0 {
+		q = q.WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.
+				Where("a.status = ? AND a.visibility = ?", StatusPublished, VisibilityPublic).
+				WhereOr("a.user_id = ?", viewerID)
+		})
+	} else {
+		q = q.Where("a.status = ? AND a.visibility = ?", StatusPublished, VisibilityPublic)
+	}
+
+	if filter.AuthorID != 0 {
+		q = q.Where("a.user_id = ?", filter.AuthorID)
+	}
+	if filter.Tag != "" {
+		q = q.Where("a.id IN (SELECT at.article_id FROM article_tags AS at JOIN tags AS t ON t.id = at.tag_id WHERE t.slug = ?)", filter.Tag)
+	}
+	if !filter.Since.IsZero() {
+		q = q.Where("a.published_at >= ?", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		q = q.Where("a.published_at < ?", filter.Until)
+	}
+
+	total, err := hideBlocked(q, "a.user_id", viewerID).ScanAndCount(ctx)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to search articles: %w", err)
+	}
+
+	articles := make([]*Article, len(results))
+	for i, result := range results {
+		articles[i] = &result.Article
+	}
+	if err := loadTags(ctx, r.db, articles...); err != nil {
+		return nil, 0, err
+	}
+	return results, total, nil
+}
@@ -0,0 +1,230 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/uptrace/bun"
+)
+
+// Limits on the tags of one article.
+const (
+	MaxTagsPerArticle = 10
+	MaxTagLength      = 50
+)
+
+// Tag is a topic articles can be filed under, identified by its normalized slug.
+type Tag struct {
+	bun.BaseModel `bun:"table:tags,alias:t"`
+
+	ID   uint64 `bun:"id,pk,autoincrement"`
+	Slug string `bun:"slug,notnull,unique"`
+}
+
+// ArticleTag links an article to one of its tags.
+type ArticleTag struct {
+	bun.BaseModel `bun:"table:article_tags,alias:at"`
+
+	ArticleID uint64 `bun:"article_id,pk"`
+	TagID     uint64 `bun:"tag_id,pk"`
+}
+
+// TagCount is a tag with the number of published public articles filed under it.
+type TagCount struct {
+	Slug     string `bun:"slug"`
+	Articles int    `bun:"articles"`
+}
+
+// NormalizeTag turns a tag as typed by a user into its slug: lower case letters
+// and digits, with every other run of characters replaced by a single hyphen.
+// It returns an empty string if nothing is left.
+func NormalizeTag(name string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if hyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			hyphen = false
+		} else {
+			hyphen = true
+		}
+	}
+	return b.String()
+}
+
+// normalizeTags returns the sorted slugs of the given tags without duplicates.
+func normalizeTags(names []string) ([]string, error) {
+	slugs := make([]string, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		slug := NormalizeTag(name)
+		if slug == "" {
+			return nil, fmt.Errorf("invalid tag %q", name)
+		}
+		if utf8.RuneCountInString(slug) > MaxTagLength {
+			return nil, fmt.Errorf("tag %q is longer than %d characters", name, MaxTagLength)
+		}
+		if !seen[slug] {
+			seen[slug] = true
+			slugs = append(slugs, slug)
+		}
+	}
+	if len(slugs) > MaxTagsPerArticle {
+		return nil, fmt.Errorf("an article can have at most %d tags", MaxTagsPerArticle)
+	}
+	sort.Strings(slugs)
+	return slugs, nil
+}
+
+type TagRepository struct {
+	db *bun.DB
+}
+
+func NewTagRepository(db *bun.DB) *TagRepository {
+	return &TagRepository{db: db}
+}
+
+// EnsureIndexes creates the index used to find the articles with a tag.
+func (r *TagRepository) EnsureIndexes() error {
+	ctx := context.Background()
+
+	_, err := r.db.ExecContext(ctx, "CREATE INDEX IF NOT EXISTS article_tags_tag_id_idx ON article_tags (tag_id)")
+	if err != nil {
+		return fmt.Errorf("failed to create tag index: %w", err)
+	}
+	return nil
+}
+
+// List returns the tags of published public articles with the number of such
+// articles, most used first.
+func (r *TagRepository) List(limit, offset int) ([]*TagCount, error) {
+	ctx := context.Background()
+	var tags []*TagCount
+
+	err := r.db.NewSelect().
+		Model((*Tag)(nil)).
+		Column("t.slug").
+		ColumnExpr("COUNT(*) AS articles").
+		Join("JOIN article_tags AS at ON at.tag_id = t.id").
+		Join("JOIN articles AS a ON a.id = at.article_id").
+		Where("a.status = ?", StatusPublished).
+		Where("a.visibility = ?", VisibilityPublic).
+		Group("t.slug").
+		OrderExpr("articles DESC, t.slug").
+		Limit(limit).
+		Offset(offset).
+		Scan(ctx, &tags)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags: %w", err)
+	}
+	return tags, nil
+}
+
+// setTags replaces the tags of an article with the given slugs, creating the
+// tags that do not exist yet.
+func setTags(ctx context.Context, db bun.IDB, articleID uint64, slugs []string) error {
+	_, err := db.NewDelete().
+		Model((*ArticleTag)(nil)).
+		Where("article_id = ?", articleID).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to clear tags: %w", err)
+	}
+	if len(slugs) == 0 {
+		return nil
+	}
+
+	tags := make([]*Tag, len(slugs))
+	for i, slug := range slugs {
+		tags[i] = &Tag{Slug: slug}
+	}
+	_, err = db.NewInsert().
+		Model(&tags).
+		On("CONFLICT (slug) DO NOTHING").
+		Returning("NULL").
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create tags: %w", err)
+	}
+
+	var tagIDs []uint64
+	err = db.NewSelect().
+		Model((*Tag)(nil)).
+		Column("id").
+		Where("slug IN (?)", bun.In(slugs)).
+		Scan(ctx, &tagIDs)
+	if err != nil {
+		return fmt.Errorf("failed to get tags: %w", err)
+	}
+
+	links := make([]*ArticleTag, len(tagIDs))
+	for i, tagID := range tagIDs {
+		links[i] = &ArticleTag{ArticleID: articleID, TagID: tagID}
+	}
+	if _, err := db.NewInsert().Model(&links).Exec(ctx); err != nil {
+		return fmt.Errorf("failed to tag article: %w", err)
+	}
+	return nil
+}
+
+// loadTags fills in the tags of the given articles.
+func loadTags(ctx context.Context, db bun.IDB, articles ...*Article) error {
+	if len(articles) == 0 {
+		return nil
+	}
+
+	byID := make(map[uint64]*Article, len(articles))
+	ids := make([]uint64, len(articles))
+	for i, article := range articles {
+		article.Tags = []string{}
+		byID[article.ID] = article
+		ids[i] = article.ID
+	}
+
+	var rows []struct {
+		ArticleID uint64 `bun:"article_id"`
+		Slug      string `bun:"slug"`
+	}
+	err := db.NewSelect().
+		Model((*ArticleTag)(nil)).
+		Column("at.article_id").
+		ColumnExpr("t.slug").
+		Join("JOIN tags AS t ON t.id = at.tag_id").
+		Where("at.article_id IN (?)", bun.In(ids)).
+		Order("t.slug").
+		Scan(ctx, &rows)
+	if err != nil {
+		return fmt.Errorf("failed to load tags: %w", err)
+	}
+
+	for _, row := range rows {
+		if article, ok := byID[row.ArticleID]; ok {
+			article.Tags = append(article.Tags, row.Slug)
+		}
+	}
+	return nil
+}
+
+// deleteArticleTags unlinks the given articles from their tags.
+func deleteArticleTags(ctx context.Context, db bun.IDB, articleIDs []uint64) error {
+	if len(articleIDs) == 0 {
+		return nil
+	}
+
+	_, err := db.NewDelete().
+		Model((*ArticleTag)(nil)).
+		Where("article_id IN (?)", bun.In(articleIDs)).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to delete article tags: %w", err)
+	}
+	return nil
+}
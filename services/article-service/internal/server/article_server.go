@@ -2,7 +2,9 @@ package server
 
 import (
 	"context"
+	"errors"
 	"log/slog"
+	"time"
 
 	"google.golang.org/protobuf/types/known/timestamppb"
 
@@ -48,74 +50,116 @@ func visibilityFromProto(v pb.Visibility) repository.Visibility {
 	}
 }
 
+func statusToProto(s repository.Status) pb.ArticleStatus {
+	switch s {
+	case repository.StatusDraft:
+		return pb.ArticleStatus_DRAFT
+	case repository.StatusScheduled:
+		return pb.ArticleStatus_SCHEDULED
+	case repository.StatusArchived:
+		return pb.ArticleStatus_ARCHIVED
+	default:
+		return pb.ArticleStatus_PUBLISHED
+	}
+}
+
+func statusFromProto(s pb.ArticleStatus) repository.Status {
+	switch s {
+	case pb.ArticleStatus_DRAFT:
+		return repository.StatusDraft
+	case pb.ArticleStatus_SCHEDULED:
+		return repository.StatusScheduled
+	case pb.ArticleStatus_ARCHIVED:
+		return repository.StatusArchived
+	default:
+		return repository.StatusPublished
+	}
+}
+
+// optionalTimestamp leaves the field unset for zero times.
+func optionalTimestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+// timeFromProto turns an unset timestamp into the zero time.
+func timeFromProto(ts *timestamppb.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+	return ts.AsTime()
+}
+
+func articleToProto(article *repository.Article) *pb.Article {
+	return &pb.Article{
+		Id:          article.ID,
+		UserId:      article.UserID,
+		Title:       article.Title,
+		Content:     article.Content,
+		Visibility:  visibilityToProto(article.Visibility),
+		AccessToken: article.AccessToken,
+		CreatedAt:   timestamppb.New(article.CreatedAt),
+		UpdatedAt:   timestamppb.New(article.UpdatedAt),
+		Status:      statusToProto(article.Status),
+		PublishedAt: optionalTimestamp(article.PublishedAt),
+		ScheduledAt: optionalTimestamp(article.ScheduledAt),
+		Version:     int32(article.Version),
+		Tags:        article.Tags,
+	}
+}
+
 func (s *ArticleServer) CreateArticle(ctx context.Context, req *pb.CreateArticleRequest) (*pb.CreateArticleResponse, error) {
 	visibility := visibilityFromProto(req.Visibility)
-	article, err := s.articleService.Create(ctx, req.UserId, req.Title, req.Content, visibility)
+	status := statusFromProto(req.Status)
+	article, err := s.articleService.Create(ctx, req.UserId, req.Title, req.Content, visibility, status, timeFromProto(req.ScheduledAt), req.Tags)
 	if err != nil {
 		s.logger.Error("create article failed", "user_id", req.UserId, "error", err)
 		return &pb.CreateArticleResponse{Error: err.Error()}, nil
 	}
 
 	return &pb.CreateArticleResponse{
-		Article: &pb.Article{
-			Id:          article.ID,
-			UserId:      article.UserID,
-			Title:       article.Title,
-			Content:     article.Content,
-			Visibility:  visibilityToProto(article.Visibility),
-			AccessToken: article.AccessToken,
-			CreatedAt:   timestamppb.New(article.CreatedAt),
-			UpdatedAt:   timestamppb.New(article.UpdatedAt),
-		},
+		Article: articleToProto(article),
 	}, nil
 }
 
 func (s *ArticleServer) GetArticle(ctx context.Context, req *pb.GetArticleRequest) (*pb.GetArticleResponse, error) {
-	article, username, err := s.articleService.GetByID(ctx, req.Id, req.ViewerId, req.AccessToken)
+	article, username, err := s.articleService.GetByID(ctx, req.Id, req.ViewerId, req.AccessToken, req.Preview)
 	if err != nil {
 		s.logger.Error("get article failed", "article_id", req.Id, "error", err)
 		return &pb.GetArticleResponse{Error: err.Error()}, nil
 	}
 
 	return &pb.GetArticleResponse{
-		Article: &pb.Article{
-			Id:          article.ID,
-			UserId:      article.UserID,
-			Title:       article.Title,
-			Content:     article.Content,
-			Visibility:  visibilityToProto(article.Visibility),
-			AccessToken: article.AccessToken,
-			CreatedAt:   timestamppb.New(article.CreatedAt),
-			UpdatedAt:   timestamppb.New(article.UpdatedAt),
-		},
+		Article:        articleToProto(article),
 		AuthorUsername: username,
 	}, nil
 }
 
 func (s *ArticleServer) UpdateArticle(ctx context.Context, req *pb.UpdateArticleRequest) (*pb.UpdateArticleResponse, error) {
 	visibility := visibilityFromProto(req.Visibility)
-	article, err := s.articleService.Update(ctx, req.Id, req.UserId, req.Title, req.Content, visibility)
+	var status repository.Status // Empty keeps the current status
+	if req.Status != nil {
+		status = statusFromProto(*req.Status)
+	}
+	article, err := s.articleService.Update(ctx, req.Id, req.UserId, req.Roles, req.Title, req.Content, visibility, status, timeFromProto(req.ScheduledAt), req.Tags, req.ReplaceTags, int(req.ExpectedVersion))
+	if errors.Is(err, repository.ErrVersionConflict) {
+		// Send the current article so the editor can merge
+		return &pb.UpdateArticleResponse{Article: articleToProto(article), Error: err.Error()}, nil
+	}
 	if err != nil {
 		s.logger.Error("update article failed", "article_id", req.Id, "error", err)
 		return &pb.UpdateArticleResponse{Error: err.Error()}, nil
 	}
 
 	return &pb.UpdateArticleResponse{
-		Article: &pb.Article{
-			Id:          article.ID,
-			UserId:      article.UserID,
-			Title:       article.Title,
-			Content:     article.Content,
-			Visibility:  visibilityToProto(article.Visibility),
-			AccessToken: article.AccessToken,
-			CreatedAt:   timestamppb.New(article.CreatedAt),
-			UpdatedAt:   timestamppb.New(article.UpdatedAt),
-		},
+		Article: articleToProto(article),
 	}, nil
 }
 
 func (s *ArticleServer) DeleteArticle(ctx context.Context, req *pb.DeleteArticleRequest) (*pb.DeleteArticleResponse, error) {
-	err := s.articleService.Delete(ctx, req.Id, req.UserId)
+	err := s.articleService.Delete(ctx, req.Id, req.UserId, req.Roles)
 	if err != nil {
 		s.logger.Error("delete article failed", "article_id", req.Id, "error", err)
 		return &pb.DeleteArticleResponse{Success: false, Error: err.Error()}, nil
@@ -130,6 +174,9 @@ func (s *ArticleServer) ListArticles(ctx context.Context, req *pb.ListArticlesRe
 		limit = 20
 	}
 	offset := int(req.Offset)
+	if offset < 0 {
+		offset = 0
+	}
 
 	articles, usernames, err := s.articleService.List(ctx, req.ViewerId, limit, offset)
 	if err != nil {
@@ -139,16 +186,7 @@ func (s *ArticleServer) ListArticles(ctx context.Context, req *pb.ListArticlesRe
 
 	pbArticles := make([]*pb.Article, len(articles))
 	for i, article := range articles {
-		pbArticles[i] = &pb.Article{
-			Id:          article.ID,
-			UserId:      article.UserID,
-			Title:       article.Title,
-			Content:     article.Content,
-			Visibility:  visibilityToProto(article.Visibility),
-			AccessToken: article.AccessToken,
-			CreatedAt:   timestamppb.New(article.CreatedAt),
-			UpdatedAt:   timestamppb.New(article.UpdatedAt),
-		}
+		pbArticles[i] = articleToProto(article)
 	}
 
 	return &pb.ListArticlesResponse{
@@ -159,7 +197,7 @@ func (s *ArticleServer) ListArticles(ctx context.Context, req *pb.ListArticlesRe
 }
 
 func (s *ArticleServer) GetArticlesByUser(ctx context.Context, req *pb.GetArticlesByUserRequest) (*pb.GetArticlesByUserResponse, error) {
-	articles, err := s.articleService.GetByUser(ctx, req.UserId, req.ViewerId)
+	articles, username, err := s.articleService.GetByUser(ctx, req.UserId, req.ViewerId)
 	if err != nil {
 		s.logger.Error("get articles by user failed", "user_id", req.UserId, "error", err)
 		return &pb.GetArticlesByUserResponse{Error: err.Error()}, nil
@@ -167,19 +205,10 @@ func (s *ArticleServer) GetArticlesByUser(ctx context.Context, req *pb.GetArticl
 
 	pbArticles := make([]*pb.Article, len(articles))
 	for i, article := range articles {
-		pbArticles[i] = &pb.Article{
-			Id:          article.ID,
-			UserId:      article.UserID,
-			Title:       article.Title,
-			Content:     article.Content,
-			Visibility:  visibilityToProto(article.Visibility),
-			AccessToken: article.AccessToken,
-			CreatedAt:   timestamppb.New(article.CreatedAt),
-			UpdatedAt:   timestamppb.New(article.UpdatedAt),
-		}
+		pbArticles[i] = articleToProto(article)
 	}
 
-	return &pb.GetArticlesByUserResponse{Articles: pbArticles}, nil
+	return &pb.GetArticlesByUserResponse{Articles: pbArticles, AuthorUsername: username}, nil
 }
 
 func (s *ArticleServer) CheckArticleAccess(ctx context.Context, req *pb.CheckArticleAccessRequest) (*pb.CheckArticleAccessResponse, error) {
@@ -190,3 +219,15 @@ func (s *ArticleServer) CheckArticleAccess(ctx context.Context, req *pb.CheckArt
 
 	return &pb.CheckArticleAccessResponse{HasAccess: hasAccess}, nil
 }
+
+func (s *ArticleServer) UnpublishArticle(ctx context.Context, req *pb.UnpublishArticleRequest) (*pb.UnpublishArticleResponse, error) {
+	article, err := s.articleService.Unpublish(ctx, req.Id, req.UserId, req.Roles)
+	if err != nil {
+		s.logger.Error("unpublish article failed", "article_id", req.Id, "error", err)
+		return &pb.UnpublishArticleResponse{Error: err.Error()}, nil
+	}
+
+	return &pb.UnpublishArticleResponse{
+		Article: articleToProto(article),
+	}, nil
+}
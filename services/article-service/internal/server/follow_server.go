@@ -0,0 +1,104 @@
+package server
+
+import (
+	"context"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/XRS0/blog/services/article-service/internal/service"
+	pb "github.com/XRS0/blog/services/article-service/proto/article"
+)
+
+func followedUsersToProto(followers []*service.Follower) []*pb.FollowedUser {
+	users := make([]*pb.FollowedUser, len(followers))
+	for i, follower := range followers {
+		users[i] = &pb.FollowedUser{
+			UserId:     follower.UserID,
+			Username:   follower.Username,
+			FollowedAt: timestamppb.New(follower.FollowedAt),
+		}
+	}
+	return users
+}
+
+func (s *ArticleServer) FollowUser(ctx context.Context, req *pb.FollowUserRequest) (*pb.FollowUserResponse, error) {
+	if err := s.articleService.Follow(ctx, req.FollowerId, req.FolloweeId); err != nil {
+		s.logger.Error("follow user failed", "follower_id", req.FollowerId, "followee_id", req.FolloweeId, "error", err)
+		return &pb.FollowUserResponse{Success: false, Error: err.Error()}, nil
+	}
+
+	return &pb.FollowUserResponse{Success: true}, nil
+}
+
+func (s *ArticleServer) UnfollowUser(ctx context.Context, req *pb.UnfollowUserRequest) (*pb.UnfollowUserResponse, error) {
+	if err := s.articleService.Unfollow(ctx, req.FollowerId, req.FolloweeId); err != nil {
+		s.logger.Error("unfollow user failed", "follower_id", req.FollowerId, "followee_id", req.FolloweeId, "error", err)
+		return &pb.UnfollowUserResponse{Success: false, Error: err.Error()}, nil
+	}
+
+	return &pb.UnfollowUserResponse{Success: true}, nil
+}
+
+func (s *ArticleServer) ListFollowers(ctx context.Context, req *pb.ListFollowsRequest) (*pb.ListFollowsResponse, error) {
+	limit := int(req.Limit)
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	offset := int(req.Offset)
+	if offset < 0 {
+		offset = 0
+	}
+
+	followers, total, err := s.articleService.ListFollowers(ctx, req.UserId, limit, offset)
+	if err != nil {
+		s.logger.Error("list followers failed", "user_id", req.UserId, "error", err)
+		return &pb.ListFollowsResponse{Error: err.Error()}, nil
+	}
+
+	return &pb.ListFollowsResponse{Users: followedUsersToProto(followers), Total: int32(total)}, nil
+}
+
+func (s *ArticleServer) ListFollowing(ctx context.Context, req *pb.ListFollowsRequest) (*pb.ListFollowsResponse, error) {
+	limit := int(req.Limit)
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	offset := int(req.Offset)
+	if offset < 0 {
+		offset = 0
+	}
+
+	following, total, err := s.articleService.ListFollowing(ctx, req.UserId, limit, offset)
+	if err != nil {
+		s.logger.Error("list following failed", "user_id", req.UserId, "error", err)
+		return &pb.ListFollowsResponse{Error: err.Error()}, nil
+	}
+
+	return &pb.ListFollowsResponse{Users: followedUsersToProto(following), Total: int32(total)}, nil
+}
+
+func (s *ArticleServer) GetFeed(ctx context.Context, req *pb.GetFeedRequest) (*pb.GetFeedResponse, error) {
+	limit := int(req.Limit)
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	entries, next, err := s.articleService.Feed(ctx, req.UserId, req.BeforeId, limit)
+	if err != nil {
+		s.logger.Error("get feed failed", "user_id", req.UserId, "error", err)
+		return &pb.GetFeedResponse{Error: err.Error()}, nil
+	}
+
+	articles := make([]*pb.Article, len(entries))
+	usernames := make([]string, len(entries))
+	for i, entry := range entries {
+		articles[i] = articleToProto(entry.Article)
+		usernames[i] = entry.AuthorUsername
+	}
+
+	return &pb.GetFeedResponse{
+		Articles:        articles,
+		AuthorUsernames: usernames,
+		NextBeforeId:    next,
+	}, nil
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/XRS0/blog/services/article-service/internal/repository"
+	pb "github.com/XRS0/blog/services/article-service/proto/article"
+)
+
+func revisionToProto(revision *repository.ArticleRevision) *pb.ArticleRevision {
+	return &pb.ArticleRevision{
+		ArticleId:    revision.ArticleID,
+		Number:       int32(revision.Number),
+		AuthorId:     revision.AuthorID,
+		Title:        revision.Title,
+		Content:      revision.Content,
+		RestoredFrom: int32(revision.RestoredFrom),
+		CreatedAt:    timestamppb.New(revision.CreatedAt),
+	}
+}
+
+func (s *ArticleServer) ListRevisions(ctx context.Context, req *pb.ListRevisionsRequest) (*pb.ListRevisionsResponse, error) {
+	limit := int(req.Limit)
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	offset := int(req.Offset)
+	if offset < 0 {
+		offset = 0
+	}
+
+	revisions, total, err := s.articleService.ListRevisions(ctx, req.ArticleId, req.UserId, limit, offset)
+	if err != nil {
+		s.logger.Error("list revisions failed", "article_id", req.ArticleId, "error", err)
+		return &pb.ListRevisionsResponse{Error: err.Error()}, nil
+	}
+
+	pbRevisions := make([]*pb.ArticleRevision, len(revisions))
+	for i, revision := range revisions {
+		pbRevisions[i] = revisionToProto(revision)
+	}
+
+	return &pb.ListRevisionsResponse{Revisions: pbRevisions, Total: int32(total)}, nil
+}
+
+func (s *ArticleServer) GetRevision(ctx context.Context, req *pb.GetRevisionRequest) (*pb.GetRevisionResponse, error) {
+	revision, err := s.articleService.GetRevision(ctx, req.ArticleId, req.UserId, int(req.Number))
+	if err != nil {
+		s.logger.Error("get revision failed", "article_id", req.ArticleId, "revision", req.Number, "error", err)
+		return &pb.GetRevisionResponse{Error: err.Error()}, nil
+	}
+
+	return &pb.GetRevisionResponse{Revision: revisionToProto(revision)}, nil
+}
+
+func (s *ArticleServer) DiffRevisions(ctx context.Context, req *pb.DiffRevisionsRequest) (*pb.DiffRevisionsResponse, error) {
+	diff, err := s.articleService.DiffRevisions(ctx, req.ArticleId, req.UserId, int(req.From), int(req.To))
+	if err != nil {
+		s.logger.Error("diff revisions failed", "article_id", req.ArticleId, "from", req.From, "to", req.To, "error", err)
+		return &pb.DiffRevisionsResponse{Error: err.Error()}, nil
+	}
+
+	return &pb.DiffRevisionsResponse{Diff: diff}, nil
+}
+
+func (s *ArticleServer) RestoreRevision(ctx context.Context, req *pb.RestoreRevisionRequest) (*pb.RestoreRevisionResponse, error) {
+	article, err := s.articleService.RestoreRevision(ctx, req.ArticleId, req.UserId, int(req.Number), int(req.ExpectedVersion))
+	if errors.Is(err, repository.ErrVersionConflict) {
+		return &pb.RestoreRevisionResponse{Article: articleToProto(article), Error: err.Error()}, nil
+	}
+	if err != nil {
+		s.logger.Error("restore revision failed", "article_id", req.ArticleId, "revision", req.Number, "error", err)
+		return &pb.RestoreRevisionResponse{Error: err.Error()}, nil
+	}
+
+	return &pb.RestoreRevisionResponse{Article: articleToProto(article)}, nil
+}
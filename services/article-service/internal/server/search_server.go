@@ -0,0 +1,46 @@
+package server
+
+import (
+	"context"
+
+	"github.com/XRS0/blog/services/article-service/internal/repository"
+	pb "github.com/XRS0/blog/services/article-service/proto/article"
+)
+
+func (s *ArticleServer) SearchArticles(ctx context.Context, req *pb.SearchArticlesRequest) (*pb.SearchArticlesResponse, error) {
+	limit := int(req.Limit)
+	if limit <= 0 || limit > 50 {
+		limit = 20
+	}
+	offset := int(req.Offset)
+	if offset < 0 {
+		offset = 0
+	}
+
+	filter := repository.SearchFilter{
+		AuthorID: req.AuthorId,
+		Tag:      req.Tag,
+		Since:    timeFromProto(req.PublishedSince),
+		Until:    timeFromProto(req.PublishedUntil),
+	}
+	results, usernames, total, err := s.articleService.Search(ctx, req.Query, req.ViewerId, filter, limit, offset)
+	if err != nil {
+		s.logger.Error("search articles failed", "error", err)
+		return &pb.SearchArticlesResponse{Error: err.Error()}, nil
+	}
+
+	pbResults := make([]*pb.SearchResult, len(results))
+	for i, result := range results {
+		article := articleToProto(&result.Article)
+		article.Content = "" // The snippet stands in for it
+		pbResults[i] = &pb.SearchResult{
+			Article:        article,
+			AuthorUsername: usernames[i],
+			Rank:           result.Rank,
+			TitleHighlight: result.TitleHighlight,
+			Snippet:        result.Snippet,
+		}
+	}
+
+	return &pb.SearchArticlesResponse{Results: pbResults, Total: int32(total)}, nil
+}
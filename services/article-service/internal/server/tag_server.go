@@ -0,0 +1,59 @@
+package server
+
+import (
+	"context"
+
+	pb "github.com/XRS0/blog/services/article-service/proto/article"
+)
+
+func (s *ArticleServer) ListTags(ctx context.Context, req *pb.ListTagsRequest) (*pb.ListTagsResponse, error) {
+	limit := int(req.Limit)
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+	offset := int(req.Offset)
+	if offset < 0 {
+		offset = 0
+	}
+
+	tags, err := s.articleService.ListTags(ctx, limit, offset)
+	if err != nil {
+		s.logger.Error("list tags failed", "error", err)
+		return &pb.ListTagsResponse{Error: err.Error()}, nil
+	}
+
+	pbTags := make([]*pb.TagCount, len(tags))
+	for i, tag := range tags {
+		pbTags[i] = &pb.TagCount{Slug: tag.Slug, Articles: int32(tag.Articles)}
+	}
+
+	return &pb.ListTagsResponse{Tags: pbTags}, nil
+}
+
+func (s *ArticleServer) ListArticlesByTag(ctx context.Context, req *pb.ListArticlesByTagRequest) (*pb.ListArticlesResponse, error) {
+	limit := int(req.Limit)
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	offset := int(req.Offset)
+	if offset < 0 {
+		offset = 0
+	}
+
+	articles, usernames, err := s.articleService.ListByTag(ctx, req.Tag, req.ViewerId, limit, offset)
+	if err != nil {
+		s.logger.Error("list articles by tag failed", "tag", req.Tag, "error", err)
+		return &pb.ListArticlesResponse{Error: err.Error()}, nil
+	}
+
+	pbArticles := make([]*pb.Article, len(articles))
+	for i, article := range articles {
+		pbArticles[i] = articleToProto(article)
+	}
+
+	return &pb.ListArticlesResponse{
+		Articles:        pbArticles,
+		AuthorUsernames: usernames,
+		Total:           int32(len(articles)),
+	}, nil
+}
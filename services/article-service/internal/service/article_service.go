@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"time"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
@@ -13,16 +14,33 @@ import (
 	"github.com/XRS0/blog/shared/rabbitmq"
 )
 
+// Roles carried in access tokens that grant moderation rights.
+const (
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
 type ArticleService struct {
 	repo       *repository.ArticleRepository
+	follows    *repository.FollowRepository
+	blocks     *repository.BlockRepository
+	revisions  *repository.RevisionRepository
+	tags       *repository.TagRepository
 	authClient authpb.AuthServiceClient
 	mq         *rabbitmq.Client
 	logger     *slog.Logger
+
+	authors *authorCache
 }
 
 func NewArticleService(
 	repo *repository.ArticleRepository,
+	follows *repository.FollowRepository,
+	blocks *repository.BlockRepository,
+	revisions *repository.RevisionRepository,
+	tags *repository.TagRepository,
 	authServiceURL string,
+	authorCacheTTL time.Duration,
 	mq *rabbitmq.Client,
 	logger *slog.Logger,
 ) (*ArticleService, error) {
@@ -36,14 +54,19 @@ func NewArticleService(
 
 	return &ArticleService{
 		repo:       repo,
+		follows:    follows,
+		blocks:     blocks,
+		revisions:  revisions,
+		tags:       tags,
 		authClient: authClient,
 		mq:         mq,
 		logger:     logger,
+		authors:    newAuthorCache(authorCacheTTL),
 	}, nil
 }
 
-func (s *ArticleService) Create(ctx context.Context, userID uint64, title, content string, visibility repository.Visibility) (*repository.Article, error) {
-	article, err := s.repo.Create(userID, title, content, visibility)
+func (s *ArticleService) Create(ctx context.Context, userID uint64, title, content string, visibility repository.Visibility, status repository.Status, scheduledAt time.Time, tags []string) (*repository.Article, error) {
+	article, err := s.repo.Create(userID, title, content, visibility, status, scheduledAt, tags)
 	if err != nil {
 		return nil, err
 	}
@@ -55,25 +78,32 @@ func (s *ArticleService) Create(ctx context.Context, userID uint64, title, conte
 			"article_id": article.ID,
 			"user_id":    article.UserID,
 			"visibility": string(article.Visibility),
+			"status":     string(article.Status),
+			"tags":       article.Tags,
 		},
 	}
 	if err := s.mq.Publish(ctx, "articles", "article.created", event); err != nil {
 		s.logger.Error("failed to publish article created event", "article_id", article.ID, "error", err)
 		return nil, err
 	}
+	if article.Status == repository.StatusPublished {
+		s.publishPublished(ctx, article)
+	}
 
-	s.logger.Info("article created", "article_id", article.ID, "user_id", userID)
+	s.logger.Info("article created", "article_id", article.ID, "user_id", userID, "status", article.Status)
 	return article, nil
 }
 
-func (s *ArticleService) GetByID(ctx context.Context, id, viewerID uint64, accessToken string) (*repository.Article, string, error) {
+// GetByID returns an article the viewer may read. Previews by the author are
+// not counted as views.
+func (s *ArticleService) GetByID(ctx context.Context, id, viewerID uint64, accessToken string, preview bool) (*repository.Article, string, error) {
 	article, err := s.repo.GetByID(id)
 	if err != nil {
 		return nil, "", err
 	}
 
 	// Check access
-	hasAccess, err := s.repo.CheckAccess(id, viewerID, accessToken)
+	hasAccess, err := s.CheckAccess(ctx, id, viewerID, accessToken)
 	if err != nil {
 		return nil, "", err
 	}
@@ -81,58 +111,255 @@ func (s *ArticleService) GetByID(ctx context.Context, id, viewerID uint64, acces
 		return nil, "", fmt.Errorf("access denied")
 	}
 
-	// Get author username
-	userResp, err := s.authClient.GetUserByID(ctx, &authpb.GetUserByIDRequest{Id: article.UserID})
-	if err != nil || userResp.Error != "" {
-		s.logger.Error("failed to get author", "user_id", article.UserID, "error", err)
-		return article, "", nil
+	// Publish view event
+	if !preview || !article.OwnedBy(viewerID) {
+		event := rabbitmq.Event{
+			Type: rabbitmq.EventArticleViewed,
+			Data: map[string]interface{}{
+				"article_id": article.ID,
+				"user_id":    viewerID,
+				"author_id":  article.UserID,
+			},
+		}
+		if err := s.mq.Publish(ctx, "articles", "article.viewed", event); err != nil {
+			s.logger.Error("failed to publish view event", "article_id", article.ID, "error", err)
+		}
 	}
 
-	// Publish view event
+	return article, s.authorUsernames(ctx, []uint64{article.UserID})[article.UserID], nil
+}
+
+// Update changes an article of the user. An empty status keeps the current one
+// and the tags are kept unless replaceTags is set.
+// If expectedVersion is set and the article has moved on, the current article is
+// returned with repository.ErrVersionConflict. Only moderators can change the
+// status of an article a moderator archived.
+func (s *ArticleService) Update(ctx context.Context, id, userID uint64, roles []string, title, content string, visibility repository.Visibility, status repository.Status, scheduledAt time.Time, tags []string, replaceTags bool, expectedVersion int) (*repository.Article, error) {
+	article, published, err := s.repo.Update(id, userID, title, content, visibility, status, scheduledAt, tags, replaceTags, expectedVersion, canModerate(roles))
+	if err != nil {
+		return article, err
+	}
+	s.publishUpdated(ctx, article)
+	if published {
+		s.publishPublished(ctx, article)
+	}
+	return article, nil
+}
+
+// publishUpdated announces a saved change to an article.
+func (s *ArticleService) publishUpdated(ctx context.Context, article *repository.Article) {
 	event := rabbitmq.Event{
-		Type: rabbitmq.EventArticleViewed,
+		Type: rabbitmq.EventArticleUpdated,
 		Data: map[string]interface{}{
 			"article_id": article.ID,
-			"user_id":    viewerID,
+			"user_id":    article.UserID,
+			"visibility": string(article.Visibility),
+			"status":     string(article.Status),
+			"version":    article.Version,
+			"tags":       article.Tags,
 		},
 	}
-	if err := s.mq.Publish(ctx, "articles", "article.viewed", event); err != nil {
-		s.logger.Error("failed to publish view event", "article_id", article.ID, "error", err)
+	if err := s.mq.Publish(ctx, "articles", rabbitmq.EventArticleUpdated, event); err != nil {
+		s.logger.Error("failed to publish article updated event", "article_id", article.ID, "error", err)
+	}
+}
+
+// Delete removes an article owned by the user. Moderators may delete any article.
+func (s *ArticleService) Delete(ctx context.Context, id, userID uint64, roles []string) error {
+	if canModerate(roles) {
+		article, err := s.repo.GetByID(id)
+		if err != nil {
+			return err
+		}
+		if !article.OwnedBy(userID) {
+			if err := s.repo.DeleteAny(id); err != nil {
+				return err
+			}
+			s.publishDeleted(ctx, id)
+			s.logger.Info("article deleted by moderator", "article_id", id, "moderator_id", userID)
+			return nil
+		}
 	}
 
-	return article, userResp.User.Username, nil
+	if err := s.repo.Delete(id, userID); err != nil {
+		return err
+	}
+	s.publishDeleted(ctx, id)
+	return nil
 }
 
-func (s *ArticleService) Update(ctx context.Context, id, userID uint64, title, content string, visibility repository.Visibility) (*repository.Article, error) {
-	return s.repo.Update(id, userID, title, content, visibility)
+// publishDeleted tells other services to drop data about a deleted article.
+func (s *ArticleService) publishDeleted(ctx context.Context, id uint64) {
+	event := rabbitmq.Event{
+		Type: rabbitmq.EventArticleDeleted,
+		Data: map[string]interface{}{
+			"article_id": id,
+		},
+	}
+	if err := s.mq.Publish(ctx, "articles", "article.deleted", event); err != nil {
+		s.logger.Error("failed to publish article deleted event", "article_id", id, "error", err)
+	}
 }
 
-func (s *ArticleService) Delete(ctx context.Context, id, userID uint64) error {
-	return s.repo.Delete(id, userID)
+// Unpublish archives an article, which hides it from everyone but its author.
+// Only moderators may do this.
+func (s *ArticleService) Unpublish(ctx context.Context, id, userID uint64, roles []string) (*repository.Article, error) {
+	if !canModerate(roles) {
+		return nil, fmt.Errorf("unauthorized")
+	}
+
+	article, err := s.repo.SetStatus(id, repository.StatusArchived)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("article unpublished by moderator", "article_id", id, "moderator_id", userID)
+	return article, nil
 }
 
 func (s *ArticleService) List(ctx context.Context, viewerID uint64, limit, offset int) ([]*repository.Article, []string, error) {
-	articles, err := s.repo.List(limit, offset)
+	articles, err := s.repo.List(viewerID, limit, offset)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	// Get author usernames
+	return articles, s.articleAuthors(ctx, articles), nil
+}
+
+// articleAuthors returns the usernames of the authors of the articles, by index.
+func (s *ArticleService) articleAuthors(ctx context.Context, articles []*repository.Article) []string {
+	userIDs := make([]uint64, len(articles))
+	for i, article := range articles {
+		userIDs[i] = article.UserID
+	}
+	authors := s.authorUsernames(ctx, userIDs)
+
 	usernames := make([]string, len(articles))
 	for i, article := range articles {
-		userResp, err := s.authClient.GetUserByID(ctx, &authpb.GetUserByIDRequest{Id: article.UserID})
-		if err == nil && userResp.Error == "" {
-			usernames[i] = userResp.User.Username
+		usernames[i] = authors[article.UserID]
+	}
+	return usernames
+}
+
+// GetByUser returns the articles of a user visible to the viewer and the user's
+// username. Users blocked by the author get an empty list.
+func (s *ArticleService) GetByUser(ctx context.Context, userID, viewerID uint64) ([]*repository.Article, string, error) {
+	blocked, err := s.blockedBy(userID, viewerID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var articles []*repository.Article
+	if !blocked {
+		articles, err = s.repo.GetByUser(userID, viewerID)
+		if err != nil {
+			return nil, "", err
 		}
 	}
 
-	return articles, usernames, nil
+	return articles, s.authorUsernames(ctx, []uint64{userID})[userID], nil
 }
 
-func (s *ArticleService) GetByUser(ctx context.Context, userID, viewerID uint64) ([]*repository.Article, error) {
-	return s.repo.GetByUser(userID, viewerID)
+// CheckAccess reports whether the viewer may read the article. Users blocked by
+// the author may not, whatever its visibility.
+func (s *ArticleService) CheckAccess(ctx context.Context, articleID, viewerID uint64, accessToken string) (bool, error) {
+	hasAccess, err := s.repo.CheckAccess(articleID, viewerID, accessToken)
+	if err != nil || !hasAccess {
+		return hasAccess, err
+	}
+
+	article, err := s.repo.GetByID(articleID)
+	if err != nil {
+		return false, err
+	}
+	blocked, err := s.blockedBy(article.UserID, viewerID)
+	if err != nil {
+		return false, err
+	}
+	return !blocked, nil
 }
 
-func (s *ArticleService) CheckAccess(ctx context.Context, articleID, viewerID uint64, accessToken string) (bool, error) {
-	return s.repo.CheckAccess(articleID, viewerID, accessToken)
+// StartEventConsumer handles events about user accounts.
+func (s *ArticleService) StartEventConsumer(ctx context.Context) error {
+	if err := s.mq.DeclareExchange("users"); err != nil {
+		return err
+	}
+
+	// Declare queue for user events
+	if err := s.mq.DeclareQueue("article-user-events"); err != nil {
+		return err
+	}
+
+	// Bind to users exchange
+	for _, key := range []string{
+		rabbitmq.EventUserDeleted,
+		rabbitmq.EventUserBlocked,
+		rabbitmq.EventUserUnblocked,
+		rabbitmq.EventUserMuted,
+		rabbitmq.EventUserUnmuted,
+	} {
+		if err := s.mq.BindQueue("article-user-events", "users", key); err != nil {
+			return err
+		}
+	}
+
+	return s.mq.Consume("article-user-events", s.handleEvent)
+}
+
+func (s *ArticleService) handleEvent(event rabbitmq.Event) error {
+	switch event.Type {
+	case rabbitmq.EventUserDeleted:
+		userID, err := event.ID("user_id")
+		if err != nil {
+			return err
+		}
+		deleteArticles, _ := event.Data["delete_articles"].(bool)
+		return s.removeUser(context.Background(), userID, deleteArticles)
+
+	case rabbitmq.EventUserBlocked, rabbitmq.EventUserUnblocked,
+		rabbitmq.EventUserMuted, rabbitmq.EventUserUnmuted:
+		return s.handleBlockEvent(event)
+	}
+
+	return nil
+}
+
+// removeUser deletes or anonymizes the articles of a purged account and drops its
+// follows and blocks. It is safe to repeat, since a repeated event finds nothing left to change.
+func (s *ArticleService) removeUser(ctx context.Context, userID uint64, deleteArticles bool) error {
+	var (
+		deleted []uint64
+		err     error
+	)
+	if deleteArticles {
+		deleted, err = s.repo.DeleteByUser(userID)
+	} else {
+		deleted, err = s.repo.AnonymizeByUser(userID)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, id := range deleted {
+		s.publishDeleted(ctx, id)
+	}
+
+	if err := s.follows.RemoveUser(userID); err != nil {
+		return err
+	}
+	if err := s.blocks.RemoveUser(userID); err != nil {
+		return err
+	}
+
+	s.logger.Info("articles of deleted user removed", "user_id", userID, "deleted", len(deleted), "anonymized", !deleteArticles)
+	return nil
+}
+
+func canModerate(roles []string) bool {
+	for _, role := range roles {
+		if role == RoleModerator || role == RoleAdmin {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	authpb "github.com/XRS0/blog/services/article-service/proto/auth"
+)
+
+// maxAuthorCacheEntries caps the author cache; once reached, expired entries are
+// dropped and, if that is not enough, the cache starts over.
+const maxAuthorCacheEntries = 10000
+
+// authorUsernames returns the usernames of the given authors, keyed by user ID.
+// Cached names are used for up to the cache TTL; the rest are fetched with a
+// single GetUsersByIDs call. Unknown users and anonymized articles (user 0) map
+// to an empty name. If auth-service is unavailable the names are left empty.
+func (s *ArticleService) authorUsernames(ctx context.Context, userIDs []uint64) map[uint64]string {
+	now := time.Now()
+	usernames := make(map[uint64]string, len(userIDs))
+
+	var missing []uint64
+	for _, id := range userIDs {
+		if id == 0 {
+			continue
+		}
+		if _, seen := usernames[id]; seen {
+			continue
+		}
+		if username, ok := s.authors.get(id, now); ok {
+			usernames[id] = username
+			continue
+		}
+		usernames[id] = ""
+		missing = append(missing, id)
+	}
+
+	if len(missing) == 0 {
+		return usernames
+	}
+
+	resp, err := s.authClient.GetUsersByIDs(ctx, &authpb.GetUsersByIDsRequest{Ids: missing})
+	if err == nil && resp.Error != "" {
+		err = fmt.Errorf("%s", resp.Error)
+	}
+	if err != nil {
+		s.logger.Error("failed to get authors", "count", len(missing), "error", err)
+		return usernames
+	}
+
+	// Users that were not found are not cached, since they may register any moment
+	for _, user := range resp.Users {
+		usernames[user.Id] = user.Username
+		s.authors.set(user.Id, user.Username, now)
+	}
+
+	return usernames
+}
+
+// userExists asks auth-service whether a user exists, bypassing the author cache.
+func (s *ArticleService) userExists(ctx context.Context, userID uint64) (bool, error) {
+	resp, err := s.authClient.GetUsersByIDs(ctx, &authpb.GetUsersByIDsRequest{Ids: []uint64{userID}})
+	if err != nil {
+		return false, fmt.Errorf("failed to get user: %w", err)
+	}
+	if resp.Error != "" {
+		return false, fmt.Errorf("failed to get user: %s", resp.Error)
+	}
+	return len(resp.Users) > 0, nil
+}
+
+// authorCache remembers author usernames for a short time. A renamed author
+// shows up under the old name until the entry expires.
+type authorCache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[uint64]authorCacheEntry
+}
+
+type authorCacheEntry struct {
+	username  string
+	fetchedAt time.Time
+}
+
+func newAuthorCache(ttl time.Duration) *authorCache {
+	return &authorCache{ttl: ttl, entries: make(map[uint64]authorCacheEntry)}
+}
+
+func (c *authorCache) get(id uint64, now time.Time) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[id]
+	if !ok || now.Sub(entry.fetchedAt) >= c.ttl {
+		return "", false
+	}
+	return entry.username, true
+}
+
+func (c *authorCache) set(id uint64, username string, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.entries) >= maxAuthorCacheEntries {
+		for key, entry := range c.entries {
+			if now.Sub(entry.fetchedAt) >= c.ttl {
+				delete(c.entries, key)
+			}
+		}
+		if len(c.entries) >= maxAuthorCacheEntries {
+			c.entries = make(map[uint64]authorCacheEntry)
+		}
+	}
+	c.entries[id] = authorCacheEntry{username: username, fetchedAt: now}
+}
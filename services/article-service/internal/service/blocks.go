@@ -0,0 +1,61 @@
+package service
+
+import (
+	"github.com/XRS0/blog/services/article-service/internal/repository"
+	"github.com/XRS0/blog/shared/rabbitmq"
+)
+
+// blockedBy reports whether authorID has blocked viewerID. Anonymous viewers and
+// authors viewing their own articles are never blocked.
+func (s *ArticleService) blockedBy(authorID, viewerID uint64) (bool, error) {
+	if viewerID == 0 || authorID == viewerID {
+		return false, nil
+	}
+	return s.blocks.IsBlocked(authorID, viewerID)
+}
+
+// blockedEitherWay reports whether one of the two users has blocked the other.
+func (s *ArticleService) blockedEitherWay(a, b uint64) (bool, error) {
+	blocked, err := s.blocks.IsBlocked(a, b)
+	if err != nil || blocked {
+		return blocked, err
+	}
+	return s.blocks.IsBlocked(b, a)
+}
+
+// handleBlockEvent mirrors blocks and mutes made in auth-service. A block also
+// ends the follows between the two users in both directions.
+func (s *ArticleService) handleBlockEvent(event rabbitmq.Event) error {
+	blockerID, err := event.ID("blocker_id")
+	if err != nil {
+		return err
+	}
+	blockedID, err := event.ID("blocked_id")
+	if err != nil {
+		return err
+	}
+
+	switch event.Type {
+	case rabbitmq.EventUserBlocked:
+		if err := s.blocks.Add(blockerID, blockedID, repository.BlockKindBlock); err != nil {
+			return err
+		}
+		if _, err := s.follows.Unfollow(blockerID, blockedID); err != nil {
+			return err
+		}
+		if _, err := s.follows.Unfollow(blockedID, blockerID); err != nil {
+			return err
+		}
+
+	case rabbitmq.EventUserUnblocked:
+		return s.blocks.Remove(blockerID, blockedID, repository.BlockKindBlock)
+
+	case rabbitmq.EventUserMuted:
+		return s.blocks.Add(blockerID, blockedID, repository.BlockKindMute)
+
+	case rabbitmq.EventUserUnmuted:
+		return s.blocks.Remove(blockerID, blockedID, repository.BlockKindMute)
+	}
+
+	return nil
+}
@@ -0,0 +1,204 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/XRS0/blog/services/article-service/internal/repository"
+	"github.com/XRS0/blog/shared/rabbitmq"
+)
+
+// Follower is one side of a follow with the username of that user.
+type Follower struct {
+	UserID     uint64
+	Username   string
+	FollowedAt time.Time
+}
+
+// FeedEntry is an article in a reader's feed.
+type FeedEntry struct {
+	Article        *repository.Article
+	AuthorUsername string
+}
+
+func (s *ArticleService) Follow(ctx context.Context, followerID, followeeID uint64) error {
+	if followerID == followeeID {
+		return fmt.Errorf("cannot follow yourself")
+	}
+	exists, err := s.userExists(ctx, followeeID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("user not found")
+	}
+	blocked, err := s.blockedEitherWay(followerID, followeeID)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return fmt.Errorf("cannot follow this user")
+	}
+
+	if err := s.follows.Follow(followerID, followeeID); err != nil {
+		return err
+	}
+
+	s.logger.Info("user followed", "follower_id", followerID, "followee_id", followeeID)
+	return nil
+}
+
+func (s *ArticleService) Unfollow(ctx context.Context, followerID, followeeID uint64) error {
+	removed, err := s.follows.Unfollow(followerID, followeeID)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return fmt.Errorf("not following")
+	}
+
+	s.logger.Info("user unfollowed", "follower_id", followerID, "followee_id", followeeID)
+	return nil
+}
+
+// ListFollowers returns the users following userID and their total count.
+func (s *ArticleService) ListFollowers(ctx context.Context, userID uint64, limit, offset int) ([]*Follower, int, error) {
+	follows, total, err := s.follows.ListFollowers(userID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ids := make([]uint64, len(follows))
+	for i, follow := range follows {
+		ids[i] = follow.FollowerID
+	}
+	return s.followers(ctx, ids, follows), total, nil
+}
+
+// ListFollowing returns the users userID follows and their total count.
+func (s *ArticleService) ListFollowing(ctx context.Context, userID uint64, limit, offset int) ([]*Follower, int, error) {
+	follows, total, err := s.follows.ListFollowing(userID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ids := make([]uint64, len(follows))
+	for i, follow := range follows {
+		ids[i] = follow.FolloweeID
+	}
+	return s.followers(ctx, ids, follows), total, nil
+}
+
+func (s *ArticleService) followers(ctx context.Context, ids []uint64, follows []*repository.Follow) []*Follower {
+	usernames := s.authorUsernames(ctx, ids)
+
+	result := make([]*Follower, len(follows))
+	for i, follow := range follows {
+		result[i] = &Follower{
+			UserID:     ids[i],
+			Username:   usernames[ids[i]],
+			FollowedAt: follow.CreatedAt,
+		}
+	}
+	return result
+}
+
+// Feed returns the newest articles of the authors the user follows, older than
+// the entry beforeID (0 for the first page). Articles deleted, archived or hidden
+// since they were published are skipped, so a page may be shorter than limit; the
+// returned cursor still moves past them.
+func (s *ArticleService) Feed(ctx context.Context, userID, beforeID uint64, limit int) ([]*FeedEntry, uint64, error) {
+	items, err := s.follows.Feed(userID, beforeID, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(items) == 0 {
+		return nil, 0, nil
+	}
+
+	articleIDs := make([]uint64, len(items))
+	for i, item := range items {
+		articleIDs[i] = item.ArticleID
+	}
+	articles, err := s.repo.GetByIDs(articleIDs)
+	if err != nil {
+		return nil, 0, err
+	}
+	byID := make(map[uint64]*repository.Article, len(articles))
+	authorIDs := make([]uint64, len(articles))
+	for i, article := range articles {
+		byID[article.ID] = article
+		authorIDs[i] = article.UserID
+	}
+	usernames := s.authorUsernames(ctx, authorIDs)
+
+	var entries []*FeedEntry
+	for _, item := range items {
+		article, ok := byID[item.ArticleID]
+		if !ok || article.Status != repository.StatusPublished || article.Visibility != repository.VisibilityPublic {
+			continue
+		}
+		entries = append(entries, &FeedEntry{
+			Article:        article,
+			AuthorUsername: usernames[article.UserID],
+		})
+	}
+
+	var next uint64
+	if len(items) == limit {
+		next = items[len(items)-1].ID
+	}
+	return entries, next, nil
+}
+
+// StartFeedConsumer keeps feeds up to date from article events.
+func (s *ArticleService) StartFeedConsumer(ctx context.Context) error {
+	// Declare queue for article events
+	if err := s.mq.DeclareQueue("article-feed-events"); err != nil {
+		return err
+	}
+
+	// Bind to articles exchange
+	if err := s.mq.BindQueue("article-feed-events", "articles", rabbitmq.EventArticlePublished); err != nil {
+		return err
+	}
+	if err := s.mq.BindQueue("article-feed-events", "articles", rabbitmq.EventArticleDeleted); err != nil {
+		return err
+	}
+
+	return s.mq.Consume("article-feed-events", s.handleFeedEvent)
+}
+
+func (s *ArticleService) handleFeedEvent(event rabbitmq.Event) error {
+	switch event.Type {
+	case rabbitmq.EventArticlePublished:
+		visibility, _ := event.Data["visibility"].(string)
+		if repository.Visibility(visibility) != repository.VisibilityPublic {
+			return nil
+		}
+		articleID, err := event.ID("article_id")
+		if err != nil {
+			return err
+		}
+		authorID, err := event.ID("user_id")
+		if err != nil {
+			return err
+		}
+
+		delivered, err := s.follows.FanOut(articleID, authorID, event.Timestamp)
+		if err != nil {
+			return err
+		}
+		s.logger.Debug("article delivered to feeds", "article_id", articleID, "followers", delivered)
+
+	case rabbitmq.EventArticleDeleted:
+		articleID, err := event.ID("article_id")
+		if err != nil {
+			return err
+		}
+		return s.follows.RemoveArticle(articleID)
+	}
+
+	return nil
+}
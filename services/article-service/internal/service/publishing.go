@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/XRS0/blog/services/article-service/internal/repository"
+	"github.com/XRS0/blog/shared/rabbitmq"
+)
+
+// publishPublished announces that an article went live for the first time.
+// Feeds are built from this event rather than from article.created, so drafts
+// and scheduled articles reach readers only once they are published.
+func (s *ArticleService) publishPublished(ctx context.Context, article *repository.Article) {
+	event := rabbitmq.Event{
+		Type: rabbitmq.EventArticlePublished,
+		Data: map[string]interface{}{
+			"article_id": article.ID,
+			"user_id":    article.UserID,
+			"visibility": string(article.Visibility),
+		},
+	}
+	if err := s.mq.Publish(ctx, "articles", rabbitmq.EventArticlePublished, event); err != nil {
+		s.logger.Error("failed to publish article published event", "article_id", article.ID, "error", err)
+	}
+}
+
+// StartScheduler periodically publishes scheduled articles whose time has come.
+func (s *ArticleService) StartScheduler(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.publishDue(ctx)
+			}
+		}
+	}()
+}
+
+func (s *ArticleService) publishDue(ctx context.Context) {
+	articles, err := s.repo.PublishDue(time.Now())
+	if err != nil {
+		s.logger.Error("failed to publish scheduled articles", "error", err)
+		return
+	}
+
+	for _, article := range articles {
+		s.publishPublished(ctx, article)
+		s.logger.Info("scheduled article published", "article_id", article.ID, "user_id", article.UserID)
+	}
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/XRS0/blog/services/article-service/internal/diff"
+	"github.com/XRS0/blog/services/article-service/internal/repository"
+)
+
+// diffContext is the number of unchanged lines shown around each change.
+const diffContext = 3
+
+// ownArticle returns the article if it belongs to the user. Only authors may
+// see the history of their articles, since it can hold text they removed.
+func (s *ArticleService) ownArticle(articleID, userID uint64) (*repository.Article, error) {
+	article, err := s.repo.GetByID(articleID)
+	if err != nil {
+		return nil, fmt.Errorf("article not found")
+	}
+	if !article.OwnedBy(userID) {
+		return nil, fmt.Errorf("unauthorized")
+	}
+	return article, nil
+}
+
+// ListRevisions returns the revisions of an article, newest first, without their
+// content, and their total count.
+func (s *ArticleService) ListRevisions(ctx context.Context, articleID, userID uint64, limit, offset int) ([]*repository.ArticleRevision, int, error) {
+	if _, err := s.ownArticle(articleID, userID); err != nil {
+		return nil, 0, err
+	}
+	return s.revisions.List(articleID, limit, offset)
+}
+
+func (s *ArticleService) GetRevision(ctx context.Context, articleID, userID uint64, number int) (*repository.ArticleRevision, error) {
+	if _, err := s.ownArticle(articleID, userID); err != nil {
+		return nil, err
+	}
+	return s.revisions.Get(articleID, number)
+}
+
+// DiffRevisions returns a unified diff of the content between two revisions.
+// Revision 0 stands for an empty article.
+func (s *ArticleService) DiffRevisions(ctx context.Context, articleID, userID uint64, from, to int) (string, error) {
+	if _, err := s.ownArticle(articleID, userID); err != nil {
+		return "", err
+	}
+
+	fromContent, err := s.revisionContent(articleID, from)
+	if err != nil {
+		return "", err
+	}
+	toContent, err := s.revisionContent(articleID, to)
+	if err != nil {
+		return "", err
+	}
+
+	return diff.Unified(revisionName(from), revisionName(to), fromContent, toContent, diffContext), nil
+}
+
+func (s *ArticleService) revisionContent(articleID uint64, number int) (string, error) {
+	if number == 0 {
+		return "", nil
+	}
+	revision, err := s.revisions.Get(articleID, number)
+	if err != nil {
+		return "", err
+	}
+	return revision.Content, nil
+}
+
+func revisionName(number int) string {
+	return "revision " + strconv.Itoa(number)
+}
+
+// RestoreRevision brings back the title and content of a revision as a new
+// revision. Visibility and status stay as they are. If expectedVersion is set and
+// the article has moved on, the current article is returned with
+// repository.ErrVersionConflict.
+func (s *ArticleService) RestoreRevision(ctx context.Context, articleID, userID uint64, number, expectedVersion int) (*repository.Article, error) {
+	article, err := s.repo.Restore(articleID, userID, number, expectedVersion)
+	if err != nil {
+		return article, err
+	}
+	s.publishUpdated(ctx, article)
+
+	s.logger.Info("article revision restored", "article_id", articleID, "revision", number)
+	return article, nil
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/XRS0/blog/services/article-service/internal/repository"
+)
+
+// maxSearchQueryLength limits search queries, in characters.
+const maxSearchQueryLength = 200
+
+var highlighter = strings.NewReplacer(
+	repository.HighlightStart, "<mark>",
+	repository.HighlightStop, "</mark>",
+)
+
+// Search finds articles the viewer may read that match the query, best matches
+// first, and returns the usernames of their authors and the total number of
+// matches. Highlighted titles and snippets are HTML with matches in <mark>.
+func (s *ArticleService) Search(ctx context.Context, query string, viewerID uint64, filter repository.SearchFilter, limit, offset int) ([]*repository.SearchResult, []string, int, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, nil, 0, fmt.Errorf("query is required")
+	}
+	if utf8.RuneCountInString(query) > maxSearchQueryLength {
+		return nil, nil, 0, fmt.Errorf("query is longer than %d characters", maxSearchQueryLength)
+	}
+
+	if filter.Tag != "" {
+		filter.Tag = repository.NormalizeTag(filter.Tag)
+		if filter.Tag == "" {
+			return nil, nil, 0, fmt.Errorf("invalid tag")
+		}
+	}
+
+	results, total, err := s.repo.Search(query, viewerID, filter, limit, offset)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	articles := make([]*repository.Article, len(results))
+	for i, result := range results {
+		result.TitleHighlight = highlightHTML(result.TitleHighlight)
+		result.Snippet = highlightHTML(result.Snippet)
+		articles[i] = &result.Article
+	}
+
+	return results, s.articleAuthors(ctx, articles), total, nil
+}
+
+// highlightHTML escapes a headline from the search index and marks its matches.
+func highlightHTML(headline string) string {
+	return highlighter.Replace(html.EscapeString(headline))
+}
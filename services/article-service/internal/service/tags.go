@@ -0,0 +1,31 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/XRS0/blog/services/article-service/internal/repository"
+)
+
+// ListTags returns the tags in use with the number of published public articles
+// filed under each, most used first.
+func (s *ArticleService) ListTags(ctx context.Context, limit, offset int) ([]*repository.TagCount, error) {
+	return s.tags.List(limit, offset)
+}
+
+// ListByTag returns the published public articles with the tag, as List does,
+// and the usernames of their authors. The tag may be given in any form that
+// normalizes to its slug.
+func (s *ArticleService) ListByTag(ctx context.Context, tag string, viewerID uint64, limit, offset int) ([]*repository.Article, []string, error) {
+	slug := repository.NormalizeTag(tag)
+	if slug == "" {
+		return nil, nil, fmt.Errorf("invalid tag")
+	}
+
+	articles, err := s.repo.ListByTag(slug, viewerID, limit, offset)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return articles, s.articleAuthors(ctx, articles), nil
+}
@@ -0,0 +1,331 @@
+package keys
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/XRS0/blog/services/auth-service/internal/repository"
+	"github.com/XRS0/blog/shared/jwks"
+)
+
+// Supported signing algorithms.
+const (
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+// reloadCooldown limits how often an unknown kid triggers a reload from the database.
+const reloadCooldown = 10 * time.Second
+
+type signingKey struct {
+	kid     string
+	method  jwt.SigningMethod
+	private crypto.Signer
+}
+
+// Keyring holds the signing keys shared by all auth-service instances. Keys live
+// in the database so that every instance signs with the same active key and can
+// verify tokens signed by any key that has not been purged yet.
+type Keyring struct {
+	repo      *repository.SigningKeyRepository
+	algorithm string
+	retention time.Duration
+	logger    *slog.Logger
+
+	mu         sync.RWMutex
+	active     *signingKey
+	keys       map[string]*signingKey
+	lastReload time.Time
+}
+
+// NewKeyring creates a keyring that signs with algorithm. Retired keys are kept
+// for verification during retention, which must cover the longest token lifetime.
+func NewKeyring(repo *repository.SigningKeyRepository, algorithm string, retention time.Duration, logger *slog.Logger) (*Keyring, error) {
+	if _, err := signingMethod(algorithm); err != nil {
+		return nil, err
+	}
+
+	return &Keyring{
+		repo:      repo,
+		algorithm: algorithm,
+		retention: retention,
+		logger:    logger,
+		keys:      make(map[string]*signingKey),
+	}, nil
+}
+
+// NewEphemeral creates a keyring holding one generated key that is not stored
+// anywhere, for tests that sign and verify their own tokens. It must not be
+// loaded, rotated or refreshed.
+func NewEphemeral(algorithm string, logger *slog.Logger) (*Keyring, error) {
+	method, err := signingMethod(algorithm)
+	if err != nil {
+		return nil, err
+	}
+	private, err := generateKey(algorithm)
+	if err != nil {
+		return nil, err
+	}
+	kid, err := randomKid()
+	if err != nil {
+		return nil, err
+	}
+
+	key := &signingKey{kid: kid, method: method, private: private}
+	return &Keyring{
+		algorithm: algorithm,
+		logger:    logger,
+		active:    key,
+		keys:      map[string]*signingKey{kid: key},
+	}, nil
+}
+
+// Load reads the keys from the database, generating the first key (or a key for
+// a newly configured algorithm) when needed.
+func (k *Keyring) Load() error {
+	if err := k.reload(); err != nil {
+		return err
+	}
+
+	k.mu.RLock()
+	active := k.active
+	k.mu.RUnlock()
+
+	if active == nil || active.method.Alg() != k.algorithm {
+		if _, err := k.Rotate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Rotate generates a new active key and retires the previous one.
+func (k *Keyring) Rotate() (string, error) {
+	kid, err := randomKid()
+	if err != nil {
+		return "", err
+	}
+
+	private, err := generateKey(k.algorithm)
+	if err != nil {
+		return "", err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode signing key: %w", err)
+	}
+
+	if err := k.repo.Rotate(&repository.SigningKey{
+		Kid:        kid,
+		Algorithm:  k.algorithm,
+		PrivateKey: string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+	}); err != nil {
+		return "", err
+	}
+
+	if err := k.reload(); err != nil {
+		return "", err
+	}
+
+	k.logger.Info("signing key rotated", "kid", kid, "algorithm", k.algorithm)
+	return kid, nil
+}
+
+// Sign signs claims with the active key and sets the kid header.
+func (k *Keyring) Sign(claims jwt.Claims) (string, error) {
+	k.mu.RLock()
+	active := k.active
+	k.mu.RUnlock()
+
+	if active == nil {
+		return "", fmt.Errorf("no active signing key")
+	}
+
+	token := jwt.NewWithClaims(active.method, claims)
+	token.Header["kid"] = active.kid
+	return token.SignedString(active.private)
+}
+
+// Keyfunc resolves the verification key for a token by its kid header.
+func (k *Keyring) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return nil, fmt.Errorf("missing kid")
+	}
+
+	key, ok := k.lookup(kid)
+	if !ok {
+		// The key may have been created by another instance since the last reload.
+		if k.reloadAllowed() {
+			if err := k.reload(); err != nil {
+				k.logger.Error("failed to reload signing keys", "error", err)
+			}
+			key, ok = k.lookup(kid)
+		}
+		if !ok {
+			return nil, fmt.Errorf("unknown kid %q", kid)
+		}
+	}
+
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+	}
+	return key.private.Public(), nil
+}
+
+// ValidMethods lists the algorithms accepted when parsing tokens.
+func (k *Keyring) ValidMethods() []string {
+	return []string{AlgorithmRS256, AlgorithmEdDSA}
+}
+
+// JWKS returns the public keys that may still have signed unexpired tokens.
+func (k *Keyring) JWKS() (*jwks.Set, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	set := &jwks.Set{Keys: make([]jwks.Key, 0, len(k.keys))}
+	for _, key := range k.keys {
+		jwk, err := jwks.NewKey(key.kid, key.method.Alg(), key.private.Public())
+		if err != nil {
+			return nil, err
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set, nil
+}
+
+// StartRefresh periodically reloads keys rotated by other instances and purges
+// keys retired longer than the retention period.
+func (k *Keyring) StartRefresh(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := k.repo.DeleteRetiredBefore(time.Now().Add(-k.retention)); err != nil {
+					k.logger.Error("failed to purge signing keys", "error", err)
+				}
+				if err := k.reload(); err != nil {
+					k.logger.Error("failed to reload signing keys", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+func (k *Keyring) lookup(kid string) (*signingKey, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	key, ok := k.keys[kid]
+	return key, ok
+}
+
+func (k *Keyring) reloadAllowed() bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if time.Since(k.lastReload) < reloadCooldown {
+		return false
+	}
+	k.lastReload = time.Now()
+	return true
+}
+
+func (k *Keyring) reload() error {
+	records, err := k.repo.ListUsable(time.Now().Add(-k.retention))
+	if err != nil {
+		return err
+	}
+
+	keys := make(map[string]*signingKey, len(records))
+	var active *signingKey
+	for _, record := range records {
+		key, err := decodeKey(record)
+		if err != nil {
+			k.logger.Error("skipping invalid signing key", "kid", record.Kid, "error", err)
+			continue
+		}
+		keys[key.kid] = key
+		// Records are ordered newest first.
+		if active == nil && record.RetiredAt.IsZero() {
+			active = key
+		}
+	}
+
+	k.mu.Lock()
+	k.keys = keys
+	k.active = active
+	k.lastReload = time.Now()
+	k.mu.Unlock()
+	return nil
+}
+
+func decodeKey(record *repository.SigningKey) (*signingKey, error) {
+	method, err := signingMethod(record.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode([]byte(record.PrivateKey))
+	if block == nil {
+		return nil, fmt.Errorf("invalid PEM")
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+
+	private, ok := parsed.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", parsed)
+	}
+
+	return &signingKey{kid: record.Kid, method: method, private: private}, nil
+}
+
+func signingMethod(algorithm string) (jwt.SigningMethod, error) {
+	switch algorithm {
+	case AlgorithmRS256:
+		return jwt.SigningMethodRS256, nil
+	case AlgorithmEdDSA:
+		return jwt.SigningMethodEdDSA, nil
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm %q", algorithm)
+	}
+}
+
+func generateKey(algorithm string) (crypto.Signer, error) {
+	switch algorithm {
+	case AlgorithmRS256:
+		return rsa.GenerateKey(rand.Reader, 2048)
+	case AlgorithmEdDSA:
+		_, private, err := ed25519.GenerateKey(rand.Reader)
+		return private, err
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm %q", algorithm)
+	}
+}
+
+func randomKid() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", b), nil
+}
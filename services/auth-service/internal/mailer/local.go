@@ -0,0 +1,50 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+// LogMailer writes messages to the logger instead of delivering them.
+// Intended for local development.
+type LogMailer struct {
+	logger *slog.Logger
+}
+
+func NewLogMailer(logger *slog.Logger) *LogMailer {
+	return &LogMailer{logger: logger}
+}
+
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	m.logger.Info("email", "to", msg.To, "subject", msg.Subject, "body", msg.Body)
+	return nil
+}
+
+// FileMailer stores every message as a separate file in a directory, so that
+// tests and local setups can read what would have been sent.
+type FileMailer struct {
+	dir string
+	seq atomic.Uint64
+}
+
+func NewFileMailer(dir string) (*FileMailer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create mail directory: %w", err)
+	}
+	return &FileMailer{dir: dir}, nil
+}
+
+func (m *FileMailer) Send(ctx context.Context, msg Message) error {
+	name := fmt.Sprintf("%s-%d.eml", time.Now().UTC().Format("20060102T150405.000000000"), m.seq.Add(1))
+	content := fmt.Sprintf("To: %s\nSubject: %s\n\n%s\n", msg.To, msg.Subject, msg.Body)
+
+	if err := os.WriteFile(filepath.Join(m.dir, name), []byte(content), 0o644); err != nil {
+		return fmt.Errorf("failed to write email: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,15 @@
+package mailer
+
+import "context"
+
+// Message is a plain-text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers email messages.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
@@ -0,0 +1,258 @@
+// Package oidc implements the relying-party side of the OpenID Connect
+// authorization code flow with PKCE.
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/XRS0/blog/shared/jwks"
+)
+
+// ProviderConfig describes an OpenID provider registered with this application.
+type ProviderConfig struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+// Claims are the ID token claims used for signing users in.
+type Claims struct {
+	jwt.RegisteredClaims
+	Nonce             string `json:"nonce"`
+	Email             string `json:"email"`
+	EmailVerified     bool   `json:"email_verified"`
+	PreferredUsername string `json:"preferred_username"`
+	Name              string `json:"name"`
+}
+
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Provider talks to one OpenID provider. Discovery metadata and keys are fetched
+// lazily and cached, so a provider that is down does not prevent startup.
+type Provider struct {
+	config ProviderConfig
+	client *http.Client
+
+	mu        sync.Mutex
+	discovery *discovery
+	keys      *jwks.Set
+	keysAt    time.Time
+}
+
+func NewProvider(config ProviderConfig, client *http.Client) *Provider {
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{"openid", "email", "profile"}
+	}
+	return &Provider{config: config, client: client}
+}
+
+func (p *Provider) Name() string {
+	return p.config.Name
+}
+
+// AuthCodeURL returns the URL the user is sent to. The verifier is kept by the
+// caller and passed to Exchange; only its S256 challenge leaves the server.
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	d, err := p.getDiscovery(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	challenge := sha256.Sum256([]byte(verifier))
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", p.config.ClientID)
+	params.Set("redirect_uri", p.config.RedirectURL)
+	params.Set("scope", strings.Join(p.config.Scopes, " "))
+	params.Set("state", state)
+	params.Set("nonce", nonce)
+	params.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	params.Set("code_challenge_method", "S256")
+
+	sep := "?"
+	if strings.Contains(d.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return d.AuthorizationEndpoint + sep + params.Encode(), nil
+}
+
+// Exchange redeems an authorization code and returns the verified ID token claims.
+func (p *Provider) Exchange(ctx context.Context, code, verifier, nonce string) (*Claims, error) {
+	d, err := p.getDiscovery(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.config.RedirectURL)
+	form.Set("code_verifier", verifier)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+
+	var token struct {
+		IDToken string `json:"id_token"`
+		Error   string `json:"error"`
+	}
+	if err := p.doJSON(req, &token); err != nil {
+		return nil, fmt.Errorf("token exchange failed: %w", err)
+	}
+	if token.Error != "" {
+		return nil, fmt.Errorf("token exchange failed: %s", token.Error)
+	}
+	if token.IDToken == "" {
+		return nil, fmt.Errorf("token response has no id_token")
+	}
+
+	return p.verifyIDToken(ctx, token.IDToken, nonce)
+}
+
+func (p *Provider) verifyIDToken(ctx context.Context, raw, nonce string) (*Claims, error) {
+	d, err := p.getDiscovery(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := &Claims{}
+	_, err = jwt.ParseWithClaims(raw, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, err := p.key(ctx, kid)
+		if err != nil {
+			return nil, err
+		}
+		return key.PublicKey()
+	},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512", "EdDSA"}),
+		jwt.WithIssuer(d.Issuer),
+		jwt.WithAudience(p.config.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id token: %w", err)
+	}
+
+	if claims.Nonce != nonce {
+		return nil, fmt.Errorf("invalid id token: nonce mismatch")
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("invalid id token: missing subject")
+	}
+	return claims, nil
+}
+
+func (p *Provider) getDiscovery(ctx context.Context) (*discovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(p.config.Issuer, "/")+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &discovery{}
+	if err := p.doJSON(req, d); err != nil {
+		return nil, fmt.Errorf("oidc discovery failed for %s: %w", p.config.Name, err)
+	}
+	if d.Issuer != p.config.Issuer {
+		return nil, fmt.Errorf("oidc discovery failed for %s: issuer mismatch %q", p.config.Name, d.Issuer)
+	}
+	if d.AuthorizationEndpoint == "" || d.TokenEndpoint == "" || d.JWKSURI == "" {
+		return nil, fmt.Errorf("oidc discovery failed for %s: incomplete metadata", p.config.Name)
+	}
+
+	p.discovery = d
+	return d, nil
+}
+
+// key returns the provider key for kid, refetching the key set when kid is unknown
+// (at most once a minute) to pick up provider key rotation.
+func (p *Provider) key(ctx context.Context, kid string) (jwks.Key, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.keys != nil {
+		if key, ok := p.find(kid); ok {
+			return key, nil
+		}
+		if time.Since(p.keysAt) < time.Minute {
+			return jwks.Key{}, fmt.Errorf("unknown kid %q", kid)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.discovery.JWKSURI, nil)
+	if err != nil {
+		return jwks.Key{}, err
+	}
+
+	set := &jwks.Set{}
+	if err := p.doJSON(req, set); err != nil {
+		return jwks.Key{}, fmt.Errorf("failed to fetch provider keys: %w", err)
+	}
+	p.keys = set
+	p.keysAt = time.Now()
+
+	if key, ok := p.find(kid); ok {
+		return key, nil
+	}
+	return jwks.Key{}, fmt.Errorf("unknown kid %q", kid)
+}
+
+// find looks up kid; tokens without a kid are accepted if the provider has a single key.
+func (p *Provider) find(kid string) (jwks.Key, bool) {
+	if kid == "" && len(p.keys.Keys) == 1 {
+		return p.keys.Keys[0], true
+	}
+	return p.keys.Find(kid)
+}
+
+func (p *Provider) doJSON(req *http.Request, v interface{}) error {
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusBadRequest {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("invalid response: %w", err)
+	}
+	return nil
+}
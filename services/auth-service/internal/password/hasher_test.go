@@ -0,0 +1,132 @@
+package password
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testParams keep the tests fast; they are far below what production uses.
+var testParams = Argon2Params{Memory: 1024, Iterations: 1, Parallelism: 1}
+
+func TestArgon2idRoundTrip(t *testing.T) {
+	h := NewArgon2id(testParams)
+
+	encoded, err := h.Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(encoded, "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Errorf("unexpected encoding %q", encoded)
+	}
+
+	if ok, err := h.Verify("correct horse", encoded); err != nil || !ok {
+		t.Errorf("Verify(correct) = %v, %v; want true", ok, err)
+	}
+	if ok, err := h.Verify("wrong horse", encoded); err != nil || ok {
+		t.Errorf("Verify(wrong) = %v, %v; want false", ok, err)
+	}
+
+	other, err := h.Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other == encoded {
+		t.Error("two hashes of the same password are equal; salt is not random")
+	}
+	if h.NeedsRehash(encoded) {
+		t.Error("fresh hash needs rehash")
+	}
+}
+
+func TestBcryptStillVerifies(t *testing.T) {
+	h := NewArgon2id(testParams)
+
+	legacy, err := bcrypt.GenerateFromPassword([]byte("legacy secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, err := h.Verify("legacy secret", string(legacy)); err != nil || !ok {
+		t.Errorf("Verify(correct) = %v, %v; want true", ok, err)
+	}
+	if ok, err := h.Verify("other secret", string(legacy)); err != nil || ok {
+		t.Errorf("Verify(wrong) = %v, %v; want false", ok, err)
+	}
+	if !h.NeedsRehash(string(legacy)) {
+		t.Error("bcrypt hash does not need rehash")
+	}
+}
+
+func TestNeedsRehashWeakerParams(t *testing.T) {
+	weak, err := NewArgon2id(testParams).Hash("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		params Argon2Params
+		want   bool
+	}{
+		{"same", testParams, false},
+		{"more memory", Argon2Params{Memory: 2048, Iterations: 1, Parallelism: 1}, true},
+		{"more iterations", Argon2Params{Memory: 1024, Iterations: 2, Parallelism: 1}, true},
+		{"other parallelism", Argon2Params{Memory: 1024, Iterations: 1, Parallelism: 2}, true},
+		{"longer salt", Argon2Params{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 32}, true},
+		{"longer key", Argon2Params{Memory: 1024, Iterations: 1, Parallelism: 1, KeyLength: 64}, true},
+		{"less memory", Argon2Params{Memory: 512, Iterations: 1, Parallelism: 1}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewArgon2id(tt.params).NeedsRehash(weak); got != tt.want {
+				t.Errorf("NeedsRehash() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMalformedHashes(t *testing.T) {
+	h := NewArgon2id(testParams)
+
+	valid, err := h.Hash("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(valid, "$")
+	with := func(i int, value string) string {
+		changed := append([]string(nil), parts...)
+		changed[i] = value
+		return strings.Join(changed, "$")
+	}
+
+	tests := []struct {
+		name    string
+		encoded string
+	}{
+		{"empty", ""},
+		{"plain text", "secret"},
+		{"missing key", strings.Join(parts[:5], "$")},
+		{"extra field", valid + "$x"},
+		{"argon2i", with(1, "argon2i")},
+		{"old version", with(2, "v=16")},
+		{"bad parameters", with(3, "m=1024,t=1")},
+		{"zero memory", with(3, "m=0,t=1,p=1")},
+		{"bad salt", with(4, "not base64!")},
+		{"bad key", with(5, "not base64!")},
+		{"empty key", with(5, "")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, err := h.Verify("secret", tt.encoded)
+			if ok || !errors.Is(err, ErrUnknownFormat) {
+				t.Errorf("Verify() = %v, %v; want false, ErrUnknownFormat", ok, err)
+			}
+			if !h.NeedsRehash(tt.encoded) {
+				t.Error("NeedsRehash() = false for a malformed hash")
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package password
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// commonPasswords is the built-in list of frequently used passwords, one per line.
+//
+//go:embed common_passwords.txt
+var commonPasswords []byte
+
+// Policy decides which new passwords are acceptable. Existing passwords are not
+// checked, so tightening the policy does not lock anyone out.
+type Policy struct {
+	// MinLength and MaxLength count characters, not bytes. Zero MaxLength means no limit.
+	MinLength int
+	MaxLength int
+
+	common map[string]struct{}
+}
+
+// NewPolicy returns a policy refusing passwords on the common list. A nil list
+// disables the common password check.
+func NewPolicy(minLength, maxLength int, common []string) *Policy {
+	p := &Policy{MinLength: minLength, MaxLength: maxLength}
+	if common != nil {
+		p.common = make(map[string]struct{}, len(common))
+		for _, password := range common {
+			p.common[strings.ToLower(password)] = struct{}{}
+		}
+	}
+	return p
+}
+
+// Check returns a user-facing error if the password does not satisfy the policy.
+func (p *Policy) Check(password string) error {
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		return fmt.Errorf("password must be at least %d characters", p.MinLength)
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		return fmt.Errorf("password must be at most %d characters", p.MaxLength)
+	}
+	if _, ok := p.common[strings.ToLower(password)]; ok {
+		return fmt.Errorf("password is too common")
+	}
+	return nil
+}
+
+// LoadCommonPasswords reads a list of passwords, one per line, from path. An
+// empty path returns the built-in list.
+func LoadCommonPasswords(path string) ([]string, error) {
+	if path == "" {
+		return readList(bytes.NewReader(commonPasswords))
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open common password list: %w", err)
+	}
+	defer f.Close()
+
+	list, err := readList(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read common password list: %w", err)
+	}
+	return list, nil
+}
+
+func readList(r io.Reader) ([]string, error) {
+	var list []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" && !strings.HasPrefix(line, "#") {
+			list = append(list, line)
+		}
+	}
+	return list, scanner.Err()
+}
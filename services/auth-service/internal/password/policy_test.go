@@ -0,0 +1,56 @@
+package password
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPolicyCheck(t *testing.T) {
+	p := NewPolicy(8, 10, []string{"Password1"})
+
+	tests := []struct {
+		name     string
+		password string
+		valid    bool
+	}{
+		{"ascii", "abcdefgh", true},
+		// 8 characters but 15 bytes
+		{"cyrillic at minimum", "пароль12", true},
+		// 7 characters but 13 bytes, so a byte count would accept it
+		{"cyrillic too short", "пароль1", false},
+		// 10 characters but 20 bytes, so a byte count would reject it
+		{"accents at maximum", "éééééééééé", true},
+		{"accents too long", "ééééééééééé", false},
+		{"emoji too short", "😀😀😀😀😀😀😀", false},
+		{"common", "password1", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := p.Check(tt.password); (err == nil) != tt.valid {
+				t.Errorf("Check(%q) = %v, want valid %v", tt.password, err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestPolicyWithoutLimits(t *testing.T) {
+	p := NewPolicy(0, 0, nil)
+	for _, password := range []string{"", "password1", strings.Repeat("a", 1000)} {
+		if err := p.Check(password); err != nil {
+			t.Errorf("Check(%q) = %v, want nil", password, err)
+		}
+	}
+}
+
+func TestLoadCommonPasswords(t *testing.T) {
+	list, err := LoadCommonPasswords("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) == 0 {
+		t.Fatal("built-in list is empty")
+	}
+	if err := NewPolicy(1, 0, list).Check(list[0]); err == nil {
+		t.Errorf("Check(%q) accepted a password from the built-in list", list[0])
+	}
+}
@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// AuthEvent is an entry of the security audit trail. Entries are only ever
+// appended; nothing updates or deletes them.
+type AuthEvent struct {
+	bun.BaseModel `bun:"table:auth_events,alias:ae"`
+
+	ID        uint64                 `bun:"id,pk,autoincrement"`
+	Type      string                 `bun:"type,notnull"`
+	UserID    uint64                 `bun:"user_id,nullzero"`
+	IP        string                 `bun:"ip,notnull,default:''"`
+	UserAgent string                 `bun:"user_agent,notnull,default:''"`
+	Details   map[string]interface{} `bun:"details,type:jsonb"`
+	CreatedAt time.Time              `bun:"created_at,nullzero,notnull,default:current_timestamp"`
+}
+
+// AuthEventFilter narrows ListAuthEvents. Zero fields do not filter.
+type AuthEventFilter struct {
+	UserID uint64
+	// Type matches the event type exactly or as a prefix ending before a dot,
+	// so "auth.login" matches "auth.login.failed".
+	Type  string
+	IP    string
+	Since time.Time
+	Until time.Time
+}
+
+type AuditRepository struct {
+	db *bun.DB
+}
+
+func NewAuditRepository(db *bun.DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+// EnsureIndexes creates the indexes used by the admin queries.
+func (r *AuditRepository) EnsureIndexes() error {
+	ctx := context.Background()
+
+	for _, query := range []string{
+		"CREATE INDEX IF NOT EXISTS auth_events_user_id_idx ON auth_events (user_id, id)",
+		"CREATE INDEX IF NOT EXISTS auth_events_type_idx ON auth_events (type, id)",
+		"CREATE INDEX IF NOT EXISTS auth_events_created_at_idx ON auth_events (created_at)",
+	} {
+		if _, err := r.db.ExecContext(ctx, query); err != nil {
+			return fmt.Errorf("failed to create auth event index: %w", err)
+		}
+	}
+	return nil
+}
+
+func (r *AuditRepository) Append(event *AuthEvent) error {
+	ctx := context.Background()
+
+	_, err := r.db.NewInsert().Model(event).Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to append auth event: %w", err)
+	}
+	return nil
+}
+
+// List returns matching events, newest first, and their total count.
+func (r *AuditRepository) List(filter AuthEventFilter, limit, offset int) ([]*AuthEvent, int, error) {
+	ctx := context.Background()
+	var events []*AuthEvent
+
+	query := r.db.NewSelect().
+		Model(&events).
+		Order("id DESC").
+		Limit(limit).
+		Offset(offset)
+
+	if filter.UserID != 0 {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.Type != "" {
+		query = query.Where("type = ? OR type LIKE ?", filter.Type, escapeLike(filter.Type)+".%")
+	}
+	if filter.IP != "" {
+		query = query.Where("ip = ?", filter.IP)
+	}
+	if !filter.Since.IsZero() {
+		query = query.Where("created_at >= ?", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		query = query.Where("created_at < ?", filter.Until)
+	}
+
+	total, err := query.ScanAndCount(ctx)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list auth events: %w", err)
+	}
+
+	return events, total, nil
+}
+
+// escapeLike escapes the LIKE wildcards in s.
+func escapeLike(s string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return replacer.Replace(s)
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// Kinds of user relationships. A block keeps the blocked user away from the
+// blocker's articles; a mute only hides the muted user's articles from the muter.
+const (
+	BlockKindBlock = "block"
+	BlockKindMute  = "mute"
+)
+
+type UserBlock struct {
+	bun.BaseModel `bun:"table:user_blocks,alias:ub"`
+
+	BlockerID uint64    `bun:"blocker_id,pk"`
+	BlockedID uint64    `bun:"blocked_id,pk"`
+	Kind      string    `bun:"kind,pk"`
+	CreatedAt time.Time `bun:"created_at,nullzero,notnull,default:current_timestamp"`
+}
+
+type BlockRepository struct {
+	db *bun.DB
+}
+
+func NewBlockRepository(db *bun.DB) *BlockRepository {
+	return &BlockRepository{db: db}
+}
+
+// Add records the relationship. Adding it twice is not an error.
+func (r *BlockRepository) Add(blockerID, blockedID uint64, kind string) error {
+	ctx := context.Background()
+
+	block := &UserBlock{
+		BlockerID: blockerID,
+		BlockedID: blockedID,
+		Kind:      kind,
+		CreatedAt: time.Now(),
+	}
+	_, err := r.db.NewInsert().
+		Model(block).
+		On("CONFLICT DO NOTHING").
+		Exec(ctx)
+
+	if err != nil {
+		return fmt.Errorf("failed to save %s: %w", kind, err)
+	}
+	return nil
+}
+
+// Remove deletes the relationship and reports whether it existed.
+func (r *BlockRepository) Remove(blockerID, blockedID uint64, kind string) (bool, error) {
+	ctx := context.Background()
+
+	res, err := r.db.NewDelete().
+		Model((*UserBlock)(nil)).
+		Where("blocker_id = ? AND blocked_id = ? AND kind = ?", blockerID, blockedID, kind).
+		Exec(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to remove %s: %w", kind, err)
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to remove %s: %w", kind, err)
+	}
+	return rows > 0, nil
+}
+
+// List returns the users the blocker has blocked or muted, newest first.
+func (r *BlockRepository) List(blockerID uint64, kind string) ([]*UserBlock, error) {
+	ctx := context.Background()
+	var blocks []*UserBlock
+
+	err := r.db.NewSelect().
+		Model(&blocks).
+		Where("blocker_id = ? AND kind = ?", blockerID, kind).
+		Order("created_at DESC").
+		Scan(ctx)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %ss: %w", kind, err)
+	}
+	return blocks, nil
+}
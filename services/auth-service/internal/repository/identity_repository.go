@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// UserIdentity links a user to an account at an external OpenID provider.
+type UserIdentity struct {
+	bun.BaseModel `bun:"table:user_identities,alias:ui"`
+
+	ID        uint64    `bun:"id,pk,autoincrement"`
+	UserID    uint64    `bun:"user_id,notnull"`
+	Provider  string    `bun:"provider,notnull,unique:provider_subject"`
+	Subject   string    `bun:"subject,notnull,unique:provider_subject"`
+	Email     string    `bun:"email"`
+	CreatedAt time.Time `bun:"created_at,nullzero,notnull,default:current_timestamp"`
+}
+
+// OIDCLoginState is a pending authorization request. It keeps the PKCE verifier
+// and nonce on the server and is consumed by the callback.
+type OIDCLoginState struct {
+	bun.BaseModel `bun:"table:oidc_login_states,alias:ols"`
+
+	StateHash    string    `bun:"state_hash,pk"`
+	Provider     string    `bun:"provider,notnull"`
+	CodeVerifier string    `bun:"code_verifier,notnull"`
+	Nonce        string    `bun:"nonce,notnull"`
+	ExpiresAt    time.Time `bun:"expires_at,notnull"`
+}
+
+type IdentityRepository struct {
+	db *bun.DB
+}
+
+func NewIdentityRepository(db *bun.DB) *IdentityRepository {
+	return &IdentityRepository{db: db}
+}
+
+// GetByProviderSubject returns the linked identity, or nil if there is none.
+func (r *IdentityRepository) GetByProviderSubject(provider, subject string) (*UserIdentity, error) {
+	ctx := context.Background()
+	identity := new(UserIdentity)
+
+	err := r.db.NewSelect().
+		Model(identity).
+		Where("provider = ?", provider).
+		Where("subject = ?", subject).
+		Scan(ctx)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get identity: %w", err)
+	}
+
+	return identity, nil
+}
+
+func (r *IdentityRepository) Create(userID uint64, provider, subject, email string) error {
+	ctx := context.Background()
+	identity := &UserIdentity{
+		UserID:    userID,
+		Provider:  provider,
+		Subject:   subject,
+		Email:     email,
+		CreatedAt: time.Now(),
+	}
+
+	_, err := r.db.NewInsert().Model(identity).Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create identity: %w", err)
+	}
+
+	return nil
+}
+
+// CreateUserWithIdentity creates a passwordless user and links the identity in one transaction.
+func (r *IdentityRepository) CreateUserWithIdentity(user *User, provider, subject string) error {
+	ctx := context.Background()
+	now := time.Now()
+	user.CreatedAt = now
+	user.UpdatedAt = now
+
+	return r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if _, err := tx.NewInsert().Model(user).Exec(ctx); err != nil {
+			return fmt.Errorf("failed to create user: %w", err)
+		}
+
+		identity := &UserIdentity{
+			UserID:    user.ID,
+			Provider:  provider,
+			Subject:   subject,
+			Email:     user.Email,
+			CreatedAt: now,
+		}
+		if _, err := tx.NewInsert().Model(identity).Exec(ctx); err != nil {
+			return fmt.Errorf("failed to create identity: %w", err)
+		}
+		return nil
+	})
+}
+
+func (r *IdentityRepository) CreateLoginState(state *OIDCLoginState) error {
+	ctx := context.Background()
+
+	_, err := r.db.NewInsert().Model(state).Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create login state: %w", err)
+	}
+
+	return nil
+}
+
+// ConsumeLoginState deletes and returns an unexpired login state, so each state
+// can complete at most one login.
+func (r *IdentityRepository) ConsumeLoginState(stateHash string, now time.Time) (*OIDCLoginState, error) {
+	ctx := context.Background()
+	state := new(OIDCLoginState)
+
+	err := r.db.NewDelete().
+		Model(state).
+		Where("state_hash = ?", stateHash).
+		Where("expires_at > ?", now).
+		Returning("*").
+		Scan(ctx)
+
+	if err != nil {
+		return nil, fmt.Errorf("login state not found: %w", err)
+	}
+
+	return state, nil
+}
+
+func (r *IdentityRepository) DeleteExpiredLoginStates(before time.Time) error {
+	ctx := context.Background()
+
+	_, err := r.db.NewDelete().
+		Model((*OIDCLoginState)(nil)).
+		Where("expires_at < ?", before).
+		Exec(ctx)
+
+	if err != nil {
+		return fmt.Errorf("failed to delete expired login states: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// ErrInviteInvalid is returned when an invite code does not exist, was revoked,
+// has expired or has no uses left.
+var ErrInviteInvalid = errors.New("invalid invite code")
+
+// InviteCode lets up to MaxUses people register while registration is invite-only.
+// Only its SHA-256 hash is stored; Prefix is kept so codes can be told apart.
+type InviteCode struct {
+	bun.BaseModel `bun:"table:invite_codes,alias:ic"`
+
+	ID        uint64    `bun:"id,pk,autoincrement"`
+	CreatedBy uint64    `bun:"created_by,notnull"`
+	Prefix    string    `bun:"prefix,notnull"`
+	CodeHash  string    `bun:"code_hash,notnull,unique"`
+	MaxUses   int       `bun:"max_uses,notnull"`
+	Uses      int       `bun:"uses,notnull,default:0"`
+	ExpiresAt time.Time `bun:"expires_at,nullzero"`
+	RevokedAt time.Time `bun:"revoked_at,nullzero"`
+	CreatedAt time.Time `bun:"created_at,nullzero,notnull,default:current_timestamp"`
+}
+
+type InviteRepository struct {
+	db *bun.DB
+}
+
+func NewInviteRepository(db *bun.DB) *InviteRepository {
+	return &InviteRepository{db: db}
+}
+
+func (r *InviteRepository) Create(invite *InviteCode) error {
+	ctx := context.Background()
+	invite.CreatedAt = time.Now()
+
+	_, err := r.db.NewInsert().Model(invite).Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create invite code: %w", err)
+	}
+
+	return nil
+}
+
+// ListByCreator returns the codes the user created that have not been revoked, newest first.
+func (r *InviteRepository) ListByCreator(userID uint64) ([]*InviteCode, error) {
+	ctx := context.Background()
+	var invites []*InviteCode
+
+	err := r.db.NewSelect().
+		Model(&invites).
+		Where("created_by = ?", userID).
+		Where("revoked_at IS NULL").
+		Order("created_at DESC").
+		Scan(ctx)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to list invite codes: %w", err)
+	}
+
+	return invites, nil
+}
+
+// CountActiveByCreator counts the user's codes that can still be used.
+func (r *InviteRepository) CountActiveByCreator(userID uint64, now time.Time) (int, error) {
+	ctx := context.Background()
+
+	count, err := r.db.NewSelect().
+		Model((*InviteCode)(nil)).
+		Where("created_by = ?", userID).
+		Where("revoked_at IS NULL").
+		Where("expires_at IS NULL OR expires_at > ?", now).
+		Where("uses < max_uses").
+		Count(ctx)
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to count invite codes: %w", err)
+	}
+	return count, nil
+}
+
+// Revoke revokes a code created by userID. It reports false if there was no such active code.
+func (r *InviteRepository) Revoke(userID, id uint64) (bool, error) {
+	ctx := context.Background()
+
+	res, err := r.db.NewUpdate().
+		Model((*InviteCode)(nil)).
+		Set("revoked_at = ?", time.Now()).
+		Where("id = ?", id).
+		Where("created_by = ?", userID).
+		Where("revoked_at IS NULL").
+		Exec(ctx)
+
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke invite code: %w", err)
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke invite code: %w", err)
+	}
+	return rows > 0, nil
+}
+
+// CreateUserWithInvite uses up one use of the code and creates the user, invited
+// by the code's creator, in one transaction. Concurrent registrations cannot use
+// a code more than MaxUses times, and a failed insert gives the use back.
+func (r *InviteRepository) CreateUserWithInvite(user *User, codeHash string, now time.Time) error {
+	ctx := context.Background()
+	user.CreatedAt = now
+	user.UpdatedAt = now
+
+	return r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		invite := new(InviteCode)
+		err := tx.NewUpdate().
+			Model(invite).
+			Set("uses = uses + 1").
+			Where("code_hash = ?", codeHash).
+			Where("revoked_at IS NULL").
+			Where("expires_at IS NULL OR expires_at > ?", now).
+			Where("uses < max_uses").
+			Returning("id, created_by").
+			Scan(ctx)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInviteInvalid
+		}
+		if err != nil {
+			return fmt.Errorf("failed to use invite code: %w", err)
+		}
+
+		user.InvitedBy = invite.CreatedBy
+		user.InviteCodeID = invite.ID
+		if _, err := tx.NewInsert().Model(user).Exec(ctx); err != nil {
+			return fmt.Errorf("failed to create user: %w", err)
+		}
+		return nil
+	})
+}
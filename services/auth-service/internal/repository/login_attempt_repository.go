@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// Kinds of keys failed logins are counted under.
+const (
+	LoginAttemptAccount = "account"
+	LoginAttemptIP      = "ip"
+)
+
+// LoginAttempt counts recent failed logins for one account (by email) or one
+// client address. LockedUntil is set once the failures exceed the free allowance.
+type LoginAttempt struct {
+	bun.BaseModel `bun:"table:login_attempts,alias:la"`
+
+	Kind          string    `bun:"kind,pk"`
+	Key           string    `bun:"key,pk"`
+	Failures      int       `bun:"failures,notnull"`
+	LastFailureAt time.Time `bun:"last_failure_at,notnull"`
+	LockedUntil   time.Time `bun:"locked_until,nullzero"`
+}
+
+type LoginAttemptRepository struct {
+	db *bun.DB
+}
+
+func NewLoginAttemptRepository(db *bun.DB) *LoginAttemptRepository {
+	return &LoginAttemptRepository{db: db}
+}
+
+// LockedUntil returns the lockout end for the key, or the zero time if it is not locked.
+func (r *LoginAttemptRepository) LockedUntil(kind, key string) (time.Time, error) {
+	ctx := context.Background()
+	attempt := new(LoginAttempt)
+
+	err := r.db.NewSelect().
+		Model(attempt).
+		Where("kind = ?", kind).
+		Where("key = ?", key).
+		Scan(ctx)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get login attempts: %w", err)
+	}
+
+	return attempt.LockedUntil, nil
+}
+
+// RecordFailure counts a failed login and returns the number of failures in the
+// current window. Failures older than window are forgotten.
+func (r *LoginAttemptRepository) RecordFailure(kind, key string, window time.Duration, now time.Time) (int, error) {
+	ctx := context.Background()
+	attempt := &LoginAttempt{
+		Kind:          kind,
+		Key:           key,
+		Failures:      1,
+		LastFailureAt: now,
+	}
+
+	_, err := r.db.NewInsert().
+		Model(attempt).
+		On("CONFLICT (kind, key) DO UPDATE").
+		Set("failures = CASE WHEN la.last_failure_at < ? THEN 1 ELSE la.failures + 1 END", now.Add(-window)).
+		Set("last_failure_at = EXCLUDED.last_failure_at").
+		Returning("failures").
+		Exec(ctx)
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to record login attempt: %w", err)
+	}
+
+	return attempt.Failures, nil
+}
+
+func (r *LoginAttemptRepository) Lock(kind, key string, until time.Time) error {
+	ctx := context.Background()
+
+	_, err := r.db.NewUpdate().
+		Model((*LoginAttempt)(nil)).
+		Set("locked_until = ?", until).
+		Where("kind = ?", kind).
+		Where("key = ?", key).
+		Exec(ctx)
+
+	if err != nil {
+		return fmt.Errorf("failed to lock login: %w", err)
+	}
+	return nil
+}
+
+// Reset forgets the failures of the key, lifting any lockout.
+func (r *LoginAttemptRepository) Reset(kind, key string) error {
+	ctx := context.Background()
+
+	_, err := r.db.NewDelete().
+		Model((*LoginAttempt)(nil)).
+		Where("kind = ?", kind).
+		Where("key = ?", key).
+		Exec(ctx)
+
+	if err != nil {
+		return fmt.Errorf("failed to reset login attempts: %w", err)
+	}
+	return nil
+}
+
+// DeleteStale removes entries whose failures are outside window and that are not locked.
+func (r *LoginAttemptRepository) DeleteStale(window time.Duration, now time.Time) error {
+	ctx := context.Background()
+
+	_, err := r.db.NewDelete().
+		Model((*LoginAttempt)(nil)).
+		Where("last_failure_at < ?", now.Add(-window)).
+		Where("locked_until IS NULL OR locked_until < ?", now).
+		Exec(ctx)
+
+	if err != nil {
+		return fmt.Errorf("failed to delete stale login attempts: %w", err)
+	}
+	return nil
+}
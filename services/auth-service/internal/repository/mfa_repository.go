@@ -0,0 +1,207 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// TOTPFactor is a user's authenticator app secret. It only protects logins once
+// EnabledAt is set, i.e. after the user has confirmed enrollment with a valid code.
+type TOTPFactor struct {
+	bun.BaseModel `bun:"table:totp_factors,alias:tf"`
+
+	UserID    uint64    `bun:"user_id,pk"`
+	Secret    string    `bun:"secret,notnull"`
+	EnabledAt time.Time `bun:"enabled_at,nullzero"`
+	// LastUsedStep is the time step of the last accepted code; codes cannot be replayed.
+	LastUsedStep int64     `bun:"last_used_step,notnull,default:0"`
+	CreatedAt    time.Time `bun:"created_at,nullzero,notnull,default:current_timestamp"`
+}
+
+// RecoveryCode is a single-use code that replaces a TOTP code. Only its hash is stored.
+type RecoveryCode struct {
+	bun.BaseModel `bun:"table:recovery_codes,alias:rc"`
+
+	ID        uint64    `bun:"id,pk,autoincrement"`
+	UserID    uint64    `bun:"user_id,notnull"`
+	CodeHash  string    `bun:"code_hash,notnull,unique"`
+	UsedAt    time.Time `bun:"used_at,nullzero"`
+	CreatedAt time.Time `bun:"created_at,nullzero,notnull,default:current_timestamp"`
+}
+
+type MFARepository struct {
+	db *bun.DB
+}
+
+func NewMFARepository(db *bun.DB) *MFARepository {
+	return &MFARepository{db: db}
+}
+
+// GetTOTPFactor returns the user's factor, or nil if the user has none.
+func (r *MFARepository) GetTOTPFactor(userID uint64) (*TOTPFactor, error) {
+	ctx := context.Background()
+	factor := new(TOTPFactor)
+
+	err := r.db.NewSelect().
+		Model(factor).
+		Where("user_id = ?", userID).
+		Scan(ctx)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get totp factor: %w", err)
+	}
+
+	return factor, nil
+}
+
+// SavePendingTOTPFactor stores a new, not yet enabled secret, replacing an
+// unconfirmed one. Enabled factors are left untouched.
+func (r *MFARepository) SavePendingTOTPFactor(userID uint64, secret string) error {
+	ctx := context.Background()
+	factor := &TOTPFactor{
+		UserID:    userID,
+		Secret:    secret,
+		CreatedAt: time.Now(),
+	}
+
+	_, err := r.db.NewInsert().
+		Model(factor).
+		On("CONFLICT (user_id) DO UPDATE").
+		Set("secret = EXCLUDED.secret").
+		Set("created_at = EXCLUDED.created_at").
+		Set("last_used_step = 0").
+		Where("tf.enabled_at IS NULL").
+		Exec(ctx)
+
+	if err != nil {
+		return fmt.Errorf("failed to save totp factor: %w", err)
+	}
+	return nil
+}
+
+// EnableTOTPFactor enables the factor and replaces the recovery codes in one transaction.
+func (r *MFARepository) EnableTOTPFactor(userID uint64, step int64, recoveryCodeHashes []string) error {
+	ctx := context.Background()
+
+	return r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		res, err := tx.NewUpdate().
+			Model((*TOTPFactor)(nil)).
+			Set("enabled_at = ?", time.Now()).
+			Set("last_used_step = ?", step).
+			Where("user_id = ?", userID).
+			Where("enabled_at IS NULL").
+			Exec(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to enable totp factor: %w", err)
+		}
+		if rows, err := res.RowsAffected(); err != nil || rows == 0 {
+			return fmt.Errorf("no pending totp enrollment")
+		}
+
+		return replaceRecoveryCodes(ctx, tx, userID, recoveryCodeHashes)
+	})
+}
+
+// UseTOTPStep records an accepted code. It reports false if a code for this or a
+// later step was already used.
+func (r *MFARepository) UseTOTPStep(userID uint64, step int64) (bool, error) {
+	ctx := context.Background()
+
+	res, err := r.db.NewUpdate().
+		Model((*TOTPFactor)(nil)).
+		Set("last_used_step = ?", step).
+		Where("user_id = ?", userID).
+		Where("last_used_step < ?", step).
+		Exec(ctx)
+
+	if err != nil {
+		return false, fmt.Errorf("failed to update totp factor: %w", err)
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to update totp factor: %w", err)
+	}
+	return rows > 0, nil
+}
+
+// UseRecoveryCode marks an unused recovery code as used. It reports false if there was none.
+func (r *MFARepository) UseRecoveryCode(userID uint64, codeHash string) (bool, error) {
+	ctx := context.Background()
+
+	res, err := r.db.NewUpdate().
+		Model((*RecoveryCode)(nil)).
+		Set("used_at = ?", time.Now()).
+		Where("user_id = ?", userID).
+		Where("code_hash = ?", codeHash).
+		Where("used_at IS NULL").
+		Exec(ctx)
+
+	if err != nil {
+		return false, fmt.Errorf("failed to use recovery code: %w", err)
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to use recovery code: %w", err)
+	}
+	return rows > 0, nil
+}
+
+// ReplaceRecoveryCodes invalidates all recovery codes of the user and stores new ones.
+func (r *MFARepository) ReplaceRecoveryCodes(userID uint64, codeHashes []string) error {
+	ctx := context.Background()
+
+	return r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		return replaceRecoveryCodes(ctx, tx, userID, codeHashes)
+	})
+}
+
+// DeleteTOTP removes the factor and the recovery codes of the user.
+func (r *MFARepository) DeleteTOTP(userID uint64) error {
+	ctx := context.Background()
+
+	return r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if _, err := tx.NewDelete().
+			Model((*RecoveryCode)(nil)).
+			Where("user_id = ?", userID).
+			Exec(ctx); err != nil {
+			return fmt.Errorf("failed to delete recovery codes: %w", err)
+		}
+
+		if _, err := tx.NewDelete().
+			Model((*TOTPFactor)(nil)).
+			Where("user_id = ?", userID).
+			Exec(ctx); err != nil {
+			return fmt.Errorf("failed to delete totp factor: %w", err)
+		}
+		return nil
+	})
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx bun.Tx, userID uint64, codeHashes []string) error {
+	if _, err := tx.NewDelete().
+		Model((*RecoveryCode)(nil)).
+		Where("user_id = ?", userID).
+		Exec(ctx); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	codes := make([]*RecoveryCode, len(codeHashes))
+	for i, hash := range codeHashes {
+		codes[i] = &RecoveryCode{UserID: userID, CodeHash: hash, CreatedAt: time.Now()}
+	}
+
+	if _, err := tx.NewInsert().Model(&codes).Exec(ctx); err != nil {
+		return fmt.Errorf("failed to create recovery codes: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// PersonalAccessToken is a long-lived token for scripts and CI, limited to Scopes.
+// Only its SHA-256 hash is stored; Prefix is kept so users can tell tokens apart.
+type PersonalAccessToken struct {
+	bun.BaseModel `bun:"table:personal_access_tokens,alias:pat"`
+
+	ID         uint64    `bun:"id,pk,autoincrement"`
+	UserID     uint64    `bun:"user_id,notnull"`
+	Name       string    `bun:"name,notnull"`
+	Prefix     string    `bun:"prefix,notnull"`
+	TokenHash  string    `bun:"token_hash,notnull,unique"`
+	Scopes     []string  `bun:"scopes,array,notnull"`
+	ExpiresAt  time.Time `bun:"expires_at,nullzero"`
+	LastUsedAt time.Time `bun:"last_used_at,nullzero"`
+	RevokedAt  time.Time `bun:"revoked_at,nullzero"`
+	CreatedAt  time.Time `bun:"created_at,nullzero,notnull,default:current_timestamp"`
+}
+
+type PersonalAccessTokenRepository struct {
+	db *bun.DB
+}
+
+func NewPersonalAccessTokenRepository(db *bun.DB) *PersonalAccessTokenRepository {
+	return &PersonalAccessTokenRepository{db: db}
+}
+
+func (r *PersonalAccessTokenRepository) Create(token *PersonalAccessToken) error {
+	ctx := context.Background()
+	token.CreatedAt = time.Now()
+
+	_, err := r.db.NewInsert().Model(token).Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create personal access token: %w", err)
+	}
+
+	return nil
+}
+
+// GetActiveByHash returns a token that is neither revoked nor expired.
+func (r *PersonalAccessTokenRepository) GetActiveByHash(tokenHash string, now time.Time) (*PersonalAccessToken, error) {
+	ctx := context.Background()
+	token := new(PersonalAccessToken)
+
+	err := r.db.NewSelect().
+		Model(token).
+		Where("token_hash = ?", tokenHash).
+		Where("revoked_at IS NULL").
+		Where("expires_at IS NULL OR expires_at > ?", now).
+		Scan(ctx)
+
+	if err != nil {
+		return nil, fmt.Errorf("personal access token not found: %w", err)
+	}
+
+	return token, nil
+}
+
+// ListByUser returns the user's tokens that have not been revoked, newest first.
+func (r *PersonalAccessTokenRepository) ListByUser(userID uint64) ([]*PersonalAccessToken, error) {
+	ctx := context.Background()
+	var tokens []*PersonalAccessToken
+
+	err := r.db.NewSelect().
+		Model(&tokens).
+		Where("user_id = ?", userID).
+		Where("revoked_at IS NULL").
+		Order("created_at DESC").
+		Scan(ctx)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to list personal access tokens: %w", err)
+	}
+
+	return tokens, nil
+}
+
+// CountActiveByUser counts the user's tokens that are neither revoked nor expired.
+func (r *PersonalAccessTokenRepository) CountActiveByUser(userID uint64, now time.Time) (int, error) {
+	ctx := context.Background()
+
+	count, err := r.db.NewSelect().
+		Model((*PersonalAccessToken)(nil)).
+		Where("user_id = ?", userID).
+		Where("revoked_at IS NULL").
+		Where("expires_at IS NULL OR expires_at > ?", now).
+		Count(ctx)
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to count personal access tokens: %w", err)
+	}
+	return count, nil
+}
+
+// Revoke revokes a token owned by userID. It reports false if there was no such active token.
+func (r *PersonalAccessTokenRepository) Revoke(userID, id uint64) (bool, error) {
+	ctx := context.Background()
+
+	res, err := r.db.NewUpdate().
+		Model((*PersonalAccessToken)(nil)).
+		Set("revoked_at = ?", time.Now()).
+		Where("id = ?", id).
+		Where("user_id = ?", userID).
+		Where("revoked_at IS NULL").
+		Exec(ctx)
+
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke personal access token: %w", err)
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke personal access token: %w", err)
+	}
+	return rows > 0, nil
+}
+
+// TouchLastUsed records a use of the token. Writes are throttled to one per
+// minute so that busy tokens do not cause an update on every request.
+func (r *PersonalAccessTokenRepository) TouchLastUsed(id uint64, now time.Time) error {
+	ctx := context.Background()
+
+	_, err := r.db.NewUpdate().
+		Model((*PersonalAccessToken)(nil)).
+		Set("last_used_at = ?", now).
+		Where("id = ?", id).
+		Where("last_used_at IS NULL OR last_used_at < ?", now.Add(-time.Minute)).
+		Exec(ctx)
+
+	if err != nil {
+		return fmt.Errorf("failed to update personal access token: %w", err)
+	}
+	return nil
+}
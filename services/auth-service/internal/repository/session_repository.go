@@ -0,0 +1,185 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// Session is one login of a user on a device. Its ID is the refresh token family
+// ID and the sid claim of access tokens, so revoking the session ends both.
+type Session struct {
+	bun.BaseModel `bun:"table:sessions,alias:s"`
+
+	ID         string    `bun:"id,pk"`
+	UserID     uint64    `bun:"user_id,notnull"`
+	UserAgent  string    `bun:"user_agent"`
+	IP         string    `bun:"ip"`
+	CreatedAt  time.Time `bun:"created_at,nullzero,notnull,default:current_timestamp"`
+	LastSeenAt time.Time `bun:"last_seen_at,nullzero,notnull,default:current_timestamp"`
+	// ExpiresAt follows the expiry of the newest refresh token of the session.
+	ExpiresAt time.Time `bun:"expires_at,notnull"`
+	RevokedAt time.Time `bun:"revoked_at,nullzero"`
+}
+
+type SessionRepository struct {
+	db *bun.DB
+}
+
+func NewSessionRepository(db *bun.DB) *SessionRepository {
+	return &SessionRepository{db: db}
+}
+
+func (r *SessionRepository) Create(session *Session) error {
+	ctx := context.Background()
+
+	_, err := r.db.NewInsert().Model(session).Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+
+	return nil
+}
+
+func (r *SessionRepository) GetByID(id string) (*Session, error) {
+	ctx := context.Background()
+	session := new(Session)
+
+	err := r.db.NewSelect().
+		Model(session).
+		Where("id = ?", id).
+		Scan(ctx)
+
+	if err != nil {
+		return nil, fmt.Errorf("session not found: %w", err)
+	}
+
+	return session, nil
+}
+
+// Seen records activity on the session and returns it. An empty ip or userAgent
+// keeps the stored value; a zero expiresAt keeps the stored expiry.
+func (r *SessionRepository) Seen(id, ip, userAgent string, at, expiresAt time.Time) (*Session, error) {
+	ctx := context.Background()
+	session := new(Session)
+
+	q := r.db.NewUpdate().
+		Model(session).
+		Set("last_seen_at = ?", at).
+		Set("ip = COALESCE(NULLIF(?, ''), ip)", ip).
+		Set("user_agent = COALESCE(NULLIF(?, ''), user_agent)", userAgent).
+		Where("id = ?", id).
+		Returning("*")
+	if !expiresAt.IsZero() {
+		q = q.Set("expires_at = ?", expiresAt)
+	}
+
+	if err := q.Scan(ctx); err != nil {
+		return nil, fmt.Errorf("session not found: %w", err)
+	}
+
+	return session, nil
+}
+
+// ListActive returns the user's sessions that are neither revoked nor expired,
+// most recently used first.
+func (r *SessionRepository) ListActive(userID uint64, now time.Time) ([]*Session, error) {
+	ctx := context.Background()
+	var sessions []*Session
+
+	err := r.db.NewSelect().
+		Model(&sessions).
+		Where("user_id = ?", userID).
+		Where("revoked_at IS NULL").
+		Where("expires_at > ?", now).
+		Order("last_seen_at DESC").
+		Scan(ctx)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	return sessions, nil
+}
+
+// Revoke revokes a session. If userID is not zero, the session must belong to that
+// user. It reports false if there was no such active session.
+func (r *SessionRepository) Revoke(userID uint64, id string) (bool, error) {
+	ctx := context.Background()
+
+	q := r.db.NewUpdate().
+		Model((*Session)(nil)).
+		Set("revoked_at = ?", time.Now()).
+		Where("id = ?", id).
+		Where("revoked_at IS NULL")
+	if userID != 0 {
+		q = q.Where("user_id = ?", userID)
+	}
+
+	res, err := q.Exec(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke session: %w", err)
+	}
+	return rows > 0, nil
+}
+
+// RevokeAllForUser revokes every active session of a user and returns their IDs.
+func (r *SessionRepository) RevokeAllForUser(userID uint64) ([]string, error) {
+	ctx := context.Background()
+	var ids []string
+
+	err := r.db.NewUpdate().
+		Model((*Session)(nil)).
+		Set("revoked_at = ?", time.Now()).
+		Where("user_id = ?", userID).
+		Where("revoked_at IS NULL").
+		Returning("id").
+		Scan(ctx, &ids)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	return ids, nil
+}
+
+// BackfillFromRefreshTokens creates sessions for refresh token families issued
+// before sessions were recorded, so existing logins keep working.
+func (r *SessionRepository) BackfillFromRefreshTokens() error {
+	ctx := context.Background()
+
+	_, err := r.db.NewRaw(`
+		INSERT INTO sessions (id, user_id, created_at, last_seen_at, expires_at)
+		SELECT family_id, user_id, MIN(created_at), MAX(created_at), MAX(expires_at)
+		FROM refresh_tokens
+		WHERE revoked_at IS NULL
+		GROUP BY family_id, user_id
+		ON CONFLICT (id) DO NOTHING`).
+		Exec(ctx)
+
+	if err != nil {
+		return fmt.Errorf("failed to backfill sessions: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpired removes sessions that expired or were revoked before the given time.
+func (r *SessionRepository) DeleteExpired(before time.Time) error {
+	ctx := context.Background()
+
+	_, err := r.db.NewDelete().
+		Model((*Session)(nil)).
+		Where("expires_at < ? OR revoked_at < ?", before, before).
+		Exec(ctx)
+
+	if err != nil {
+		return fmt.Errorf("failed to delete expired sessions: %w", err)
+	}
+	return nil
+}
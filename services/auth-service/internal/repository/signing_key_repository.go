@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// SigningKey is an asymmetric JWT signing key. The newest key without RetiredAt
+// signs new tokens; retired keys only verify tokens until they expire.
+type SigningKey struct {
+	bun.BaseModel `bun:"table:signing_keys,alias:sk"`
+
+	Kid        string    `bun:"kid,pk"`
+	Algorithm  string    `bun:"algorithm,notnull"`
+	PrivateKey string    `bun:"private_key,notnull"` // PKCS#8 PEM
+	CreatedAt  time.Time `bun:"created_at,nullzero,notnull,default:current_timestamp"`
+	RetiredAt  time.Time `bun:"retired_at,nullzero"`
+}
+
+type SigningKeyRepository struct {
+	db *bun.DB
+}
+
+func NewSigningKeyRepository(db *bun.DB) *SigningKeyRepository {
+	return &SigningKeyRepository{db: db}
+}
+
+// ListUsable returns active keys and keys retired after retiredAfter, newest first.
+func (r *SigningKeyRepository) ListUsable(retiredAfter time.Time) ([]*SigningKey, error) {
+	ctx := context.Background()
+	var keys []*SigningKey
+
+	err := r.db.NewSelect().
+		Model(&keys).
+		Where("retired_at IS NULL OR retired_at > ?", retiredAfter).
+		Order("created_at DESC").
+		Scan(ctx)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to list signing keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+// Rotate stores a new key and retires every other active key in one transaction.
+func (r *SigningKeyRepository) Rotate(key *SigningKey) error {
+	ctx := context.Background()
+
+	return r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if _, err := tx.NewUpdate().
+			Model((*SigningKey)(nil)).
+			Set("retired_at = ?", time.Now()).
+			Where("retired_at IS NULL").
+			Exec(ctx); err != nil {
+			return fmt.Errorf("failed to retire signing keys: %w", err)
+		}
+
+		if _, err := tx.NewInsert().Model(key).Exec(ctx); err != nil {
+			return fmt.Errorf("failed to create signing key: %w", err)
+		}
+		return nil
+	})
+}
+
+// DeleteRetiredBefore removes keys that can no longer verify any unexpired token.
+func (r *SigningKeyRepository) DeleteRetiredBefore(t time.Time) error {
+	ctx := context.Background()
+
+	_, err := r.db.NewDelete().
+		Model((*SigningKey)(nil)).
+		Where("retired_at IS NOT NULL AND retired_at < ?", t).
+		Exec(ctx)
+
+	if err != nil {
+		return fmt.Errorf("failed to delete retired signing keys: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,229 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// ErrRefreshTokenUsed is returned when a refresh token has already been rotated or revoked.
+var ErrRefreshTokenUsed = errors.New("refresh token already used")
+
+// RefreshToken is a long-lived opaque token. Only its SHA-256 hash is stored.
+// Tokens issued by rotating one another share a FamilyID, so reuse of a rotated
+// token can revoke the whole chain. The FamilyID is also the ID of the Session.
+type RefreshToken struct {
+	bun.BaseModel `bun:"table:refresh_tokens,alias:rt"`
+
+	ID           uint64    `bun:"id,pk,autoincrement"`
+	UserID       uint64    `bun:"user_id,notnull"`
+	FamilyID     string    `bun:"family_id,notnull"`
+	TokenHash    string    `bun:"token_hash,notnull,unique"`
+	ExpiresAt    time.Time `bun:"expires_at,notnull"`
+	RevokedAt    time.Time `bun:"revoked_at,nullzero"`
+	ReplacedByID uint64    `bun:"replaced_by_id,nullzero"`
+	CreatedAt    time.Time `bun:"created_at,nullzero,notnull,default:current_timestamp"`
+}
+
+// PasswordResetToken is a single-use token sent by email. Only its hash is stored.
+type PasswordResetToken struct {
+	bun.BaseModel `bun:"table:password_reset_tokens,alias:prt"`
+
+	ID        uint64    `bun:"id,pk,autoincrement"`
+	UserID    uint64    `bun:"user_id,notnull"`
+	TokenHash string    `bun:"token_hash,notnull,unique"`
+	ExpiresAt time.Time `bun:"expires_at,notnull"`
+	UsedAt    time.Time `bun:"used_at,nullzero"`
+	CreatedAt time.Time `bun:"created_at,nullzero,notnull,default:current_timestamp"`
+}
+
+type TokenRepository struct {
+	db *bun.DB
+}
+
+func NewTokenRepository(db *bun.DB) *TokenRepository {
+	return &TokenRepository{db: db}
+}
+
+func (r *TokenRepository) CreateRefreshToken(userID uint64, familyID, tokenHash string, expiresAt time.Time) (*RefreshToken, error) {
+	ctx := context.Background()
+	token := &RefreshToken{
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: tokenHash,
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now(),
+	}
+
+	_, err := r.db.NewInsert().Model(token).Exec(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create refresh token: %w", err)
+	}
+
+	return token, nil
+}
+
+func (r *TokenRepository) GetRefreshTokenByHash(tokenHash string) (*RefreshToken, error) {
+	ctx := context.Background()
+	token := new(RefreshToken)
+
+	err := r.db.NewSelect().
+		Model(token).
+		Where("token_hash = ?", tokenHash).
+		Scan(ctx)
+
+	if err != nil {
+		return nil, fmt.Errorf("refresh token not found: %w", err)
+	}
+
+	return token, nil
+}
+
+// RotateRefreshToken marks old as used and stores its successor in the same family.
+// It returns ErrRefreshTokenUsed if old was rotated or revoked concurrently.
+func (r *TokenRepository) RotateRefreshToken(old *RefreshToken, tokenHash string, expiresAt time.Time) (*RefreshToken, error) {
+	ctx := context.Background()
+	next := &RefreshToken{
+		UserID:    old.UserID,
+		FamilyID:  old.FamilyID,
+		TokenHash: tokenHash,
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now(),
+	}
+
+	err := r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if _, err := tx.NewInsert().Model(next).Exec(ctx); err != nil {
+			return fmt.Errorf("failed to create refresh token: %w", err)
+		}
+
+		result, err := tx.NewUpdate().
+			Model((*RefreshToken)(nil)).
+			Set("revoked_at = ?", time.Now()).
+			Set("replaced_by_id = ?", next.ID).
+			Where("id = ? AND revoked_at IS NULL", old.ID).
+			Exec(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to revoke refresh token: %w", err)
+		}
+
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get affected rows: %w", err)
+		}
+		if rows == 0 {
+			return ErrRefreshTokenUsed
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return next, nil
+}
+
+// RevokeFamily revokes every still-active refresh token in a family.
+func (r *TokenRepository) RevokeFamily(familyID string) error {
+	ctx := context.Background()
+
+	_, err := r.db.NewUpdate().
+		Model((*RefreshToken)(nil)).
+		Set("revoked_at = ?", time.Now()).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Exec(ctx)
+
+	if err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+	return nil
+}
+
+// RevokeAllForUser revokes every still-active refresh token of a user.
+func (r *TokenRepository) RevokeAllForUser(userID uint64) error {
+	ctx := context.Background()
+
+	_, err := r.db.NewUpdate().
+		Model((*RefreshToken)(nil)).
+		Set("revoked_at = ?", time.Now()).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Exec(ctx)
+
+	if err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+	return nil
+}
+
+// CreatePasswordResetToken stores a new reset token and invalidates any earlier
+// unused ones, so only the latest emailed link works.
+func (r *TokenRepository) CreatePasswordResetToken(userID uint64, tokenHash string, expiresAt time.Time) (*PasswordResetToken, error) {
+	ctx := context.Background()
+	token := &PasswordResetToken{
+		UserID:    userID,
+		TokenHash: tokenHash,
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now(),
+	}
+
+	err := r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if _, err := tx.NewUpdate().
+			Model((*PasswordResetToken)(nil)).
+			Set("used_at = ?", time.Now()).
+			Where("user_id = ? AND used_at IS NULL", userID).
+			Exec(ctx); err != nil {
+			return err
+		}
+
+		_, err := tx.NewInsert().Model(token).Exec(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create password reset token: %w", err)
+	}
+
+	return token, nil
+}
+
+// UsePasswordResetToken atomically consumes an unexpired, unused reset token and
+// returns the ID of the user it belongs to.
+func (r *TokenRepository) UsePasswordResetToken(tokenHash string, now time.Time) (uint64, error) {
+	ctx := context.Background()
+	token := new(PasswordResetToken)
+
+	err := r.db.NewUpdate().
+		Model(token).
+		Set("used_at = ?", now).
+		Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", tokenHash, now).
+		Returning("user_id").
+		Scan(ctx)
+
+	if err != nil {
+		return 0, fmt.Errorf("password reset token not found: %w", err)
+	}
+
+	return token.UserID, nil
+}
+
+// DeleteExpired removes tokens that expired before the given time.
+func (r *TokenRepository) DeleteExpired(now time.Time) error {
+	ctx := context.Background()
+
+	if _, err := r.db.NewDelete().
+		Model((*RefreshToken)(nil)).
+		Where("expires_at < ?", now).
+		Exec(ctx); err != nil {
+		return fmt.Errorf("failed to delete expired refresh tokens: %w", err)
+	}
+
+	if _, err := r.db.NewDelete().
+		Model((*PasswordResetToken)(nil)).
+		Where("expires_at < ?", now).
+		Exec(ctx); err != nil {
+		return fmt.Errorf("failed to delete expired password reset tokens: %w", err)
+	}
+
+	return nil
+}
@@ -6,6 +6,14 @@ import (
 	"time"
 
 	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/pgdialect"
+)
+
+// Roles a user can hold. Every user has RoleUser; admins implicitly moderate.
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
 )
 
 type User struct {
@@ -17,6 +25,44 @@ type User struct {
 	Password  string    `bun:"password,notnull"`
 	CreatedAt time.Time `bun:"created_at,nullzero,notnull,default:current_timestamp"`
 	UpdatedAt time.Time `bun:"updated_at,nullzero,notnull,default:current_timestamp"`
+
+	EmailVerifiedAt time.Time `bun:"email_verified_at,nullzero"`
+	Roles           []string  `bun:"roles,array,notnull,default:'{user}'"`
+
+	Bio       string `bun:"bio,notnull,default:''"`
+	AvatarURL string `bun:"avatar_url,notnull,default:''"`
+
+	// InvitedBy and InviteCodeID record the invite a user registered with, if any.
+	InvitedBy    uint64 `bun:"invited_by,nullzero"`
+	InviteCodeID uint64 `bun:"invite_code_id,nullzero"`
+
+	// DeletedAt is set when the user deletes their account. Until PurgeAt the
+	// deletion can be undone; after that the row is anonymized and PurgedAt is set.
+	DeletedAt time.Time `bun:"deleted_at,nullzero"`
+	PurgeAt   time.Time `bun:"purge_at,nullzero"`
+	PurgedAt  time.Time `bun:"purged_at,nullzero"`
+	// DeleteArticles chooses whether the user's articles are deleted or anonymized on purge.
+	DeleteArticles bool `bun:"delete_articles,notnull,default:false"`
+}
+
+func (u *User) HasRole(role string) bool {
+	for _, r := range u.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// IsDeleted reports whether the account was deleted, even if it can still be restored.
+func (u *User) IsDeleted() bool {
+	return !u.DeletedAt.IsZero()
+}
+
+// HasPassword reports whether the user can sign in with a password. Users created
+// through an OpenID provider have none until they set one via password reset.
+func (u *User) HasPassword() bool {
+	return u.Password != ""
 }
 
 type UserRepository struct {
@@ -27,12 +73,13 @@ func NewUserRepository(db *bun.DB) *UserRepository {
 	return &UserRepository{db: db}
 }
 
-func (r *UserRepository) Create(email, username, passwordHash string) (*User, error) {
+func (r *UserRepository) Create(email, username, passwordHash string, roles []string) (*User, error) {
 	ctx := context.Background()
 	user := &User{
 		Email:     email,
 		Username:  username,
 		Password:  passwordHash,
+		Roles:     roles,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
@@ -77,6 +124,123 @@ func (r *UserRepository) GetByID(id uint64) (*User, error) {
 	return user, nil
 }
 
+// GetByIDs returns the users with the given IDs in one query. Unknown IDs are skipped.
+func (r *UserRepository) GetByIDs(ids []uint64) ([]*User, error) {
+	ctx := context.Background()
+	var users []*User
+
+	if len(ids) == 0 {
+		return users, nil
+	}
+
+	err := r.db.NewSelect().
+		Model(&users).
+		Where("id IN (?)", bun.In(ids)).
+		Scan(ctx)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get users: %w", err)
+	}
+
+	return users, nil
+}
+
+// GetByUsername looks a user up by handle, ignoring case.
+func (r *UserRepository) GetByUsername(username string) (*User, error) {
+	ctx := context.Background()
+	user := new(User)
+
+	err := r.db.NewSelect().
+		Model(user).
+		Where("lower(username) = lower(?)", username).
+		Scan(ctx)
+
+	if err != nil {
+		return nil, fmt.Errorf("user not found: %w", err)
+	}
+
+	return user, nil
+}
+
+// UsernameExists reports whether the handle is taken, ignoring case.
+func (r *UserRepository) UsernameExists(username string) (bool, error) {
+	ctx := context.Background()
+	exists, err := r.db.NewSelect().
+		Model((*User)(nil)).
+		Where("lower(username) = lower(?)", username).
+		Exists(ctx)
+
+	return exists, err
+}
+
+// freeUsername returns name, or name with the first numeric suffix that no user
+// but userID has, compared case-insensitively like the username index.
+func freeUsername(ctx context.Context, db bun.IDB, name string, userID uint64) (string, error) {
+	candidate := name
+	for n := 2; ; n++ {
+		exists, err := db.NewSelect().
+			Model((*User)(nil)).
+			Where("lower(username) = lower(?)", candidate).
+			Where("id != ?", userID).
+			Exists(ctx)
+		if err != nil {
+			return "", fmt.Errorf("failed to check username: %w", err)
+		}
+		if !exists {
+			return candidate, nil
+		}
+		candidate = fmt.Sprintf("%s-%d", name, n)
+	}
+}
+
+// EnsureUniqueUsernames renames users whose handle clashes with an older
+// account (appending their ID, and a counter if that is taken too) and then
+// enforces case-insensitive uniqueness with an index. It is safe to run on
+// every start.
+func (r *UserRepository) EnsureUniqueUsernames() error {
+	ctx := context.Background()
+
+	err := r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		var duplicates []*User
+		err := tx.NewRaw(`
+			SELECT id, username FROM (
+				SELECT id, username, row_number() OVER (PARTITION BY lower(username) ORDER BY id) AS n
+				FROM users
+			) ranked
+			WHERE n > 1
+			ORDER BY id
+		`).Scan(ctx, &duplicates)
+		if err != nil {
+			return err
+		}
+
+		for _, user := range duplicates {
+			username, err := freeUsername(ctx, tx, fmt.Sprintf("%s-%d", user.Username, user.ID), user.ID)
+			if err != nil {
+				return err
+			}
+			if _, err := tx.NewUpdate().
+				Model((*User)(nil)).
+				Set("username = ?", username).
+				Set("updated_at = ?", time.Now()).
+				Where("id = ?", user.ID).
+				Exec(ctx); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to rename duplicate usernames: %w", err)
+	}
+
+	_, err = r.db.ExecContext(ctx, "CREATE UNIQUE INDEX IF NOT EXISTS users_username_lower_key ON users (lower(username))")
+	if err != nil {
+		return fmt.Errorf("failed to create username index: %w", err)
+	}
+	return nil
+}
+
 func (r *UserRepository) EmailExists(email string) (bool, error) {
 	ctx := context.Background()
 	exists, err := r.db.NewSelect().
@@ -86,3 +250,261 @@ func (r *UserRepository) EmailExists(email string) (bool, error) {
 
 	return exists, err
 }
+
+// UpdateProfile changes the public profile and email. A changed email loses its verification.
+func (r *UserRepository) UpdateProfile(id uint64, username, email, bio, avatarURL string) (*User, error) {
+	ctx := context.Background()
+	user := &User{ID: id}
+
+	_, err := r.db.NewUpdate().
+		Model(user).
+		Set("username = ?", username).
+		Set("bio = ?", bio).
+		Set("avatar_url = ?", avatarURL).
+		Set("email_verified_at = CASE WHEN email = ? THEN email_verified_at ELSE NULL END", email).
+		Set("email = ?", email).
+		Set("updated_at = ?", time.Now()).
+		WherePK().
+		Returning("*").
+		Exec(ctx)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+
+	return user, nil
+}
+
+func (r *UserRepository) UpdatePassword(id uint64, passwordHash string) error {
+	ctx := context.Background()
+
+	_, err := r.db.NewUpdate().
+		Model((*User)(nil)).
+		Set("password = ?", passwordHash).
+		Set("updated_at = ?", time.Now()).
+		Where("id = ?", id).
+		Exec(ctx)
+
+	if err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+	return nil
+}
+
+// ReplacePasswordHash stores a new hash of the same password. It does nothing
+// if the password was changed since oldHash was read.
+func (r *UserRepository) ReplacePasswordHash(id uint64, oldHash, newHash string) error {
+	ctx := context.Background()
+
+	_, err := r.db.NewUpdate().
+		Model((*User)(nil)).
+		Set("password = ?", newHash).
+		Where("id = ? AND password = ?", id, oldHash).
+		Exec(ctx)
+
+	if err != nil {
+		return fmt.Errorf("failed to update password hash: %w", err)
+	}
+	return nil
+}
+
+func (r *UserRepository) MarkEmailVerified(id uint64) (*User, error) {
+	ctx := context.Background()
+	user := &User{ID: id}
+
+	_, err := r.db.NewUpdate().
+		Model(user).
+		Set("email_verified_at = ?", time.Now()).
+		Set("updated_at = ?", time.Now()).
+		WherePK().
+		Returning("*").
+		Exec(ctx)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify email: %w", err)
+	}
+
+	return user, nil
+}
+
+func (r *UserRepository) List(limit, offset int) ([]*User, int, error) {
+	ctx := context.Background()
+	var users []*User
+
+	total, err := r.db.NewSelect().
+		Model(&users).
+		Order("id ASC").
+		Limit(limit).
+		Offset(offset).
+		ScanAndCount(ctx)
+
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list users: %w", err)
+	}
+
+	return users, total, nil
+}
+
+func (r *UserRepository) SetRoles(id uint64, roles []string) (*User, error) {
+	ctx := context.Background()
+	user := &User{ID: id}
+
+	_, err := r.db.NewUpdate().
+		Model(user).
+		Set("roles = ?", pgdialect.Array(roles)).
+		Set("updated_at = ?", time.Now()).
+		WherePK().
+		Returning("*").
+		Exec(ctx)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to update roles: %w", err)
+	}
+
+	return user, nil
+}
+
+// AddRoleByEmail grants a role to the user with the given email if they exist.
+func (r *UserRepository) AddRoleByEmail(email, role string) error {
+	ctx := context.Background()
+
+	_, err := r.db.NewUpdate().
+		Model((*User)(nil)).
+		Set("roles = array_append(roles, ?)", role).
+		Where("email = ? AND NOT (? = ANY(roles))", email, role).
+		Exec(ctx)
+
+	if err != nil {
+		return fmt.Errorf("failed to add role: %w", err)
+	}
+	return nil
+}
+
+// MarkDeleted soft-deletes the user. The account is purged at purgeAt unless restored.
+func (r *UserRepository) MarkDeleted(id uint64, purgeAt time.Time, deleteArticles bool) (*User, error) {
+	ctx := context.Background()
+	user := &User{ID: id}
+
+	err := r.db.NewUpdate().
+		Model(user).
+		Set("deleted_at = ?", time.Now()).
+		Set("purge_at = ?", purgeAt).
+		Set("delete_articles = ?", deleteArticles).
+		Set("updated_at = ?", time.Now()).
+		Where("id = ? AND deleted_at IS NULL", id).
+		Returning("*").
+		Scan(ctx)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	return user, nil
+}
+
+// Restore undoes a deletion that has not been purged yet. deletedAt must be the
+// time of the current deletion, so that only its restore link works.
+func (r *UserRepository) Restore(id uint64, deletedAt time.Time) (*User, error) {
+	ctx := context.Background()
+	user := &User{ID: id}
+
+	err := r.db.NewUpdate().
+		Model(user).
+		Set("deleted_at = NULL").
+		Set("purge_at = NULL").
+		Set("delete_articles = FALSE").
+		Set("updated_at = ?", time.Now()).
+		Where("id = ? AND deleted_at = ? AND purged_at IS NULL", id, deletedAt).
+		Returning("*").
+		Scan(ctx)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore user: %w", err)
+	}
+
+	return user, nil
+}
+
+// ListDueForPurge returns deleted users whose grace period ended before now.
+func (r *UserRepository) ListDueForPurge(now time.Time, limit int) ([]*User, error) {
+	ctx := context.Background()
+	var users []*User
+
+	err := r.db.NewSelect().
+		Model(&users).
+		Where("deleted_at IS NOT NULL").
+		Where("purged_at IS NULL").
+		Where("purge_at <= ?", now).
+		Order("purge_at ASC").
+		Limit(limit).
+		Scan(ctx)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deleted users: %w", err)
+	}
+
+	return users, nil
+}
+
+// Purge anonymizes a deleted user and removes everything that could identify them
+// or sign them in. The row itself is kept so its ID is never reused.
+func (r *UserRepository) Purge(id uint64) error {
+	ctx := context.Background()
+
+	err := r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		// Someone may have registered the placeholder name
+		username, err := freeUsername(ctx, tx, fmt.Sprintf("deleted-%d", id), id)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.NewUpdate().
+			Model((*User)(nil)).
+			Set("email = ?", fmt.Sprintf("deleted-%d@invalid", id)).
+			Set("username = ?", username).
+			Set("password = ''").
+			Set("bio = ''").
+			Set("avatar_url = ''").
+			Set("email_verified_at = NULL").
+			Set("roles = '{}'").
+			Set("purged_at = ?", time.Now()).
+			Set("updated_at = ?", time.Now()).
+			Where("id = ? AND deleted_at IS NOT NULL", id).
+			Exec(ctx); err != nil {
+			return err
+		}
+
+		for _, model := range []interface{}{
+			(*RefreshToken)(nil),
+			(*PasswordResetToken)(nil),
+			(*Session)(nil),
+			(*PersonalAccessToken)(nil),
+			(*TOTPFactor)(nil),
+			(*RecoveryCode)(nil),
+			(*UserIdentity)(nil),
+		} {
+			if _, err := tx.NewDelete().Model(model).Where("user_id = ?", id).Exec(ctx); err != nil {
+				return err
+			}
+		}
+
+		// Invited users still point at the codes, so they are kept as history
+		if _, err := tx.NewUpdate().
+			Model((*InviteCode)(nil)).
+			Set("revoked_at = ?", time.Now()).
+			Where("created_by = ? AND revoked_at IS NULL", id).
+			Exec(ctx); err != nil {
+			return err
+		}
+
+		_, err = tx.NewDelete().
+			Model((*UserBlock)(nil)).
+			Where("blocker_id = ? OR blocked_id = ?", id, id).
+			Exec(ctx)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to purge user: %w", err)
+	}
+	return nil
+}
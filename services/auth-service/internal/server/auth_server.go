@@ -2,10 +2,16 @@ package server
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
+	"math"
+	"time"
 
 	"google.golang.org/protobuf/types/known/timestamppb"
 
+	"github.com/XRS0/blog/services/auth-service/internal/repository"
 	"github.com/XRS0/blog/services/auth-service/internal/service"
 	pb "github.com/XRS0/blog/services/auth-service/proto"
 )
@@ -23,46 +29,137 @@ func NewAuthServer(authService *service.AuthService, logger *slog.Logger) *AuthS
 	}
 }
 
+func userToProto(user *repository.User) *pb.User {
+	return &pb.User{
+		Id:        user.ID,
+		Email:     user.Email,
+		Username:  user.Username,
+		CreatedAt: timestamppb.New(user.CreatedAt),
+		UpdatedAt: timestamppb.New(user.UpdatedAt),
+
+		EmailVerified: !user.EmailVerifiedAt.IsZero(),
+		Roles:         user.Roles,
+		Bio:           user.Bio,
+		AvatarUrl:     user.AvatarURL,
+		InvitedBy:     user.InvitedBy,
+	}
+}
+
+func inviteToProto(invite *repository.InviteCode) *pb.InviteCode {
+	return &pb.InviteCode{
+		Id:        invite.ID,
+		Prefix:    invite.Prefix,
+		MaxUses:   int32(invite.MaxUses),
+		Uses:      int32(invite.Uses),
+		ExpiresAt: optionalTimestamp(invite.ExpiresAt),
+		CreatedAt: timestamppb.New(invite.CreatedAt),
+	}
+}
+
+func authEventToProto(event *repository.AuthEvent) (*pb.AuthEvent, error) {
+	details, err := json.Marshal(event.Details)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode event details: %w", err)
+	}
+	return &pb.AuthEvent{
+		Id:        event.ID,
+		Type:      event.Type,
+		UserId:    event.UserID,
+		Ip:        event.IP,
+		UserAgent: event.UserAgent,
+		Details:   string(details),
+		CreatedAt: timestamppb.New(event.CreatedAt),
+	}, nil
+}
+
+func personalAccessTokenToProto(token *repository.PersonalAccessToken) *pb.PersonalAccessToken {
+	return &pb.PersonalAccessToken{
+		Id:         token.ID,
+		Name:       token.Name,
+		Prefix:     token.Prefix,
+		Scopes:     token.Scopes,
+		ExpiresAt:  optionalTimestamp(token.ExpiresAt),
+		LastUsedAt: optionalTimestamp(token.LastUsedAt),
+		CreatedAt:  timestamppb.New(token.CreatedAt),
+	}
+}
+
+// optionalTimestamp leaves the field unset for zero times.
+func optionalTimestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
 func (s *AuthServer) Register(ctx context.Context, req *pb.RegisterRequest) (*pb.RegisterResponse, error) {
-	user, token, err := s.authService.Register(req.Email, req.Username, req.Password)
+	user, tokens, err := s.authService.Register(ctx, req.Email, req.Username, req.Password, req.InviteCode, clientInfo(ctx))
 	if err != nil {
 		s.logger.Error("registration failed", "email", req.Email, "error", err)
 		return &pb.RegisterResponse{Error: err.Error()}, nil
 	}
 
 	return &pb.RegisterResponse{
-		User: &pb.User{
-			Id:        user.ID,
-			Email:     user.Email,
-			Username:  user.Username,
-			CreatedAt: timestamppb.New(user.CreatedAt),
-			UpdatedAt: timestamppb.New(user.UpdatedAt),
-		},
-		Token: token,
+		User:         userToProto(user),
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
 	}, nil
 }
 
 func (s *AuthServer) Login(ctx context.Context, req *pb.LoginRequest) (*pb.LoginResponse, error) {
-	user, token, err := s.authService.Login(req.Email, req.Password)
+	client := clientInfo(ctx)
+	result, err := s.authService.Login(req.Email, req.Password, client)
 	if err != nil {
-		s.logger.Error("login failed", "email", req.Email, "error", err)
-		return &pb.LoginResponse{Error: err.Error()}, nil
+		s.logger.Error("login failed", "email", req.Email, "client_ip", client.IP, "error", err)
+		return &pb.LoginResponse{
+			Error:             err.Error(),
+			RetryAfterSeconds: retryAfterSeconds(err),
+		}, nil
+	}
+
+	if result.MFAToken != "" {
+		return &pb.LoginResponse{
+			MfaRequired: true,
+			MfaToken:    result.MFAToken,
+		}, nil
 	}
 
 	return &pb.LoginResponse{
-		User: &pb.User{
-			Id:        user.ID,
-			Email:     user.Email,
-			Username:  user.Username,
-			CreatedAt: timestamppb.New(user.CreatedAt),
-			UpdatedAt: timestamppb.New(user.UpdatedAt),
-		},
-		Token: token,
+		User:         userToProto(result.User),
+		Token:        result.Tokens.AccessToken,
+		RefreshToken: result.Tokens.RefreshToken,
 	}, nil
 }
 
+func (s *AuthServer) VerifyMFA(ctx context.Context, req *pb.VerifyMFARequest) (*pb.VerifyMFAResponse, error) {
+	client := clientInfo(ctx)
+	user, tokens, err := s.authService.VerifyMFA(req.MfaToken, req.Code, client)
+	if err != nil {
+		s.logger.Error("mfa verification failed", "client_ip", client.IP, "error", err)
+		return &pb.VerifyMFAResponse{
+			Error:             err.Error(),
+			RetryAfterSeconds: retryAfterSeconds(err),
+		}, nil
+	}
+
+	return &pb.VerifyMFAResponse{
+		User:         userToProto(user),
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+	}, nil
+}
+
+// retryAfterSeconds returns the remaining lockout for a LoginLockedError, or 0.
+func retryAfterSeconds(err error) int32 {
+	var locked *service.LoginLockedError
+	if errors.As(err, &locked) {
+		return int32(math.Ceil(locked.RetryAfter.Seconds()))
+	}
+	return 0
+}
+
 func (s *AuthServer) ValidateToken(ctx context.Context, req *pb.ValidateTokenRequest) (*pb.ValidateTokenResponse, error) {
-	userID, err := s.authService.ValidateToken(req.Token)
+	identity, err := s.authService.ValidateToken(req.Token)
 	if err != nil {
 		return &pb.ValidateTokenResponse{
 			Valid: false,
@@ -71,11 +168,105 @@ func (s *AuthServer) ValidateToken(ctx context.Context, req *pb.ValidateTokenReq
 	}
 
 	return &pb.ValidateTokenResponse{
-		Valid:  true,
-		UserId: userID,
+		Valid:         true,
+		UserId:        identity.UserID,
+		EmailVerified: identity.EmailVerified,
+		Roles:         identity.Roles,
+
+		Scopes:              identity.Scopes,
+		PersonalAccessToken: identity.PersonalAccessToken,
+		SessionId:           identity.SessionID,
+	}, nil
+}
+
+func (s *AuthServer) RefreshToken(ctx context.Context, req *pb.RefreshTokenRequest) (*pb.RefreshTokenResponse, error) {
+	tokens, err := s.authService.RefreshToken(req.RefreshToken, clientInfo(ctx))
+	if err != nil {
+		s.logger.Warn("token refresh failed", "error", err)
+		return &pb.RefreshTokenResponse{Error: err.Error()}, nil
+	}
+
+	return &pb.RefreshTokenResponse{
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
 	}, nil
 }
 
+func (s *AuthServer) Logout(ctx context.Context, req *pb.LogoutRequest) (*pb.LogoutResponse, error) {
+	if err := s.authService.Logout(req.Token, req.RefreshToken); err != nil {
+		s.logger.Error("logout failed", "error", err)
+		return &pb.LogoutResponse{Success: false, Error: err.Error()}, nil
+	}
+
+	return &pb.LogoutResponse{Success: true}, nil
+}
+
+func (s *AuthServer) UpdateProfile(ctx context.Context, req *pb.UpdateProfileRequest) (*pb.UpdateProfileResponse, error) {
+	user, err := s.authService.UpdateProfile(ctx, req.UserId, service.ProfileUpdate{
+		Username:        req.Username,
+		Email:           req.Email,
+		CurrentPassword: req.CurrentPassword,
+		Bio:             req.Bio,
+		AvatarURL:       req.AvatarUrl,
+	})
+	if err != nil {
+		s.logger.Error("update profile failed", "user_id", req.UserId, "error", err)
+		return &pb.UpdateProfileResponse{Error: err.Error()}, nil
+	}
+
+	return &pb.UpdateProfileResponse{User: userToProto(user)}, nil
+}
+
+func (s *AuthServer) ChangePassword(ctx context.Context, req *pb.ChangePasswordRequest) (*pb.ChangePasswordResponse, error) {
+	tokens, err := s.authService.ChangePassword(req.UserId, req.CurrentPassword, req.NewPassword, clientInfo(ctx))
+	if err != nil {
+		s.logger.Error("change password failed", "user_id", req.UserId, "error", err)
+		return &pb.ChangePasswordResponse{Error: err.Error()}, nil
+	}
+
+	return &pb.ChangePasswordResponse{
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+	}, nil
+}
+
+func (s *AuthServer) RequestPasswordReset(ctx context.Context, req *pb.RequestPasswordResetRequest) (*pb.RequestPasswordResetResponse, error) {
+	if err := s.authService.RequestPasswordReset(ctx, req.Email); err != nil {
+		s.logger.Error("request password reset failed", "error", err)
+		return &pb.RequestPasswordResetResponse{Success: false, Error: err.Error()}, nil
+	}
+
+	return &pb.RequestPasswordResetResponse{Success: true}, nil
+}
+
+func (s *AuthServer) ResetPassword(ctx context.Context, req *pb.ResetPasswordRequest) (*pb.ResetPasswordResponse, error) {
+	if err := s.authService.ResetPassword(req.Token, req.NewPassword, clientInfo(ctx)); err != nil {
+		s.logger.Warn("reset password failed", "error", err)
+		return &pb.ResetPasswordResponse{Success: false, Error: err.Error()}, nil
+	}
+
+	return &pb.ResetPasswordResponse{Success: true}, nil
+}
+
+func (s *AuthServer) VerifyEmail(ctx context.Context, req *pb.VerifyEmailRequest) (*pb.VerifyEmailResponse, error) {
+	user, err := s.authService.VerifyEmail(req.Token)
+	if err != nil {
+		s.logger.Warn("email verification failed", "error", err)
+		return &pb.VerifyEmailResponse{Error: err.Error()}, nil
+	}
+
+	return &pb.VerifyEmailResponse{User: userToProto(user)}, nil
+}
+
+func (s *AuthServer) ResendVerificationEmail(ctx context.Context, req *pb.ResendVerificationEmailRequest) (*pb.ResendVerificationEmailResponse, error) {
+	if err := s.authService.ResendVerificationEmail(ctx, req.UserId); err != nil {
+		s.logger.Error("resend verification email failed", "user_id", req.UserId, "error", err)
+		return &pb.ResendVerificationEmailResponse{Success: false, Error: err.Error()}, nil
+	}
+
+	return &pb.ResendVerificationEmailResponse{Success: true}, nil
+}
+
 func (s *AuthServer) GetUserByID(ctx context.Context, req *pb.GetUserByIDRequest) (*pb.GetUserByIDResponse, error) {
 	user, err := s.authService.GetUserByID(req.Id)
 	if err != nil {
@@ -83,13 +274,7 @@ func (s *AuthServer) GetUserByID(ctx context.Context, req *pb.GetUserByIDRequest
 	}
 
 	return &pb.GetUserByIDResponse{
-		User: &pb.User{
-			Id:        user.ID,
-			Email:     user.Email,
-			Username:  user.Username,
-			CreatedAt: timestamppb.New(user.CreatedAt),
-			UpdatedAt: timestamppb.New(user.UpdatedAt),
-		},
+		User: userToProto(user),
 	}, nil
 }
 
@@ -100,12 +285,412 @@ func (s *AuthServer) GetUserByEmail(ctx context.Context, req *pb.GetUserByEmailR
 	}
 
 	return &pb.GetUserByEmailResponse{
-		User: &pb.User{
-			Id:        user.ID,
-			Email:     user.Email,
-			Username:  user.Username,
-			CreatedAt: timestamppb.New(user.CreatedAt),
-			UpdatedAt: timestamppb.New(user.UpdatedAt),
-		},
+		User: userToProto(user),
+	}, nil
+}
+
+// maxUsersByIDs bounds a single GetUsersByIDs request.
+const maxUsersByIDs = 1000
+
+func (s *AuthServer) GetUsersByIDs(ctx context.Context, req *pb.GetUsersByIDsRequest) (*pb.GetUsersByIDsResponse, error) {
+	if len(req.Ids) > maxUsersByIDs {
+		return &pb.GetUsersByIDsResponse{Error: fmt.Sprintf("at most %d ids per request", maxUsersByIDs)}, nil
+	}
+
+	users, err := s.authService.GetUsersByIDs(req.Ids)
+	if err != nil {
+		s.logger.Error("get users by ids failed", "error", err)
+		return &pb.GetUsersByIDsResponse{Error: err.Error()}, nil
+	}
+
+	pbUsers := make([]*pb.User, len(users))
+	for i, user := range users {
+		pbUsers[i] = userToProto(user)
+	}
+
+	return &pb.GetUsersByIDsResponse{Users: pbUsers}, nil
+}
+
+func (s *AuthServer) GetUserByUsername(ctx context.Context, req *pb.GetUserByUsernameRequest) (*pb.GetUserByUsernameResponse, error) {
+	user, err := s.authService.GetUserByUsername(req.Username)
+	if err != nil {
+		return &pb.GetUserByUsernameResponse{Error: err.Error()}, nil
+	}
+
+	return &pb.GetUserByUsernameResponse{
+		User: userToProto(user),
+	}, nil
+}
+
+func (s *AuthServer) ListUsers(ctx context.Context, req *pb.ListUsersRequest) (*pb.ListUsersResponse, error) {
+	limit := int(req.Limit)
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+
+	users, total, err := s.authService.ListUsers(req.ActorId, limit, int(req.Offset))
+	if err != nil {
+		s.logger.Error("list users failed", "actor_id", req.ActorId, "error", err)
+		return &pb.ListUsersResponse{Error: err.Error()}, nil
+	}
+
+	pbUsers := make([]*pb.User, len(users))
+	for i, user := range users {
+		pbUsers[i] = userToProto(user)
+	}
+
+	return &pb.ListUsersResponse{Users: pbUsers, Total: int32(total)}, nil
+}
+
+func (s *AuthServer) SetUserRoles(ctx context.Context, req *pb.SetUserRolesRequest) (*pb.SetUserRolesResponse, error) {
+	user, err := s.authService.SetUserRoles(req.ActorId, req.UserId, req.Roles, clientInfo(ctx))
+	if err != nil {
+		s.logger.Error("set user roles failed", "actor_id", req.ActorId, "user_id", req.UserId, "error", err)
+		return &pb.SetUserRolesResponse{Error: err.Error()}, nil
+	}
+
+	return &pb.SetUserRolesResponse{User: userToProto(user)}, nil
+}
+
+func (s *AuthServer) ListAuthEvents(ctx context.Context, req *pb.ListAuthEventsRequest) (*pb.ListAuthEventsResponse, error) {
+	limit := int(req.Limit)
+	if limit <= 0 {
+		limit = 50
+	}
+	if limit > 200 {
+		limit = 200
+	}
+
+	filter := repository.AuthEventFilter{
+		UserID: req.UserId,
+		Type:   req.Type,
+		IP:     req.Ip,
+	}
+	if req.Since != nil {
+		filter.Since = req.Since.AsTime()
+	}
+	if req.Until != nil {
+		filter.Until = req.Until.AsTime()
+	}
+
+	events, total, err := s.authService.ListAuthEvents(req.ActorId, filter, limit, int(req.Offset))
+	if err != nil {
+		s.logger.Error("list auth events failed", "actor_id", req.ActorId, "error", err)
+		return &pb.ListAuthEventsResponse{Error: err.Error()}, nil
+	}
+
+	pbEvents := make([]*pb.AuthEvent, len(events))
+	for i, event := range events {
+		if pbEvents[i], err = authEventToProto(event); err != nil {
+			s.logger.Error("list auth events failed", "event_id", event.ID, "error", err)
+			return &pb.ListAuthEventsResponse{Error: err.Error()}, nil
+		}
+	}
+
+	return &pb.ListAuthEventsResponse{Events: pbEvents, Total: int32(total)}, nil
+}
+
+func (s *AuthServer) GetJWKS(ctx context.Context, req *pb.GetJWKSRequest) (*pb.GetJWKSResponse, error) {
+	set, err := s.authService.JWKS()
+	if err != nil {
+		s.logger.Error("get jwks failed", "error", err)
+		return &pb.GetJWKSResponse{Error: err.Error()}, nil
+	}
+
+	keys := make([]*pb.JWK, 0, len(set.Keys))
+	for _, key := range set.Keys {
+		keys = append(keys, &pb.JWK{
+			Kty: key.Kty,
+			Kid: key.Kid,
+			Use: key.Use,
+			Alg: key.Alg,
+			N:   key.N,
+			E:   key.E,
+			Crv: key.Crv,
+			X:   key.X,
+		})
+	}
+
+	return &pb.GetJWKSResponse{Keys: keys}, nil
+}
+
+func (s *AuthServer) RotateSigningKey(ctx context.Context, req *pb.RotateSigningKeyRequest) (*pb.RotateSigningKeyResponse, error) {
+	kid, err := s.authService.RotateSigningKey(req.ActorId)
+	if err != nil {
+		s.logger.Error("rotate signing key failed", "actor_id", req.ActorId, "error", err)
+		return &pb.RotateSigningKeyResponse{Error: err.Error()}, nil
+	}
+
+	return &pb.RotateSigningKeyResponse{Kid: kid}, nil
+}
+
+func (s *AuthServer) CreatePersonalAccessToken(ctx context.Context, req *pb.CreatePersonalAccessTokenRequest) (*pb.CreatePersonalAccessTokenResponse, error) {
+	if req.ExpiresInDays < 0 {
+		return &pb.CreatePersonalAccessTokenResponse{Error: "invalid expiration"}, nil
+	}
+	ttl := time.Duration(req.ExpiresInDays) * 24 * time.Hour
+
+	plain, token, err := s.authService.CreatePersonalAccessToken(req.UserId, req.Name, req.Scopes, ttl)
+	if err != nil {
+		s.logger.Error("create personal access token failed", "user_id", req.UserId, "error", err)
+		return &pb.CreatePersonalAccessTokenResponse{Error: err.Error()}, nil
+	}
+
+	return &pb.CreatePersonalAccessTokenResponse{
+		Token:               plain,
+		PersonalAccessToken: personalAccessTokenToProto(token),
+	}, nil
+}
+
+func (s *AuthServer) ListPersonalAccessTokens(ctx context.Context, req *pb.ListPersonalAccessTokensRequest) (*pb.ListPersonalAccessTokensResponse, error) {
+	tokens, err := s.authService.ListPersonalAccessTokens(req.UserId)
+	if err != nil {
+		s.logger.Error("list personal access tokens failed", "user_id", req.UserId, "error", err)
+		return &pb.ListPersonalAccessTokensResponse{Error: err.Error()}, nil
+	}
+
+	pbTokens := make([]*pb.PersonalAccessToken, len(tokens))
+	for i, token := range tokens {
+		pbTokens[i] = personalAccessTokenToProto(token)
+	}
+
+	return &pb.ListPersonalAccessTokensResponse{Tokens: pbTokens}, nil
+}
+
+func (s *AuthServer) RevokePersonalAccessToken(ctx context.Context, req *pb.RevokePersonalAccessTokenRequest) (*pb.RevokePersonalAccessTokenResponse, error) {
+	if err := s.authService.RevokePersonalAccessToken(req.UserId, req.Id); err != nil {
+		s.logger.Error("revoke personal access token failed", "user_id", req.UserId, "token_id", req.Id, "error", err)
+		return &pb.RevokePersonalAccessTokenResponse{Success: false, Error: err.Error()}, nil
+	}
+
+	return &pb.RevokePersonalAccessTokenResponse{Success: true}, nil
+}
+
+func (s *AuthServer) UnlockUser(ctx context.Context, req *pb.UnlockUserRequest) (*pb.UnlockUserResponse, error) {
+	if err := s.authService.UnlockUser(req.ActorId, req.UserId); err != nil {
+		s.logger.Error("unlock user failed", "actor_id", req.ActorId, "user_id", req.UserId, "error", err)
+		return &pb.UnlockUserResponse{Success: false, Error: err.Error()}, nil
+	}
+
+	return &pb.UnlockUserResponse{Success: true}, nil
+}
+
+func (s *AuthServer) EnrollTOTP(ctx context.Context, req *pb.EnrollTOTPRequest) (*pb.EnrollTOTPResponse, error) {
+	secret, uri, err := s.authService.EnrollTOTP(req.UserId)
+	if err != nil {
+		s.logger.Error("totp enrollment failed", "user_id", req.UserId, "error", err)
+		return &pb.EnrollTOTPResponse{Error: err.Error()}, nil
+	}
+
+	return &pb.EnrollTOTPResponse{Secret: secret, OtpauthUri: uri}, nil
+}
+
+func (s *AuthServer) ConfirmTOTP(ctx context.Context, req *pb.ConfirmTOTPRequest) (*pb.ConfirmTOTPResponse, error) {
+	codes, err := s.authService.ConfirmTOTP(req.UserId, req.Code)
+	if err != nil {
+		s.logger.Error("totp confirmation failed", "user_id", req.UserId, "error", err)
+		return &pb.ConfirmTOTPResponse{Error: err.Error()}, nil
+	}
+
+	return &pb.ConfirmTOTPResponse{RecoveryCodes: codes}, nil
+}
+
+func (s *AuthServer) DisableTOTP(ctx context.Context, req *pb.DisableTOTPRequest) (*pb.DisableTOTPResponse, error) {
+	if err := s.authService.DisableTOTP(req.UserId, req.Password, req.Code); err != nil {
+		s.logger.Error("disable totp failed", "user_id", req.UserId, "error", err)
+		return &pb.DisableTOTPResponse{Success: false, Error: err.Error()}, nil
+	}
+
+	return &pb.DisableTOTPResponse{Success: true}, nil
+}
+
+func (s *AuthServer) ListOIDCProviders(ctx context.Context, req *pb.ListOIDCProvidersRequest) (*pb.ListOIDCProvidersResponse, error) {
+	return &pb.ListOIDCProvidersResponse{Providers: s.authService.OIDCProviderNames()}, nil
+}
+
+func (s *AuthServer) StartOIDCLogin(ctx context.Context, req *pb.StartOIDCLoginRequest) (*pb.StartOIDCLoginResponse, error) {
+	url, err := s.authService.StartOIDCLogin(ctx, req.Provider)
+	if err != nil {
+		s.logger.Error("start oidc login failed", "provider", req.Provider, "error", err)
+		return &pb.StartOIDCLoginResponse{Error: err.Error()}, nil
+	}
+
+	return &pb.StartOIDCLoginResponse{AuthorizationUrl: url}, nil
+}
+
+func (s *AuthServer) CompleteOIDCLogin(ctx context.Context, req *pb.CompleteOIDCLoginRequest) (*pb.CompleteOIDCLoginResponse, error) {
+	result, err := s.authService.CompleteOIDCLogin(ctx, req.Provider, req.Code, req.State, clientInfo(ctx))
+	if err != nil {
+		s.logger.Error("complete oidc login failed", "provider", req.Provider, "error", err)
+		return &pb.CompleteOIDCLoginResponse{Error: err.Error()}, nil
+	}
+
+	if result.MFAToken != "" {
+		return &pb.CompleteOIDCLoginResponse{
+			MfaRequired: true,
+			MfaToken:    result.MFAToken,
+		}, nil
+	}
+
+	return &pb.CompleteOIDCLoginResponse{
+		User:         userToProto(result.User),
+		Token:        result.Tokens.AccessToken,
+		RefreshToken: result.Tokens.RefreshToken,
 	}, nil
 }
+
+func (s *AuthServer) ListSessions(ctx context.Context, req *pb.ListSessionsRequest) (*pb.ListSessionsResponse, error) {
+	sessions, err := s.authService.ListSessions(req.UserId)
+	if err != nil {
+		s.logger.Error("list sessions failed", "user_id", req.UserId, "error", err)
+		return &pb.ListSessionsResponse{Error: err.Error()}, nil
+	}
+
+	pbSessions := make([]*pb.Session, len(sessions))
+	for i, session := range sessions {
+		pbSessions[i] = &pb.Session{
+			Id:         session.ID,
+			UserAgent:  session.UserAgent,
+			Ip:         session.IP,
+			CreatedAt:  timestamppb.New(session.CreatedAt),
+			LastSeenAt: timestamppb.New(session.LastSeenAt),
+			Current:    session.ID == req.CurrentSessionId,
+		}
+	}
+
+	return &pb.ListSessionsResponse{Sessions: pbSessions}, nil
+}
+
+func (s *AuthServer) RevokeSession(ctx context.Context, req *pb.RevokeSessionRequest) (*pb.RevokeSessionResponse, error) {
+	if err := s.authService.RevokeSession(req.UserId, req.SessionId); err != nil {
+		s.logger.Error("revoke session failed", "user_id", req.UserId, "error", err)
+		return &pb.RevokeSessionResponse{Success: false, Error: err.Error()}, nil
+	}
+
+	return &pb.RevokeSessionResponse{Success: true}, nil
+}
+
+func (s *AuthServer) DeleteAccount(ctx context.Context, req *pb.DeleteAccountRequest) (*pb.DeleteAccountResponse, error) {
+	user, err := s.authService.DeleteAccount(ctx, req.UserId, req.Password, req.DeleteArticles)
+	if err != nil {
+		s.logger.Error("delete account failed", "user_id", req.UserId, "error", err)
+		return &pb.DeleteAccountResponse{Success: false, Error: err.Error()}, nil
+	}
+
+	return &pb.DeleteAccountResponse{Success: true, PurgeAt: timestamppb.New(user.PurgeAt)}, nil
+}
+
+func (s *AuthServer) RestoreAccount(ctx context.Context, req *pb.RestoreAccountRequest) (*pb.RestoreAccountResponse, error) {
+	user, err := s.authService.RestoreAccount(req.Token)
+	if err != nil {
+		s.logger.Warn("restore account failed", "error", err)
+		return &pb.RestoreAccountResponse{Error: err.Error()}, nil
+	}
+
+	return &pb.RestoreAccountResponse{User: userToProto(user)}, nil
+}
+
+func (s *AuthServer) BlockUser(ctx context.Context, req *pb.UserRelationRequest) (*pb.UserRelationResponse, error) {
+	return s.changeRelation(ctx, req, "block user", s.authService.Block)
+}
+
+func (s *AuthServer) UnblockUser(ctx context.Context, req *pb.UserRelationRequest) (*pb.UserRelationResponse, error) {
+	return s.changeRelation(ctx, req, "unblock user", s.authService.Unblock)
+}
+
+func (s *AuthServer) MuteUser(ctx context.Context, req *pb.UserRelationRequest) (*pb.UserRelationResponse, error) {
+	return s.changeRelation(ctx, req, "mute user", s.authService.Mute)
+}
+
+func (s *AuthServer) UnmuteUser(ctx context.Context, req *pb.UserRelationRequest) (*pb.UserRelationResponse, error) {
+	return s.changeRelation(ctx, req, "unmute user", s.authService.Unmute)
+}
+
+func (s *AuthServer) changeRelation(ctx context.Context, req *pb.UserRelationRequest, action string, change func(context.Context, uint64, uint64) error) (*pb.UserRelationResponse, error) {
+	if err := change(ctx, req.UserId, req.TargetId); err != nil {
+		s.logger.Error(action+" failed", "user_id", req.UserId, "target_id", req.TargetId, "error", err)
+		return &pb.UserRelationResponse{Success: false, Error: err.Error()}, nil
+	}
+
+	return &pb.UserRelationResponse{Success: true}, nil
+}
+
+func (s *AuthServer) ListBlockedUsers(ctx context.Context, req *pb.ListBlockedUsersRequest) (*pb.ListBlockedUsersResponse, error) {
+	kind := repository.BlockKindBlock
+	if req.Muted {
+		kind = repository.BlockKindMute
+	}
+
+	blocks, err := s.authService.ListBlocks(req.UserId, kind)
+	if err != nil {
+		s.logger.Error("list blocked users failed", "user_id", req.UserId, "error", err)
+		return &pb.ListBlockedUsersResponse{Error: err.Error()}, nil
+	}
+
+	ids := make([]uint64, len(blocks))
+	for i, block := range blocks {
+		ids[i] = block.BlockedID
+	}
+	users, err := s.authService.GetUsersByIDs(ids)
+	if err != nil {
+		s.logger.Error("list blocked users failed", "user_id", req.UserId, "error", err)
+		return &pb.ListBlockedUsersResponse{Error: err.Error()}, nil
+	}
+	usernames := make(map[uint64]string, len(users))
+	for _, user := range users {
+		usernames[user.ID] = user.Username
+	}
+
+	pbUsers := make([]*pb.BlockedUser, len(blocks))
+	for i, block := range blocks {
+		pbUsers[i] = &pb.BlockedUser{
+			UserId:    block.BlockedID,
+			Username:  usernames[block.BlockedID],
+			CreatedAt: timestamppb.New(block.CreatedAt),
+		}
+	}
+
+	return &pb.ListBlockedUsersResponse{Users: pbUsers}, nil
+}
+
+func (s *AuthServer) CreateInvite(ctx context.Context, req *pb.CreateInviteRequest) (*pb.CreateInviteResponse, error) {
+	if req.ExpiresInDays < 0 {
+		return &pb.CreateInviteResponse{Error: "invalid expiration"}, nil
+	}
+	ttl := time.Duration(req.ExpiresInDays) * 24 * time.Hour
+
+	plain, invite, err := s.authService.CreateInvite(req.UserId, int(req.MaxUses), ttl)
+	if err != nil {
+		s.logger.Error("create invite failed", "user_id", req.UserId, "error", err)
+		return &pb.CreateInviteResponse{Error: err.Error()}, nil
+	}
+
+	return &pb.CreateInviteResponse{
+		Code:   plain,
+		Invite: inviteToProto(invite),
+	}, nil
+}
+
+func (s *AuthServer) ListInvites(ctx context.Context, req *pb.ListInvitesRequest) (*pb.ListInvitesResponse, error) {
+	invites, err := s.authService.ListInvites(req.UserId)
+	if err != nil {
+		s.logger.Error("list invites failed", "user_id", req.UserId, "error", err)
+		return &pb.ListInvitesResponse{Error: err.Error()}, nil
+	}
+
+	pbInvites := make([]*pb.InviteCode, len(invites))
+	for i, invite := range invites {
+		pbInvites[i] = inviteToProto(invite)
+	}
+
+	return &pb.ListInvitesResponse{Invites: pbInvites}, nil
+}
+
+func (s *AuthServer) RevokeInvite(ctx context.Context, req *pb.RevokeInviteRequest) (*pb.RevokeInviteResponse, error) {
+	if err := s.authService.RevokeInvite(req.UserId, req.Id); err != nil {
+		s.logger.Error("revoke invite failed", "user_id", req.UserId, "invite_id", req.Id, "error", err)
+		return &pb.RevokeInviteResponse{Success: false, Error: err.Error()}, nil
+	}
+
+	return &pb.RevokeInviteResponse{Success: true}, nil
+}
@@ -0,0 +1,39 @@
+package server
+
+import (
+	"context"
+
+	"google.golang.org/grpc/metadata"
+
+	"github.com/XRS0/blog/services/auth-service/internal/service"
+)
+
+// Metadata keys carrying the end-user address and user agent set by the API gateway.
+const (
+	clientIPMetadataKey        = "x-client-ip"
+	clientUserAgentMetadataKey = "x-client-user-agent"
+)
+
+// clientIP returns the end-user address forwarded by the gateway, or "" if absent.
+func clientIP(ctx context.Context) string {
+	return metadataValue(ctx, clientIPMetadataKey)
+}
+
+// clientInfo returns the end-user address and user agent forwarded by the gateway.
+func clientInfo(ctx context.Context) service.ClientInfo {
+	return service.ClientInfo{
+		IP:        clientIP(ctx),
+		UserAgent: metadataValue(ctx, clientUserAgentMetadataKey),
+	}
+}
+
+func metadataValue(ctx context.Context, key string) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
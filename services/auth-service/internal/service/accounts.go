@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/XRS0/blog/services/auth-service/internal/mailer"
+	"github.com/XRS0/blog/services/auth-service/internal/repository"
+	"github.com/XRS0/blog/shared/rabbitmq"
+)
+
+// usersExchange carries events about user accounts to the other services.
+const usersExchange = "users"
+
+// DeleteAccount soft-deletes the user and ends all their sessions. The account can
+// be restored with the emailed link until the grace period ends; then it is purged
+// and the other services delete or anonymize the user's articles, as chosen.
+func (s *AuthService) DeleteAccount(ctx context.Context, userID uint64, password string, deleteArticles bool) (*repository.User, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("user not found")
+	}
+	if user.IsDeleted() {
+		return nil, ErrAccountDeleted
+	}
+
+	// Users without a password (created through an OpenID provider) are trusted by their session
+	if user.HasPassword() {
+		if !s.checkPassword(user, password) {
+			return nil, fmt.Errorf("invalid current password")
+		}
+	}
+
+	user, err = s.userRepo.MarkDeleted(userID, s.now().Add(s.config.AccountDeletionGrace), deleteArticles)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.endAllSessions(userID); err != nil {
+		return nil, err
+	}
+
+	if err := s.sendAccountDeletedEmail(ctx, user); err != nil {
+		s.logger.Error("failed to send account deletion email", "user_id", userID, "error", err)
+	}
+
+	s.logger.Info("account deleted", "user_id", userID, "purge_at", user.PurgeAt, "delete_articles", deleteArticles)
+	return user, nil
+}
+
+// RestoreAccount undoes a deletion using the link sent by DeleteAccount. The user
+// signs in again afterwards.
+func (s *AuthService) RestoreAccount(token string) (*repository.User, error) {
+	claims, err := s.parseAccountRestoreToken(token)
+	if err != nil {
+		return nil, fmt.Errorf("invalid or expired restore token")
+	}
+
+	userID, err := subjectUserID(&claims.RegisteredClaims)
+	if err != nil || claims.DeletedAt == 0 {
+		return nil, fmt.Errorf("invalid or expired restore token")
+	}
+
+	user, err := s.userRepo.Restore(userID, time.UnixMicro(claims.DeletedAt))
+	if err != nil {
+		return nil, fmt.Errorf("invalid or expired restore token")
+	}
+
+	s.logger.Info("account restored", "user_id", userID)
+	return user, nil
+}
+
+// StartAccountPurge periodically purges accounts whose grace period has ended.
+func (s *AuthService) StartAccountPurge(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.purgeDeletedAccounts(ctx); err != nil {
+					s.logger.Error("failed to purge deleted accounts", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+// purgeDeletedAccounts publishes user.deleted for every account due for purging and
+// then anonymizes it. The event goes out first, so a failure in between only
+// repeats the event; consumers must handle it more than once.
+func (s *AuthService) purgeDeletedAccounts(ctx context.Context) error {
+	users, err := s.userRepo.ListDueForPurge(s.now(), 100)
+	if err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		event := rabbitmq.Event{
+			Type: rabbitmq.EventUserDeleted,
+			Data: map[string]interface{}{
+				"user_id":         user.ID,
+				"delete_articles": user.DeleteArticles,
+			},
+		}
+		if err := s.mq.Publish(ctx, usersExchange, rabbitmq.EventUserDeleted, event); err != nil {
+			return err
+		}
+
+		if err := s.userRepo.Purge(user.ID); err != nil {
+			return err
+		}
+		s.logger.Info("account purged", "user_id", user.ID)
+	}
+
+	return nil
+}
+
+func (s *AuthService) sendAccountDeletedEmail(ctx context.Context, user *repository.User) error {
+	token, err := s.generateAccountRestoreToken(user)
+	if err != nil {
+		return fmt.Errorf("failed to generate restore token: %w", err)
+	}
+
+	link := fmt.Sprintf("%s/restore-account?token=%s", s.config.AppBaseURL, url.QueryEscape(token))
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Your account has been deleted",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nYour account has been deleted and will be removed permanently on %s.\n\n"+
+				"If this was a mistake, restore it by opening the link below before then:\n\n%s\n",
+			user.Username, user.PurgeAt.UTC().Format(time.RFC1123), link,
+		),
+	})
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/XRS0/blog/services/auth-service/internal/repository"
+	"github.com/XRS0/blog/shared/rabbitmq"
+)
+
+var knownRoles = map[string]bool{
+	repository.RoleUser:      true,
+	repository.RoleModerator: true,
+	repository.RoleAdmin:     true,
+}
+
+// ListUsers returns a page of users. The actor must be an admin.
+func (s *AuthService) ListUsers(actorID uint64, limit, offset int) ([]*repository.User, int, error) {
+	if err := s.requireRole(actorID, repository.RoleAdmin); err != nil {
+		return nil, 0, err
+	}
+	return s.userRepo.List(limit, offset)
+}
+
+// SetUserRoles replaces the roles of a user. The actor must be an admin and
+// cannot remove their own admin role. The user role is always kept.
+// New roles take effect in access tokens issued after the change. Removing a role
+// ends all sessions of the user, so that tokens issued before cannot keep it.
+func (s *AuthService) SetUserRoles(actorID, userID uint64, roles []string, client ClientInfo) (*repository.User, error) {
+	if err := s.requireRole(actorID, repository.RoleAdmin); err != nil {
+		return nil, err
+	}
+
+	normalized := []string{repository.RoleUser}
+	for _, role := range roles {
+		role = strings.ToLower(strings.TrimSpace(role))
+		if !knownRoles[role] {
+			return nil, fmt.Errorf("unknown role %q", role)
+		}
+		if role != repository.RoleUser && !contains(normalized, role) {
+			normalized = append(normalized, role)
+		}
+	}
+
+	if actorID == userID && !contains(normalized, repository.RoleAdmin) {
+		return nil, fmt.Errorf("cannot remove your own admin role")
+	}
+
+	previous, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.SetRoles(userID, normalized)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, role := range previous.Roles {
+		if !contains(normalized, role) {
+			if err := s.endAllSessions(userID); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+
+	s.audit(rabbitmq.EventAuthRolesChanged, userID, client, map[string]interface{}{
+		"actor_id":       actorID,
+		"previous_roles": previous.Roles,
+		"roles":          normalized,
+	})
+
+	s.logger.Info("user roles changed", "actor_id", actorID, "user_id", userID, "roles", normalized)
+	return user, nil
+}
+
+// EnsureAdmins grants the admin role to already registered AdminEmails.
+func (s *AuthService) EnsureAdmins() error {
+	for _, email := range s.config.AdminEmails {
+		if err := s.userRepo.AddRoleByEmail(email, repository.RoleAdmin); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RotateSigningKey replaces the active JWT signing key. The actor must be an admin.
+// Tokens signed by the previous key stay valid until they expire.
+func (s *AuthService) RotateSigningKey(actorID uint64) (string, error) {
+	if err := s.requireRole(actorID, repository.RoleAdmin); err != nil {
+		return "", err
+	}
+
+	kid, err := s.keyring.Rotate()
+	if err != nil {
+		return "", err
+	}
+
+	s.logger.Info("signing key rotated by admin", "actor_id", actorID, "kid", kid)
+	return kid, nil
+}
+
+func (s *AuthService) requireRole(userID uint64, role string) error {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil || !user.HasRole(role) {
+		return fmt.Errorf("permission denied")
+	}
+	return nil
+}
+
+func (s *AuthService) isAdminEmail(email string) bool {
+	for _, admin := range s.config.AdminEmails {
+		if strings.EqualFold(admin, email) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
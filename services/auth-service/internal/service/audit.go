@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+
+	"github.com/XRS0/blog/services/auth-service/internal/repository"
+	"github.com/XRS0/blog/shared/rabbitmq"
+)
+
+// authExchange carries the security audit events to whoever wants to follow them.
+const authExchange = "auth"
+
+// Reasons recorded for failed logins.
+const (
+	loginFailureUnknownUser     = "unknown_user"
+	loginFailureInvalidPassword = "invalid_password"
+	loginFailureInvalidCode     = "invalid_code"
+	loginFailureLocked          = "locked"
+	loginFailureNoPassword      = "password_login_unavailable"
+	loginFailureProviderError   = "provider_error"
+)
+
+// audit appends an event to the audit trail and publishes it. Failures are
+// logged and never fail the audited operation.
+func (s *AuthService) audit(eventType string, userID uint64, client ClientInfo, details map[string]interface{}) {
+	event := &repository.AuthEvent{
+		Type:      eventType,
+		UserID:    userID,
+		IP:        client.IP,
+		UserAgent: client.UserAgent,
+		Details:   details,
+		CreatedAt: s.now(),
+	}
+	if err := s.auditRepo.Append(event); err != nil {
+		s.logger.Error("failed to record auth event", "type", eventType, "user_id", userID, "error", err)
+	}
+
+	message := rabbitmq.Event{
+		Type: eventType,
+		Data: map[string]interface{}{
+			"event_id":   event.ID,
+			"user_id":    userID,
+			"ip":         client.IP,
+			"user_agent": client.UserAgent,
+			"details":    details,
+		},
+	}
+	if err := s.mq.Publish(context.Background(), authExchange, eventType, message); err != nil {
+		s.logger.Error("failed to publish auth event", "type", eventType, "user_id", userID, "error", err)
+	}
+}
+
+// auditLoginFailure records a failed login. userID is 0 if the account is not
+// known; the attempted email is recorded either way.
+func (s *AuthService) auditLoginFailure(userID uint64, email, reason string, client ClientInfo) {
+	s.audit(rabbitmq.EventAuthLoginFailed, userID, client, map[string]interface{}{
+		"email":  email,
+		"reason": reason,
+	})
+}
+
+// ListAuthEvents returns a page of the audit trail. The actor must be an admin.
+func (s *AuthService) ListAuthEvents(actorID uint64, filter repository.AuthEventFilter, limit, offset int) ([]*repository.AuthEvent, int, error) {
+	if err := s.requireRole(actorID, repository.RoleAdmin); err != nil {
+		return nil, 0, err
+	}
+	return s.auditRepo.List(filter, limit, offset)
+}
@@ -1,119 +1,588 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net/http"
+	"strings"
 	"time"
 
+	"github.com/XRS0/blog/services/auth-service/internal/keys"
+	"github.com/XRS0/blog/services/auth-service/internal/mailer"
+	"github.com/XRS0/blog/services/auth-service/internal/oidc"
+	"github.com/XRS0/blog/services/auth-service/internal/password"
 	"github.com/XRS0/blog/services/auth-service/internal/repository"
-	"github.com/golang-jwt/jwt/v5"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/XRS0/blog/shared/rabbitmq"
 )
 
+// Config holds token settings for AuthService.
+type Config struct {
+	AccessTokenTTL   time.Duration
+	RefreshTokenTTL  time.Duration
+	PasswordResetTTL time.Duration
+	// EmailVerificationTTL is the lifetime of the signed link sent after registration.
+	EmailVerificationTTL time.Duration
+	// AppBaseURL is the frontend URL used to build links sent by email.
+	AppBaseURL string
+	// AdminEmails are granted the admin role on registration and at startup.
+	AdminEmails []string
+	// RegistrationMode is RegistrationOpen, RegistrationInviteOnly or RegistrationClosed.
+	RegistrationMode string
+
+	// PasswordHasher hashes new passwords; nil means argon2id with default parameters.
+	PasswordHasher password.Hasher
+	// PasswordPolicy is applied to new passwords; nil means a minimum of 8 characters.
+	PasswordPolicy *password.Policy
+
+	LoginProtection LoginProtectionConfig
+
+	// TOTPIssuer is the account issuer shown in authenticator apps.
+	TOTPIssuer string
+	// MFAChallengeTTL is how long a user has to enter the second factor after the password.
+	MFAChallengeTTL time.Duration
+
+	// OIDCProviders are the OpenID providers users can sign in with.
+	OIDCProviders []oidc.ProviderConfig
+	// OIDCStateTTL is how long the user has to complete the provider login.
+	OIDCStateTTL time.Duration
+
+	// AccountDeletionGrace is how long a deleted account can be restored before
+	// it is purged and the other services are told to drop its data.
+	AccountDeletionGrace time.Duration
+
+	// SessionCacheTTL is how long ValidateToken trusts its cached view of a
+	// session. Revocations made on another instance take effect within it.
+	SessionCacheTTL time.Duration
+
+	// Clock returns the current time; nil means time.Now. Replacing it lets tests
+	// control token expiry, lockouts and TOTP time steps.
+	Clock func() time.Time
+}
+
+// ErrPasswordLoginUnavailable is returned by Login for users created through an
+// OpenID provider that have not set a password.
+var ErrPasswordLoginUnavailable = errors.New("password login is not available for this account")
+
+// ErrAccountDeleted is returned instead of tokens for accounts pending deletion.
+var ErrAccountDeleted = errors.New("account is scheduled for deletion")
+
+// Identity is what a valid access token says about its bearer.
+type Identity struct {
+	UserID        uint64
+	EmailVerified bool
+	Roles         []string
+	// Scopes limit what a personal access token may do. Session tokens are not scoped.
+	Scopes              []string
+	PersonalAccessToken bool
+	// SessionID identifies the login a session token belongs to.
+	SessionID string
+}
+
+// TokenPair is a short-lived access JWT plus the opaque refresh token used to renew it.
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+}
+
 type AuthService struct {
-	userRepo  *repository.UserRepository
-	jwtSecret string
-	logger    *slog.Logger
+	userRepo         *repository.UserRepository
+	tokenRepo        *repository.TokenRepository
+	patRepo          *repository.PersonalAccessTokenRepository
+	loginAttemptRepo *repository.LoginAttemptRepository
+	mfaRepo          *repository.MFARepository
+	identityRepo     *repository.IdentityRepository
+	sessionRepo      *repository.SessionRepository
+	blockRepo        *repository.BlockRepository
+	inviteRepo       *repository.InviteRepository
+	auditRepo        *repository.AuditRepository
+	keyring          *keys.Keyring
+	mq               *rabbitmq.Client
+	mailer           mailer.Mailer
+	config           Config
+	logger           *slog.Logger
+
+	oidcProviders map[string]*oidc.Provider
+	sessionCache  *sessionCache
 }
 
-func NewAuthService(userRepo *repository.UserRepository, jwtSecret string, logger *slog.Logger) *AuthService {
+func NewAuthService(
+	userRepo *repository.UserRepository,
+	tokenRepo *repository.TokenRepository,
+	patRepo *repository.PersonalAccessTokenRepository,
+	loginAttemptRepo *repository.LoginAttemptRepository,
+	mfaRepo *repository.MFARepository,
+	identityRepo *repository.IdentityRepository,
+	sessionRepo *repository.SessionRepository,
+	blockRepo *repository.BlockRepository,
+	inviteRepo *repository.InviteRepository,
+	auditRepo *repository.AuditRepository,
+	keyring *keys.Keyring,
+	mq *rabbitmq.Client,
+	mailer mailer.Mailer,
+	config Config,
+	logger *slog.Logger,
+) *AuthService {
+	if config.Clock == nil {
+		config.Clock = time.Now
+	}
+	if config.RegistrationMode == "" {
+		config.RegistrationMode = RegistrationOpen
+	}
+	if config.PasswordHasher == nil {
+		config.PasswordHasher = password.NewArgon2id(password.DefaultArgon2Params)
+	}
+	if config.PasswordPolicy == nil {
+		config.PasswordPolicy = password.NewPolicy(8, 0, nil)
+	}
+
+	httpClient := &http.Client{Timeout: 10 * time.Second}
+	providers := make(map[string]*oidc.Provider, len(config.OIDCProviders))
+	for _, provider := range config.OIDCProviders {
+		providers[provider.Name] = oidc.NewProvider(provider, httpClient)
+	}
+
 	return &AuthService{
-		userRepo:  userRepo,
-		jwtSecret: jwtSecret,
-		logger:    logger,
+		userRepo:         userRepo,
+		tokenRepo:        tokenRepo,
+		patRepo:          patRepo,
+		loginAttemptRepo: loginAttemptRepo,
+		mfaRepo:          mfaRepo,
+		identityRepo:     identityRepo,
+		sessionRepo:      sessionRepo,
+		blockRepo:        blockRepo,
+		inviteRepo:       inviteRepo,
+		auditRepo:        auditRepo,
+		keyring:          keyring,
+		mq:               mq,
+		mailer:           mailer,
+		config:           config,
+		logger:           logger,
+		oidcProviders:    providers,
+		sessionCache:     newSessionCache(config.SessionCacheTTL),
 	}
 }
 
-func (s *AuthService) Register(email, username, password string) (*repository.User, string, error) {
+// Register creates an account. Unless registration is open, inviteCode must be
+// an active invite, which is used up together with creating the user.
+func (s *AuthService) Register(ctx context.Context, email, username, password, inviteCode string, client ClientInfo) (*repository.User, *TokenPair, error) {
 	// Check if email already exists
 	exists, err := s.userRepo.EmailExists(email)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to check email: %w", err)
+		return nil, nil, fmt.Errorf("failed to check email: %w", err)
 	}
 	if exists {
-		return nil, "", fmt.Errorf("email already registered")
+		return nil, nil, fmt.Errorf("email already registered")
+	}
+
+	username = strings.TrimSpace(username)
+	if err := s.checkUsernameAvailable(username); err != nil {
+		return nil, nil, err
 	}
 
 	// Hash password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	hashedPassword, err := s.hashNewPassword(password)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to hash password: %w", err)
+		return nil, nil, err
 	}
 
 	// Create user
-	user, err := s.userRepo.Create(email, username, string(hashedPassword))
-	if err != nil {
-		return nil, "", fmt.Errorf("failed to create user: %w", err)
+	roles := []string{repository.RoleUser}
+	if s.isAdminEmail(email) {
+		roles = append(roles, repository.RoleAdmin)
+	}
+	user := &repository.User{
+		Email:    email,
+		Username: username,
+		Password: hashedPassword,
+		Roles:    roles,
+	}
+	if err := s.createRegisteredUser(user, inviteCode); err != nil {
+		return nil, nil, err
 	}
 
-	// Generate JWT token
-	token, err := s.generateToken(user.ID)
+	tokens, err := s.issueTokens(user, client)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to generate token: %w", err)
+		return nil, nil, err
+	}
+
+	if err := s.sendVerificationEmail(ctx, user); err != nil {
+		s.logger.Error("failed to send verification email", "user_id", user.ID, "error", err)
 	}
 
+	details := map[string]interface{}{"method": "password", "email": email}
+	if user.InviteCodeID != 0 {
+		details["invite_id"] = user.InviteCodeID
+		details["invited_by"] = user.InvitedBy
+	}
+	s.audit(rabbitmq.EventAuthRegistered, user.ID, client, details)
+
 	s.logger.Info("user registered", "user_id", user.ID, "email", email)
-	return user, token, nil
+	return user, tokens, nil
+}
+
+// LoginResult is either a session (Tokens) or, for users with two-factor
+// authentication, a challenge (MFAToken) to be completed with VerifyMFA.
+type LoginResult struct {
+	User     *repository.User
+	Tokens   *TokenPair
+	MFAToken string
 }
 
-func (s *AuthService) Login(email, password string) (*repository.User, string, error) {
+// Login checks the credentials. Failed attempts are counted per account and per
+// client IP (if known); once either is locked, Login fails with LoginLockedError
+// without checking the password.
+func (s *AuthService) Login(email, password string, client ClientInfo) (*LoginResult, error) {
+	account := accountKey(email)
+	if err := s.checkLoginLock(account, client.IP); err != nil {
+		s.auditLoginFailure(0, email, loginFailureLocked, client)
+		return nil, err
+	}
+
 	// Get user by email
 	user, err := s.userRepo.GetByEmail(email)
 	if err != nil {
-		return nil, "", fmt.Errorf("invalid credentials")
+		s.recordLoginFailure(account, client.IP)
+		s.auditLoginFailure(0, email, loginFailureUnknownUser, client)
+		return nil, fmt.Errorf("invalid credentials")
+	}
+	if !user.HasPassword() {
+		s.auditLoginFailure(user.ID, email, loginFailureNoPassword, client)
+		return nil, ErrPasswordLoginUnavailable
 	}
 
 	// Check password
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-		return nil, "", fmt.Errorf("invalid credentials")
+	if !s.checkPassword(user, password) {
+		s.recordLoginFailure(account, client.IP)
+		s.auditLoginFailure(user.ID, email, loginFailureInvalidPassword, client)
+		return nil, fmt.Errorf("invalid credentials")
 	}
+	s.upgradePasswordHash(user, password)
 
-	// Generate JWT token
-	token, err := s.generateToken(user.ID)
+	// Users with two-factor authentication get a challenge instead of a session.
+	// Failures are reset only once the second factor has been verified.
+	factor, err := s.mfaRepo.GetTOTPFactor(user.ID)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to generate token: %w", err)
+		return nil, err
 	}
+	if factor != nil && !factor.EnabledAt.IsZero() {
+		mfaToken, err := s.generateMFAToken(user)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate token: %w", err)
+		}
+		s.logger.Info("login requires second factor", "user_id", user.ID)
+		return &LoginResult{User: user, MFAToken: mfaToken}, nil
+	}
+
+	s.resetLoginFailures(account)
 
+	tokens, err := s.issueTokens(user, client)
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit(rabbitmq.EventAuthLoginSucceeded, user.ID, client, map[string]interface{}{"method": "password"})
 	s.logger.Info("user logged in", "user_id", user.ID, "email", email)
-	return user, token, nil
+	return &LoginResult{User: user, Tokens: tokens}, nil
+}
+
+// RefreshToken rotates a refresh token: the presented token is consumed and a new
+// pair is issued in the same family. Presenting an already rotated token is treated
+// as theft and revokes the whole family. The session is marked as seen from client.
+func (s *AuthService) RefreshToken(refreshToken string, client ClientInfo) (*TokenPair, error) {
+	stored, err := s.tokenRepo.GetRefreshTokenByHash(hashToken(refreshToken))
+	if err != nil {
+		return nil, fmt.Errorf("invalid refresh token")
+	}
+
+	if !stored.RevokedAt.IsZero() {
+		s.revokeReusedFamily(stored)
+		return nil, fmt.Errorf("invalid refresh token")
+	}
+	if s.now().After(stored.ExpiresAt) {
+		return nil, fmt.Errorf("refresh token expired")
+	}
+
+	plain, err := generateOpaqueToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+
+	expiresAt := s.now().Add(s.config.RefreshTokenTTL)
+	_, err = s.tokenRepo.RotateRefreshToken(stored, hashToken(plain), expiresAt)
+	if errors.Is(err, repository.ErrRefreshTokenUsed) {
+		s.revokeReusedFamily(stored)
+		return nil, fmt.Errorf("invalid refresh token")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.sessionRepo.Seen(stored.FamilyID, client.IP, client.UserAgent, s.now(), expiresAt); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(stored.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, err := s.generateToken(user, stored.FamilyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	s.audit(rabbitmq.EventAuthTokenRefreshed, user.ID, client, map[string]interface{}{"session_id": stored.FamilyID})
+	return &TokenPair{AccessToken: accessToken, RefreshToken: plain}, nil
+}
+
+// Logout ends the session the tokens belong to, which revokes the refresh token
+// family and every access token issued in it. Either token may be empty; unknown
+// or already revoked tokens are ignored.
+func (s *AuthService) Logout(accessToken, refreshToken string) error {
+	if accessToken != "" {
+		claims, err := s.parseAccessToken(accessToken)
+		if err == nil && claims.SessionID != "" {
+			if err := s.endSession(claims.SessionID); err != nil {
+				return err
+			}
+			s.logger.Info("user logged out", "session_id", claims.SessionID)
+		}
+	}
+
+	if refreshToken != "" {
+		stored, err := s.tokenRepo.GetRefreshTokenByHash(hashToken(refreshToken))
+		if err == nil {
+			if err := s.endSession(stored.FamilyID); err != nil {
+				return err
+			}
+			s.logger.Info("user logged out", "user_id", stored.UserID, "session_id", stored.FamilyID)
+		}
+	}
+
+	return nil
+}
+
+func (s *AuthService) ValidateToken(token string) (*Identity, error) {
+	if isPersonalAccessToken(token) {
+		return s.validatePersonalAccessToken(token)
+	}
+
+	claims, err := s.parseAccessToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, err := subjectUserID(&claims.RegisteredClaims)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.SessionID == "" {
+		return nil, fmt.Errorf("invalid token")
+	}
+	active, err := s.sessionActive(claims.SessionID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check session: %w", err)
+	}
+	if !active {
+		return nil, fmt.Errorf("token revoked")
+	}
+
+	return &Identity{
+		UserID:        userID,
+		EmailVerified: claims.EmailVerified,
+		Roles:         claims.Roles,
+		SessionID:     claims.SessionID,
+	}, nil
 }
 
-func (s *AuthService) ValidateToken(token string) (uint64, error) {
-	claims := &jwt.RegisteredClaims{}
+// UpdateProfile changes the public profile and/or the email; unset fields keep
+// the current values. Changing the email requires the current password and
+// resets its verification.
+func (s *AuthService) UpdateProfile(ctx context.Context, userID uint64, update ProfileUpdate) (*repository.User, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
 
-	parsedToken, err := jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
-		return []byte(s.jwtSecret), nil
-	})
+	username := strings.TrimSpace(update.Username)
+	if username == "" {
+		username = user.Username
+	}
+	email := update.Email
+	if email == "" {
+		email = user.Email
+	}
+	bio := user.Bio
+	if update.Bio != nil {
+		bio = strings.TrimSpace(*update.Bio)
+		if err := validateBio(bio); err != nil {
+			return nil, err
+		}
+	}
+	avatarURL := user.AvatarURL
+	if update.AvatarURL != nil {
+		avatarURL = strings.TrimSpace(*update.AvatarURL)
+		if err := validateAvatarURL(avatarURL); err != nil {
+			return nil, err
+		}
+	}
 
-	if err != nil || !parsedToken.Valid {
-		return 0, fmt.Errorf("invalid token")
+	// Changing only the case of the own handle is always allowed
+	if !strings.EqualFold(username, user.Username) {
+		if err := s.checkUsernameAvailable(username); err != nil {
+			return nil, err
+		}
+	} else if username != user.Username {
+		if err := validateUsername(username); err != nil {
+			return nil, err
+		}
 	}
 
-	// Extract user ID from subject
-	var userID uint64
-	_, err = fmt.Sscanf(claims.Subject, "%d", &userID)
+	if email != user.Email {
+		if !s.checkPassword(user, update.CurrentPassword) {
+			return nil, fmt.Errorf("invalid current password")
+		}
+
+		exists, err := s.userRepo.EmailExists(email)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check email: %w", err)
+		}
+		if exists {
+			return nil, fmt.Errorf("email already registered")
+		}
+	}
+
+	updated, err := s.userRepo.UpdateProfile(userID, username, email, bio, avatarURL)
 	if err != nil {
-		return 0, fmt.Errorf("invalid token subject")
+		return nil, err
+	}
+
+	if updated.Email != user.Email {
+		if err := s.sendVerificationEmail(ctx, updated); err != nil {
+			s.logger.Error("failed to send verification email", "user_id", userID, "error", err)
+		}
 	}
 
-	return userID, nil
+	s.logger.Info("profile updated", "user_id", userID)
+	return updated, nil
+}
+
+// ChangePassword re-verifies the current password, stores the new one and ends
+// every session of the user. The caller receives a fresh token pair.
+func (s *AuthService) ChangePassword(userID uint64, currentPassword, newPassword string, client ClientInfo) (*TokenPair, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !user.HasPassword() {
+		return nil, fmt.Errorf("account has no password, use password reset to set one")
+	}
+	if !s.checkPassword(user, currentPassword) {
+		return nil, fmt.Errorf("invalid current password")
+	}
+
+	hashedPassword, err := s.hashNewPassword(newPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.userRepo.UpdatePassword(userID, hashedPassword); err != nil {
+		return nil, err
+	}
+
+	if err := s.endAllSessions(userID); err != nil {
+		return nil, err
+	}
+
+	s.audit(rabbitmq.EventAuthPasswordChanged, userID, client, map[string]interface{}{"method": "change"})
+	s.logger.Info("password changed", "user_id", userID)
+	return s.issueTokens(user, client)
 }
 
 func (s *AuthService) GetUserByID(id uint64) (*repository.User, error) {
 	return s.userRepo.GetByID(id)
 }
 
+// GetUsersByIDs looks up several users at once; unknown IDs are left out.
+func (s *AuthService) GetUsersByIDs(ids []uint64) ([]*repository.User, error) {
+	return s.userRepo.GetByIDs(ids)
+}
+
 func (s *AuthService) GetUserByEmail(email string) (*repository.User, error) {
 	return s.userRepo.GetByEmail(email)
 }
 
-func (s *AuthService) generateToken(userID uint64) (string, error) {
-	claims := jwt.RegisteredClaims{
-		Subject:   fmt.Sprintf("%d", userID),
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
-		IssuedAt:  jwt.NewNumericDate(time.Now()),
+// StartTokenCleanup periodically purges expired refresh tokens and sessions,
+// forgotten login failures and abandoned OpenID logins.
+func (s *AuthService) StartTokenCleanup(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.tokenRepo.DeleteExpired(s.now()); err != nil {
+					s.logger.Error("failed to purge expired tokens", "error", err)
+				}
+				if err := s.loginAttemptRepo.DeleteStale(s.config.LoginProtection.FailureWindow, s.now()); err != nil {
+					s.logger.Error("failed to purge login attempts", "error", err)
+				}
+				if err := s.identityRepo.DeleteExpiredLoginStates(s.now()); err != nil {
+					s.logger.Error("failed to purge login states", "error", err)
+				}
+				if err := s.sessionRepo.DeleteExpired(s.now()); err != nil {
+					s.logger.Error("failed to purge sessions", "error", err)
+				}
+				s.sessionCache.prune(s.now())
+			}
+		}
+	}()
+}
+
+func (s *AuthService) now() time.Time {
+	return s.config.Clock()
+}
+
+// issueTokens starts a new session for the user; its ID is also the refresh
+// token family. Deleted accounts get no session until they are restored.
+func (s *AuthService) issueTokens(user *repository.User, client ClientInfo) (*TokenPair, error) {
+	if user.IsDeleted() {
+		return nil, ErrAccountDeleted
+	}
+
+	sessionID, err := s.startSession(user.ID, client)
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, err := s.generateToken(user, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	plain, err := generateOpaqueToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+
+	_, err = s.tokenRepo.CreateRefreshToken(user.ID, sessionID, hashToken(plain), s.now().Add(s.config.RefreshTokenTTL))
+	if err != nil {
+		return nil, err
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(s.jwtSecret))
+	return &TokenPair{AccessToken: accessToken, RefreshToken: plain}, nil
+}
+
+func (s *AuthService) revokeReusedFamily(token *repository.RefreshToken) {
+	s.logger.Warn("refresh token reuse detected", "user_id", token.UserID, "family_id", token.FamilyID)
+	if err := s.endSession(token.FamilyID); err != nil {
+		s.logger.Error("failed to revoke refresh token family", "family_id", token.FamilyID, "error", err)
+	}
 }
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/XRS0/blog/services/auth-service/internal/repository"
+	"github.com/XRS0/blog/shared/rabbitmq"
+)
+
+// Block stops target from seeing the user's articles and liking them, and ends
+// follows between the two. The other services learn about it from the
+// user.blocked event.
+func (s *AuthService) Block(ctx context.Context, userID, targetID uint64) error {
+	return s.addBlock(ctx, userID, targetID, repository.BlockKindBlock, rabbitmq.EventUserBlocked)
+}
+
+func (s *AuthService) Unblock(ctx context.Context, userID, targetID uint64) error {
+	return s.removeBlock(ctx, userID, targetID, repository.BlockKindBlock, rabbitmq.EventUserUnblocked)
+}
+
+// Mute hides target's articles from the user's lists and feed. Target is not told
+// and can still read and like the user's articles.
+func (s *AuthService) Mute(ctx context.Context, userID, targetID uint64) error {
+	return s.addBlock(ctx, userID, targetID, repository.BlockKindMute, rabbitmq.EventUserMuted)
+}
+
+func (s *AuthService) Unmute(ctx context.Context, userID, targetID uint64) error {
+	return s.removeBlock(ctx, userID, targetID, repository.BlockKindMute, rabbitmq.EventUserUnmuted)
+}
+
+// ListBlocks returns the users blocked (or, with kind mute, muted) by the user.
+func (s *AuthService) ListBlocks(userID uint64, kind string) ([]*repository.UserBlock, error) {
+	return s.blockRepo.List(userID, kind)
+}
+
+func (s *AuthService) addBlock(ctx context.Context, userID, targetID uint64, kind, eventType string) error {
+	if userID == targetID {
+		return fmt.Errorf("cannot %s yourself", kind)
+	}
+	target, err := s.userRepo.GetByID(targetID)
+	if err != nil || target.IsDeleted() {
+		return fmt.Errorf("user not found")
+	}
+
+	if err := s.blockRepo.Add(userID, targetID, kind); err != nil {
+		return err
+	}
+
+	// Published even if the relationship already existed, so a retry after a
+	// failed publish still reaches the other services
+	if err := s.publishBlockEvent(ctx, eventType, userID, targetID); err != nil {
+		return err
+	}
+
+	s.logger.Info("user relationship added", "kind", kind, "user_id", userID, "target_id", targetID)
+	return nil
+}
+
+func (s *AuthService) removeBlock(ctx context.Context, userID, targetID uint64, kind, eventType string) error {
+	removed, err := s.blockRepo.Remove(userID, targetID, kind)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return fmt.Errorf("%s not found", kind)
+	}
+
+	if err := s.publishBlockEvent(ctx, eventType, userID, targetID); err != nil {
+		return err
+	}
+
+	s.logger.Info("user relationship removed", "kind", kind, "user_id", userID, "target_id", targetID)
+	return nil
+}
+
+func (s *AuthService) publishBlockEvent(ctx context.Context, eventType string, blockerID, blockedID uint64) error {
+	event := rabbitmq.Event{
+		Type: eventType,
+		Data: map[string]interface{}{
+			"blocker_id": blockerID,
+			"blocked_id": blockedID,
+		},
+	}
+	if err := s.mq.Publish(ctx, usersExchange, eventType, event); err != nil {
+		return fmt.Errorf("failed to publish %s event: %w", eventType, err)
+	}
+	return nil
+}
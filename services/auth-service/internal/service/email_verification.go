@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/XRS0/blog/services/auth-service/internal/mailer"
+	"github.com/XRS0/blog/services/auth-service/internal/repository"
+)
+
+// VerifyEmail marks the address from a signed verification link as verified.
+// The token is rejected if the user changed their email after it was issued.
+// Access tokens issued before verification keep the old claim until refreshed.
+func (s *AuthService) VerifyEmail(token string) (*repository.User, error) {
+	claims, err := s.parseEmailVerificationToken(token)
+	if err != nil {
+		return nil, fmt.Errorf("invalid or expired verification token")
+	}
+
+	userID, err := subjectUserID(&claims.RegisteredClaims)
+	if err != nil {
+		return nil, fmt.Errorf("invalid or expired verification token")
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Email != claims.Email {
+		return nil, fmt.Errorf("invalid or expired verification token")
+	}
+	if !user.EmailVerifiedAt.IsZero() {
+		return user, nil
+	}
+
+	user, err = s.userRepo.MarkEmailVerified(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("email verified", "user_id", userID)
+	return user, nil
+}
+
+// ResendVerificationEmail sends a fresh verification link to an unverified user.
+func (s *AuthService) ResendVerificationEmail(ctx context.Context, userID uint64) error {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	if !user.EmailVerifiedAt.IsZero() {
+		return fmt.Errorf("email already verified")
+	}
+
+	return s.sendVerificationEmail(ctx, user)
+}
+
+func (s *AuthService) sendVerificationEmail(ctx context.Context, user *repository.User) error {
+	token, err := s.generateEmailVerificationToken(user)
+	if err != nil {
+		return fmt.Errorf("failed to generate verification token: %w", err)
+	}
+
+	link := fmt.Sprintf("%s/verify-email?token=%s", s.config.AppBaseURL, url.QueryEscape(token))
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Confirm your email",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nPlease confirm your email address by opening the link below:\n\n%s\n\n"+
+				"The link expires in %s.\n",
+			user.Username, link, s.config.EmailVerificationTTL,
+		),
+	})
+}
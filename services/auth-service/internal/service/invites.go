@@ -0,0 +1,161 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/XRS0/blog/services/auth-service/internal/repository"
+)
+
+// Registration modes. In invite-only mode Register requires an invite code; in
+// closed mode nobody can register. AdminEmails may always register, so that a
+// new instance can be set up.
+const (
+	RegistrationOpen       = "open"
+	RegistrationInviteOnly = "invite"
+	RegistrationClosed     = "closed"
+)
+
+// ValidRegistrationMode reports whether mode is one of the registration modes.
+func ValidRegistrationMode(mode string) bool {
+	switch mode {
+	case RegistrationOpen, RegistrationInviteOnly, RegistrationClosed:
+		return true
+	}
+	return false
+}
+
+// inviteCodePrefix marks invite codes so they are not mistaken for other tokens.
+const inviteCodePrefix = "blog_inv_"
+
+// Limits for invite codes created by users without the admin role.
+const (
+	maxUserInviteUses    = 5
+	maxUserInviteTTL     = 30 * 24 * time.Hour
+	defaultUserInviteTTL = 7 * 24 * time.Hour
+	maxUserActiveInvites = 10
+)
+
+// maxInviteUses caps any single invite code, including those created by admins.
+const maxInviteUses = 1000
+
+// CreateInvite issues an invite code usable maxUses times and returns it in plain
+// text together with its record. The plain text is never stored and cannot be
+// shown again. A zero ttl means no expiry for admins and the default for others.
+func (s *AuthService) CreateInvite(userID uint64, maxUses int, ttl time.Duration) (string, *repository.InviteCode, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil || user.IsDeleted() {
+		return "", nil, fmt.Errorf("user not found")
+	}
+
+	if maxUses == 0 {
+		maxUses = 1
+	}
+	if maxUses < 0 || maxUses > maxInviteUses {
+		return "", nil, fmt.Errorf("invalid number of uses")
+	}
+	if ttl < 0 {
+		return "", nil, fmt.Errorf("invalid expiration")
+	}
+
+	if !user.HasRole(repository.RoleAdmin) {
+		if maxUses > maxUserInviteUses {
+			return "", nil, fmt.Errorf("invite codes can have at most %d uses", maxUserInviteUses)
+		}
+		if ttl == 0 {
+			ttl = defaultUserInviteTTL
+		}
+		if ttl > maxUserInviteTTL {
+			return "", nil, fmt.Errorf("invite codes can be valid for at most %d days", int(maxUserInviteTTL/(24*time.Hour)))
+		}
+
+		active, err := s.inviteRepo.CountActiveByCreator(userID, s.now())
+		if err != nil {
+			return "", nil, err
+		}
+		if active >= maxUserActiveInvites {
+			return "", nil, fmt.Errorf("too many invite codes")
+		}
+	}
+
+	secret, err := generateOpaqueToken()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to generate invite code: %w", err)
+	}
+	plain := inviteCodePrefix + secret
+
+	invite := &repository.InviteCode{
+		CreatedBy: userID,
+		Prefix:    plain[:len(inviteCodePrefix)+4],
+		CodeHash:  hashToken(plain),
+		MaxUses:   maxUses,
+	}
+	if ttl > 0 {
+		invite.ExpiresAt = s.now().Add(ttl)
+	}
+
+	if err := s.inviteRepo.Create(invite); err != nil {
+		return "", nil, err
+	}
+
+	s.logger.Info("invite code created", "user_id", userID, "invite_id", invite.ID, "max_uses", maxUses)
+	return plain, invite, nil
+}
+
+func (s *AuthService) ListInvites(userID uint64) ([]*repository.InviteCode, error) {
+	return s.inviteRepo.ListByCreator(userID)
+}
+
+func (s *AuthService) RevokeInvite(userID, inviteID uint64) error {
+	revoked, err := s.inviteRepo.Revoke(userID, inviteID)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return fmt.Errorf("invite not found")
+	}
+
+	s.logger.Info("invite code revoked", "user_id", userID, "invite_id", inviteID)
+	return nil
+}
+
+// createRegisteredUser stores a user signing up with email and password,
+// redeeming the invite code if the registration mode requires one.
+func (s *AuthService) createRegisteredUser(user *repository.User, inviteCode string) error {
+	inviteCode = strings.TrimSpace(inviteCode)
+
+	switch {
+	case s.config.RegistrationMode == RegistrationClosed && !s.isAdminEmail(user.Email):
+		return fmt.Errorf("registration is closed")
+
+	case s.config.RegistrationMode == RegistrationInviteOnly && !s.isAdminEmail(user.Email):
+		if inviteCode == "" {
+			return fmt.Errorf("invite code required")
+		}
+		if err := s.inviteRepo.CreateUserWithInvite(user, hashToken(inviteCode), s.now()); err != nil {
+			return err
+		}
+		s.logger.Info("invite code used", "invite_id", user.InviteCodeID, "invited_by", user.InvitedBy, "user_id", user.ID)
+		return nil
+	}
+
+	created, err := s.userRepo.Create(user.Email, user.Username, user.Password, user.Roles)
+	if err != nil {
+		return err
+	}
+	*user = *created
+	return nil
+}
+
+// checkSignupAllowed refuses new accounts created through an OpenID provider
+// unless registration is open, since those carry no invite code.
+func (s *AuthService) checkSignupAllowed(email string) error {
+	if s.config.RegistrationMode == RegistrationOpen || s.isAdminEmail(email) {
+		return nil
+	}
+	if s.config.RegistrationMode == RegistrationInviteOnly {
+		return fmt.Errorf("registration requires an invite code")
+	}
+	return fmt.Errorf("registration is closed")
+}
@@ -0,0 +1,215 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"fmt"
+	"strings"
+
+	"github.com/XRS0/blog/services/auth-service/internal/repository"
+	"github.com/XRS0/blog/services/auth-service/internal/totp"
+	"github.com/XRS0/blog/shared/rabbitmq"
+)
+
+const (
+	// totpSkew accepts codes from one step before and after the current one
+	// to tolerate clock drift on the user's device.
+	totpSkew = 1
+	// recoveryCodeCount is how many recovery codes are issued on enrollment.
+	recoveryCodeCount = 10
+)
+
+// EnrollTOTP starts two-factor enrollment and returns the secret and the otpauth
+// URI for the authenticator app. The factor is not enforced until ConfirmTOTP.
+func (s *AuthService) EnrollTOTP(userID uint64) (string, string, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return "", "", fmt.Errorf("user not found")
+	}
+
+	factor, err := s.mfaRepo.GetTOTPFactor(userID)
+	if err != nil {
+		return "", "", err
+	}
+	if factor != nil && !factor.EnabledAt.IsZero() {
+		return "", "", fmt.Errorf("two-factor authentication already enabled")
+	}
+
+	secret, err := totp.NewSecret()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate secret: %w", err)
+	}
+
+	if err := s.mfaRepo.SavePendingTOTPFactor(userID, secret); err != nil {
+		return "", "", err
+	}
+
+	return secret, totp.URI(s.config.TOTPIssuer, user.Email, secret), nil
+}
+
+// ConfirmTOTP enables two-factor authentication once the user proves the
+// authenticator app works, and returns the recovery codes in plain text.
+func (s *AuthService) ConfirmTOTP(userID uint64, code string) ([]string, error) {
+	factor, err := s.mfaRepo.GetTOTPFactor(userID)
+	if err != nil {
+		return nil, err
+	}
+	if factor == nil || !factor.EnabledAt.IsZero() {
+		return nil, fmt.Errorf("no pending two-factor enrollment")
+	}
+
+	step, ok := s.totpStep(factor.Secret, code)
+	if !ok {
+		return nil, fmt.Errorf("invalid code")
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.mfaRepo.EnableTOTPFactor(userID, step, hashes); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("two-factor authentication enabled", "user_id", userID)
+	return codes, nil
+}
+
+// DisableTOTP turns two-factor authentication off. It requires the password and
+// a current TOTP or recovery code.
+func (s *AuthService) DisableTOTP(userID uint64, password, code string) error {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return fmt.Errorf("user not found")
+	}
+
+	// Users without a password (created through an OpenID provider) only need the code
+	if user.HasPassword() {
+		if !s.checkPassword(user, password) {
+			return fmt.Errorf("invalid current password")
+		}
+	}
+
+	factor, err := s.mfaRepo.GetTOTPFactor(userID)
+	if err != nil {
+		return err
+	}
+	if factor == nil || factor.EnabledAt.IsZero() {
+		return fmt.Errorf("two-factor authentication not enabled")
+	}
+
+	ok, err := s.verifySecondFactor(factor, code)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("invalid code")
+	}
+
+	if err := s.mfaRepo.DeleteTOTP(userID); err != nil {
+		return err
+	}
+
+	s.logger.Info("two-factor authentication disabled", "user_id", userID)
+	return nil
+}
+
+// VerifyMFA completes a login started by Login with a TOTP or recovery code.
+// Wrong codes count as failed logins for the account and the client address.
+func (s *AuthService) VerifyMFA(mfaToken, code string, client ClientInfo) (*repository.User, *TokenPair, error) {
+	claims, err := s.parseMFAToken(mfaToken)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid or expired challenge")
+	}
+
+	userID, err := subjectUserID(claims)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid or expired challenge")
+	}
+
+	account := accountKey(user.Email)
+	if err := s.checkLoginLock(account, client.IP); err != nil {
+		s.auditLoginFailure(user.ID, user.Email, loginFailureLocked, client)
+		return nil, nil, err
+	}
+
+	factor, err := s.mfaRepo.GetTOTPFactor(userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if factor == nil || factor.EnabledAt.IsZero() {
+		return nil, nil, fmt.Errorf("invalid or expired challenge")
+	}
+
+	ok, err := s.verifySecondFactor(factor, code)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !ok {
+		s.recordLoginFailure(account, client.IP)
+		s.auditLoginFailure(user.ID, user.Email, loginFailureInvalidCode, client)
+		return nil, nil, fmt.Errorf("invalid code")
+	}
+
+	s.resetLoginFailures(account)
+
+	tokens, err := s.issueTokens(user, client)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s.audit(rabbitmq.EventAuthLoginSucceeded, user.ID, client, map[string]interface{}{"mfa": true})
+	s.logger.Info("user logged in", "user_id", user.ID, "email", user.Email, "mfa", true)
+	return user, tokens, nil
+}
+
+// totpStep returns the time step a TOTP code is valid for at the current time.
+func (s *AuthService) totpStep(secret, code string) (int64, bool) {
+	return totp.Validate(secret, code, s.now(), totpSkew)
+}
+
+// verifySecondFactor accepts an unused TOTP code or an unused recovery code.
+func (s *AuthService) verifySecondFactor(factor *repository.TOTPFactor, code string) (bool, error) {
+	if step, ok := s.totpStep(factor.Secret, code); ok {
+		return s.mfaRepo.UseTOTPStep(factor.UserID, step)
+	}
+
+	used, err := s.mfaRepo.UseRecoveryCode(factor.UserID, hashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		return false, err
+	}
+	if used {
+		s.logger.Info("recovery code used", "user_id", factor.UserID)
+	}
+	return used, nil
+}
+
+// generateRecoveryCodes returns codes formatted as "xxxxx-xxxxx" and their hashes.
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+
+	for i := range codes {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+		raw := strings.ToLower(encoding.EncodeToString(b))[:10]
+		codes[i] = raw[:5] + "-" + raw[5:]
+		hashes[i] = hashToken(raw)
+	}
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/XRS0/blog/services/auth-service/internal/keys"
+	"github.com/XRS0/blog/services/auth-service/internal/repository"
+	"github.com/XRS0/blog/services/auth-service/internal/totp"
+)
+
+// clock is a settable Config.Clock.
+type clock struct{ now time.Time }
+
+func (c *clock) Now() time.Time { return c.now }
+
+func newClockedService(t *testing.T, c *clock) *AuthService {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	keyring, err := keys.NewEphemeral(keys.AlgorithmEdDSA, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := Config{
+		MFAChallengeTTL: 5 * time.Minute,
+		Clock:           c.Now,
+	}
+	return NewAuthService(nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, keyring, nil, nil, config, logger)
+}
+
+func TestTOTPStepFollowsClock(t *testing.T) {
+	c := &clock{now: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)}
+	s := newClockedService(t, c)
+
+	secret, err := totp.NewSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	issued := totp.Step(c.now)
+	code, err := totp.Code(secret, issued)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		after time.Duration
+		valid bool
+	}{
+		{"same step", 0, true},
+		{"next step", totp.Period, true},
+		{"two steps later", 2 * totp.Period, false},
+		{"previous step", -totp.Period, true},
+		{"two steps earlier", -2 * totp.Period, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c.now = time.Unix(issued*int64(totp.Period.Seconds()), 0).Add(tt.after)
+			step, ok := s.totpStep(secret, code)
+			if ok != tt.valid {
+				t.Fatalf("valid = %v, want %v", ok, tt.valid)
+			}
+			if ok && step != issued {
+				t.Errorf("step = %d, want %d", step, issued)
+			}
+		})
+	}
+}
+
+func TestMFAChallengeExpiresWithClock(t *testing.T) {
+	c := &clock{now: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)}
+	s := newClockedService(t, c)
+
+	token, err := s.generateMFAToken(&repository.User{ID: 42})
+	if err != nil {
+		t.Fatal(err)
+	}
+	issued := c.now
+
+	c.now = issued.Add(4 * time.Minute)
+	claims, err := s.parseMFAToken(token)
+	if err != nil {
+		t.Fatalf("challenge rejected before expiry: %v", err)
+	}
+	if claims.Subject != "42" {
+		t.Errorf("subject = %q, want 42", claims.Subject)
+	}
+
+	c.now = issued.Add(6 * time.Minute)
+	if _, err := s.parseMFAToken(token); err == nil {
+		t.Error("challenge accepted after expiry")
+	}
+}
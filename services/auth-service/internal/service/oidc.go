@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/XRS0/blog/services/auth-service/internal/oidc"
+	"github.com/XRS0/blog/services/auth-service/internal/repository"
+	"github.com/XRS0/blog/shared/rabbitmq"
+)
+
+// OIDCProviderNames lists the configured OpenID providers.
+func (s *AuthService) OIDCProviderNames() []string {
+	names := make([]string, 0, len(s.oidcProviders))
+	for name := range s.oidcProviders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// StartOIDCLogin creates a pending login and returns the provider authorization URL.
+// The PKCE verifier and nonce never leave the server; the state identifies them.
+func (s *AuthService) StartOIDCLogin(ctx context.Context, providerName string) (string, error) {
+	provider, ok := s.oidcProviders[providerName]
+	if !ok {
+		return "", fmt.Errorf("unknown provider")
+	}
+
+	state, err := generateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	verifier, err := generateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	nonce, err := generateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.identityRepo.CreateLoginState(&repository.OIDCLoginState{
+		StateHash:    hashToken(state),
+		Provider:     providerName,
+		CodeVerifier: verifier,
+		Nonce:        nonce,
+		ExpiresAt:    s.now().Add(s.config.OIDCStateTTL),
+	}); err != nil {
+		return "", err
+	}
+
+	return provider.AuthCodeURL(ctx, state, nonce, verifier)
+}
+
+// CompleteOIDCLogin handles the provider callback. The user is found by the linked
+// identity, then by verified email (linking the identity), or created without a password.
+func (s *AuthService) CompleteOIDCLogin(ctx context.Context, providerName, code, state string, client ClientInfo) (*LoginResult, error) {
+	provider, ok := s.oidcProviders[providerName]
+	if !ok {
+		return nil, fmt.Errorf("unknown provider")
+	}
+
+	pending, err := s.identityRepo.ConsumeLoginState(hashToken(state), s.now())
+	if err != nil || pending.Provider != providerName {
+		return nil, fmt.Errorf("invalid or expired login state")
+	}
+
+	claims, err := provider.Exchange(ctx, code, pending.CodeVerifier, pending.Nonce)
+	if err != nil {
+		s.logger.Error("oidc exchange failed", "provider", providerName, "error", err)
+		s.audit(rabbitmq.EventAuthLoginFailed, 0, client, map[string]interface{}{
+			"method":   "oidc",
+			"provider": providerName,
+			"reason":   loginFailureProviderError,
+		})
+		return nil, fmt.Errorf("sign-in with %s failed", providerName)
+	}
+
+	user, err := s.findOrCreateOIDCUser(providerName, claims, client)
+	if err != nil {
+		return nil, err
+	}
+
+	factor, err := s.mfaRepo.GetTOTPFactor(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if factor != nil && !factor.EnabledAt.IsZero() {
+		mfaToken, err := s.generateMFAToken(user)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate token: %w", err)
+		}
+		return &LoginResult{User: user, MFAToken: mfaToken}, nil
+	}
+
+	tokens, err := s.issueTokens(user, client)
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit(rabbitmq.EventAuthLoginSucceeded, user.ID, client, map[string]interface{}{"method": "oidc", "provider": providerName})
+	s.logger.Info("user logged in", "user_id", user.ID, "provider", providerName)
+	return &LoginResult{User: user, Tokens: tokens}, nil
+}
+
+func (s *AuthService) findOrCreateOIDCUser(providerName string, claims *oidc.Claims, client ClientInfo) (*repository.User, error) {
+	identity, err := s.identityRepo.GetByProviderSubject(providerName, claims.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if identity != nil {
+		return s.userRepo.GetByID(identity.UserID)
+	}
+
+	// Linking by email is only safe if the provider vouches for the address
+	if claims.Email == "" || !claims.EmailVerified {
+		return nil, fmt.Errorf("%s did not provide a verified email", providerName)
+	}
+
+	existing, err := s.userRepo.GetByEmail(claims.Email)
+	if err == nil {
+		// Anyone can register an unverified address, so its owner may not be the
+		// one who chose the password. Linking would hand them the account.
+		if existing.EmailVerifiedAt.IsZero() {
+			return nil, fmt.Errorf("an account with this email exists but its email is not verified")
+		}
+		if err := s.identityRepo.Create(existing.ID, providerName, claims.Subject, claims.Email); err != nil {
+			return nil, err
+		}
+		s.logger.Info("identity linked", "user_id", existing.ID, "provider", providerName)
+		return existing, nil
+	}
+
+	if err := s.checkSignupAllowed(claims.Email); err != nil {
+		return nil, err
+	}
+
+	roles := []string{repository.RoleUser}
+	if s.isAdminEmail(claims.Email) {
+		roles = append(roles, repository.RoleAdmin)
+	}
+	username, err := s.availableUsername(oidcUsername(claims))
+	if err != nil {
+		return nil, err
+	}
+	user := &repository.User{
+		Email:           claims.Email,
+		Username:        username,
+		Roles:           roles,
+		EmailVerifiedAt: s.now(),
+	}
+	if err := s.identityRepo.CreateUserWithIdentity(user, providerName, claims.Subject); err != nil {
+		return nil, err
+	}
+
+	s.audit(rabbitmq.EventAuthRegistered, user.ID, client, map[string]interface{}{
+		"method":   "oidc",
+		"provider": providerName,
+		"email":    user.Email,
+	})
+	s.logger.Info("user registered", "user_id", user.ID, "email", user.Email, "provider", providerName)
+	return user, nil
+}
+
+// oidcUsername picks a display name from the ID token claims.
+func oidcUsername(claims *oidc.Claims) string {
+	for _, candidate := range []string{claims.PreferredUsername, claims.Name} {
+		if candidate = strings.TrimSpace(candidate); candidate != "" {
+			return candidate
+		}
+	}
+	return strings.SplitN(claims.Email, "@", 2)[0]
+}
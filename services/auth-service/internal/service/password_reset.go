@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/XRS0/blog/services/auth-service/internal/mailer"
+	"github.com/XRS0/blog/shared/rabbitmq"
+)
+
+// RequestPasswordReset emails a single-use reset link. It returns nil for unknown
+// emails so that callers cannot probe which addresses are registered.
+func (s *AuthService) RequestPasswordReset(ctx context.Context, email string) error {
+	user, err := s.userRepo.GetByEmail(email)
+	if err != nil {
+		s.logger.Info("password reset requested for unknown email")
+		return nil
+	}
+
+	plain, err := generateOpaqueToken()
+	if err != nil {
+		return fmt.Errorf("failed to generate reset token: %w", err)
+	}
+
+	if _, err := s.tokenRepo.CreatePasswordResetToken(user.ID, hashToken(plain), s.now().Add(s.config.PasswordResetTTL)); err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/reset-password?token=%s", s.config.AppBaseURL, url.QueryEscape(plain))
+	msg := mailer.Message{
+		To:      user.Email,
+		Subject: "Password reset",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nSomeone requested a password reset for your account. "+
+				"Open the link below to choose a new password:\n\n%s\n\n"+
+				"The link expires in %s. If you did not request this, ignore this email.\n",
+			user.Username, link, s.config.PasswordResetTTL,
+		),
+	}
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		s.logger.Error("failed to send password reset email", "user_id", user.ID, "error", err)
+		return nil
+	}
+
+	s.logger.Info("password reset requested", "user_id", user.ID)
+	return nil
+}
+
+// ResetPassword consumes a reset token, sets the new password and ends every
+// session of the user.
+func (s *AuthService) ResetPassword(token, newPassword string, client ClientInfo) error {
+	// Check the policy first, so that a rejected password does not use up the token
+	if err := s.config.PasswordPolicy.Check(newPassword); err != nil {
+		return err
+	}
+
+	userID, err := s.tokenRepo.UsePasswordResetToken(hashToken(token), s.now())
+	if err != nil {
+		return fmt.Errorf("invalid or expired reset token")
+	}
+
+	hashedPassword, err := s.hashNewPassword(newPassword)
+	if err != nil {
+		return err
+	}
+
+	if err := s.userRepo.UpdatePassword(userID, hashedPassword); err != nil {
+		return err
+	}
+
+	if err := s.endAllSessions(userID); err != nil {
+		return err
+	}
+
+	s.audit(rabbitmq.EventAuthPasswordChanged, userID, client, map[string]interface{}{"method": "reset"})
+	s.logger.Info("password reset", "user_id", userID)
+	return nil
+}
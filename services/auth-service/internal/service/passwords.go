@@ -0,0 +1,52 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/XRS0/blog/services/auth-service/internal/repository"
+)
+
+// hashNewPassword checks a password the user is choosing against the password
+// policy and hashes it.
+func (s *AuthService) hashNewPassword(password string) (string, error) {
+	if err := s.config.PasswordPolicy.Check(password); err != nil {
+		return "", err
+	}
+
+	hash, err := s.config.PasswordHasher.Hash(password)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return hash, nil
+}
+
+// checkPassword reports whether password is the user's password.
+func (s *AuthService) checkPassword(user *repository.User, password string) bool {
+	ok, err := s.config.PasswordHasher.Verify(password, user.Password)
+	if err != nil {
+		s.logger.Error("failed to verify password", "user_id", user.ID, "error", err)
+		return false
+	}
+	return ok
+}
+
+// upgradePasswordHash rehashes a just verified password if its stored hash uses
+// an outdated algorithm or parameters. Failures only delay the upgrade.
+func (s *AuthService) upgradePasswordHash(user *repository.User, password string) {
+	if !s.config.PasswordHasher.NeedsRehash(user.Password) {
+		return
+	}
+
+	hash, err := s.config.PasswordHasher.Hash(password)
+	if err != nil {
+		s.logger.Error("failed to rehash password", "user_id", user.ID, "error", err)
+		return
+	}
+	if err := s.userRepo.ReplacePasswordHash(user.ID, user.Password, hash); err != nil {
+		s.logger.Error("failed to store rehashed password", "user_id", user.ID, "error", err)
+		return
+	}
+
+	user.Password = hash
+	s.logger.Info("password hash upgraded", "user_id", user.ID)
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/XRS0/blog/services/auth-service/internal/repository"
+)
+
+// personalAccessTokenPrefix marks personal access tokens so they can be told
+// apart from JWTs and spotted by secret scanners.
+const personalAccessTokenPrefix = "blog_pat_"
+
+// maxPersonalAccessTokens limits how many active tokens a user may hold.
+const maxPersonalAccessTokens = 50
+
+// Scopes a personal access token can be granted. Session JWTs are not scoped.
+const (
+	ScopeArticlesRead  = "articles:read"
+	ScopeArticlesWrite = "articles:write"
+	ScopeStatsRead     = "stats:read"
+	ScopeProfileRead   = "profile:read"
+)
+
+var knownScopes = map[string]bool{
+	ScopeArticlesRead:  true,
+	ScopeArticlesWrite: true,
+	ScopeStatsRead:     true,
+	ScopeProfileRead:   true,
+}
+
+// CreatePersonalAccessToken issues a new token and returns it in plain text
+// together with its record. The plain text is never stored and cannot be shown again.
+// A zero ttl creates a token that does not expire.
+func (s *AuthService) CreatePersonalAccessToken(userID uint64, name string, scopes []string, ttl time.Duration) (string, *repository.PersonalAccessToken, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", nil, fmt.Errorf("token name is required")
+	}
+
+	normalized, err := normalizeScopes(scopes)
+	if err != nil {
+		return "", nil, err
+	}
+
+	active, err := s.patRepo.CountActiveByUser(userID, s.now())
+	if err != nil {
+		return "", nil, err
+	}
+	if active >= maxPersonalAccessTokens {
+		return "", nil, fmt.Errorf("too many personal access tokens")
+	}
+
+	secret, err := generateOpaqueToken()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+	plain := personalAccessTokenPrefix + secret
+
+	token := &repository.PersonalAccessToken{
+		UserID:    userID,
+		Name:      name,
+		Prefix:    plain[:len(personalAccessTokenPrefix)+4],
+		TokenHash: hashToken(plain),
+		Scopes:    normalized,
+	}
+	if ttl > 0 {
+		token.ExpiresAt = s.now().Add(ttl)
+	}
+
+	if err := s.patRepo.Create(token); err != nil {
+		return "", nil, err
+	}
+
+	s.logger.Info("personal access token created", "user_id", userID, "token_id", token.ID, "scopes", normalized)
+	return plain, token, nil
+}
+
+func (s *AuthService) ListPersonalAccessTokens(userID uint64) ([]*repository.PersonalAccessToken, error) {
+	return s.patRepo.ListByUser(userID)
+}
+
+func (s *AuthService) RevokePersonalAccessToken(userID, tokenID uint64) error {
+	revoked, err := s.patRepo.Revoke(userID, tokenID)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return fmt.Errorf("token not found")
+	}
+
+	s.logger.Info("personal access token revoked", "user_id", userID, "token_id", tokenID)
+	return nil
+}
+
+func isPersonalAccessToken(token string) bool {
+	return strings.HasPrefix(token, personalAccessTokenPrefix)
+}
+
+func (s *AuthService) validatePersonalAccessToken(token string) (*Identity, error) {
+	stored, err := s.patRepo.GetActiveByHash(hashToken(token), s.now())
+	if err != nil {
+		return nil, fmt.Errorf("invalid token")
+	}
+
+	// Tokens of a deleted account stop working but come back if it is restored
+	user, err := s.userRepo.GetByID(stored.UserID)
+	if err != nil || user.IsDeleted() {
+		return nil, fmt.Errorf("invalid token")
+	}
+
+	if err := s.patRepo.TouchLastUsed(stored.ID, s.now()); err != nil {
+		s.logger.Error("failed to record token use", "token_id", stored.ID, "error", err)
+	}
+
+	return &Identity{
+		UserID:              user.ID,
+		EmailVerified:       !user.EmailVerifiedAt.IsZero(),
+		Roles:               user.Roles,
+		Scopes:              stored.Scopes,
+		PersonalAccessToken: true,
+	}, nil
+}
+
+func normalizeScopes(scopes []string) ([]string, error) {
+	var normalized []string
+	for _, scope := range scopes {
+		scope = strings.ToLower(strings.TrimSpace(scope))
+		if !knownScopes[scope] {
+			return nil, fmt.Errorf("unknown scope %q", scope)
+		}
+		if !contains(normalized, scope) {
+			normalized = append(normalized, scope)
+		}
+	}
+
+	if len(normalized) == 0 {
+		return nil, fmt.Errorf("at least one scope is required")
+	}
+	return normalized, nil
+}
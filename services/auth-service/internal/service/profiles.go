@@ -0,0 +1,146 @@
+package service
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/XRS0/blog/services/auth-service/internal/repository"
+)
+
+// Usernames are public handles used in profile URLs. They are stored as typed
+// but compared without regard to case.
+const (
+	minUsernameLength  = 3
+	maxUsernameLength  = 32
+	maxBioLength       = 500
+	maxAvatarURLLength = 2048
+)
+
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// deletedUsernamePrefix is reserved for accounts anonymized by Purge.
+const deletedUsernamePrefix = "deleted-"
+
+// ProfileUpdate lists the profile fields to change. Empty Username or Email and
+// nil Bio or AvatarURL keep the current value.
+type ProfileUpdate struct {
+	Username        string
+	Email           string
+	CurrentPassword string
+	Bio             *string
+	AvatarURL       *string
+}
+
+// GetUserByUsername returns the user with the handle, ignoring case. Deleted
+// accounts are not found.
+func (s *AuthService) GetUserByUsername(username string) (*repository.User, error) {
+	user, err := s.userRepo.GetByUsername(strings.TrimSpace(username))
+	if err != nil || user.IsDeleted() {
+		return nil, fmt.Errorf("user not found")
+	}
+	return user, nil
+}
+
+// checkUsernameAvailable validates a new handle and makes sure no other account uses it.
+func (s *AuthService) checkUsernameAvailable(username string) error {
+	if err := validateUsername(username); err != nil {
+		return err
+	}
+
+	exists, err := s.userRepo.UsernameExists(username)
+	if err != nil {
+		return fmt.Errorf("failed to check username: %w", err)
+	}
+	if exists {
+		return fmt.Errorf("username already taken")
+	}
+	return nil
+}
+
+func validateUsername(username string) error {
+	if len(username) < minUsernameLength || len(username) > maxUsernameLength || !usernamePattern.MatchString(username) {
+		return fmt.Errorf("username must be %d-%d characters of letters, digits, '.', '_' or '-'", minUsernameLength, maxUsernameLength)
+	}
+	if strings.HasPrefix(strings.ToLower(username), deletedUsernamePrefix) {
+		return fmt.Errorf("username is reserved")
+	}
+	return nil
+}
+
+func validateBio(bio string) error {
+	if utf8.RuneCountInString(bio) > maxBioLength {
+		return fmt.Errorf("bio must be at most %d characters", maxBioLength)
+	}
+	return nil
+}
+
+// validateAvatarURL accepts an absolute http(s) URL, or an empty string to remove the avatar.
+func validateAvatarURL(avatarURL string) error {
+	if avatarURL == "" {
+		return nil
+	}
+
+	parsed, err := url.Parse(avatarURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" || len(avatarURL) > maxAvatarURLLength {
+		return fmt.Errorf("avatar_url must be an http or https URL")
+	}
+	return nil
+}
+
+// availableUsername turns a display name into a valid handle that is not taken
+// yet, appending a number if needed.
+func (s *AuthService) availableUsername(name string) (string, error) {
+	base := sanitizeUsername(name)
+	if validateUsername(base) != nil {
+		base = "user"
+	}
+
+	candidate := base
+	for n := 2; n <= 100; n++ {
+		exists, err := s.userRepo.UsernameExists(candidate)
+		if err != nil {
+			return "", fmt.Errorf("failed to check username: %w", err)
+		}
+		if !exists {
+			return candidate, nil
+		}
+
+		suffix := fmt.Sprintf("-%d", n)
+		candidate = base
+		if len(candidate)+len(suffix) > maxUsernameLength {
+			candidate = candidate[:maxUsernameLength-len(suffix)]
+		}
+		candidate += suffix
+	}
+
+	random, err := generateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	return "user-" + hashToken(random)[:8], nil
+}
+
+// sanitizeUsername keeps the characters allowed in a handle and turns spaces into underscores.
+func sanitizeUsername(name string) string {
+	var b strings.Builder
+	for _, r := range strings.TrimSpace(name) {
+		switch {
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			b.WriteRune(r)
+		case r == '_' || r == '.' || r == '-':
+			b.WriteRune(r)
+		case unicode.IsSpace(r):
+			b.WriteRune('_')
+		}
+	}
+
+	handle := strings.TrimLeft(b.String(), "_.-")
+	if len(handle) > maxUsernameLength {
+		handle = handle[:maxUsernameLength]
+	}
+	return handle
+}
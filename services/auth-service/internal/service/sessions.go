@@ -0,0 +1,155 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/XRS0/blog/services/auth-service/internal/repository"
+)
+
+// ClientInfo describes the device a login or refresh comes from.
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
+
+// ListSessions returns the active sessions of a user.
+func (s *AuthService) ListSessions(userID uint64) ([]*repository.Session, error) {
+	return s.sessionRepo.ListActive(userID, s.now())
+}
+
+// RevokeSession ends one of the user's sessions. Access tokens of the session
+// stop working immediately on this instance and within SessionCacheTTL elsewhere.
+func (s *AuthService) RevokeSession(userID uint64, sessionID string) error {
+	revoked, err := s.sessionRepo.Revoke(userID, sessionID)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return fmt.Errorf("session not found")
+	}
+
+	s.sessionCache.set(sessionID, false, s.now())
+	if err := s.tokenRepo.RevokeFamily(sessionID); err != nil {
+		return err
+	}
+
+	s.logger.Info("session revoked", "user_id", userID, "session_id", sessionID)
+	return nil
+}
+
+// startSession records a new session for the user and returns its ID.
+func (s *AuthService) startSession(userID uint64, client ClientInfo) (string, error) {
+	id, err := generateOpaqueToken()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate session id: %w", err)
+	}
+
+	now := s.now()
+	if err := s.sessionRepo.Create(&repository.Session{
+		ID:         id,
+		UserID:     userID,
+		UserAgent:  client.UserAgent,
+		IP:         client.IP,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(s.config.RefreshTokenTTL),
+	}); err != nil {
+		return "", err
+	}
+
+	return id, nil
+}
+
+// endSession revokes a session and its refresh tokens.
+func (s *AuthService) endSession(sessionID string) error {
+	if _, err := s.sessionRepo.Revoke(0, sessionID); err != nil {
+		return err
+	}
+	s.sessionCache.set(sessionID, false, s.now())
+	return s.tokenRepo.RevokeFamily(sessionID)
+}
+
+// endAllSessions revokes every session and refresh token of a user.
+func (s *AuthService) endAllSessions(userID uint64) error {
+	ids, err := s.sessionRepo.RevokeAllForUser(userID)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		s.sessionCache.set(id, false, s.now())
+	}
+	return s.tokenRepo.RevokeAllForUser(userID)
+}
+
+// sessionActive reports whether an access token's session is still active. The
+// answer is cached, so most requests do not reach the database; a cache miss also
+// records the session as seen.
+func (s *AuthService) sessionActive(sessionID string, userID uint64) (bool, error) {
+	now := s.now()
+	if active, ok := s.sessionCache.get(sessionID, now); ok {
+		return active, nil
+	}
+
+	session, err := s.sessionRepo.Seen(sessionID, "", "", now, time.Time{})
+	if errors.Is(err, sql.ErrNoRows) {
+		s.sessionCache.set(sessionID, false, now)
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	active := session.UserID == userID && session.RevokedAt.IsZero()
+	s.sessionCache.set(sessionID, active, now)
+	return active, nil
+}
+
+// sessionCache remembers whether sessions are active for a short time.
+// Revocations made by this instance are applied to it directly.
+type sessionCache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[string]sessionCacheEntry
+}
+
+type sessionCacheEntry struct {
+	active    bool
+	checkedAt time.Time
+}
+
+func newSessionCache(ttl time.Duration) *sessionCache {
+	return &sessionCache{ttl: ttl, entries: make(map[string]sessionCacheEntry)}
+}
+
+func (c *sessionCache) get(id string, now time.Time) (bool, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[id]
+	if !ok || now.Sub(entry.checkedAt) >= c.ttl {
+		return false, false
+	}
+	return entry.active, true
+}
+
+func (c *sessionCache) set(id string, active bool, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[id] = sessionCacheEntry{active: active, checkedAt: now}
+}
+
+// prune drops entries older than the TTL.
+func (c *sessionCache) prune(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for id, entry := range c.entries {
+		if now.Sub(entry.checkedAt) >= c.ttl {
+			delete(c.entries, id)
+		}
+	}
+}
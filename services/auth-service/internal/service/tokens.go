@@ -0,0 +1,178 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/XRS0/blog/services/auth-service/internal/repository"
+	"github.com/XRS0/blog/shared/jwks"
+)
+
+// Audiences keep the different kinds of signed tokens from being used in place of each other.
+const (
+	audienceAccess            = "blog:access"
+	audienceEmailVerification = "blog:email-verification"
+	audienceMFA               = "blog:mfa"
+	audienceAccountRestore    = "blog:account-restore"
+)
+
+type accessClaims struct {
+	jwt.RegisteredClaims
+	EmailVerified bool     `json:"email_verified"`
+	Roles         []string `json:"roles,omitempty"`
+	SessionID     string   `json:"sid,omitempty"`
+}
+
+// accountRestoreClaims name the deletion a restore link undoes, so that a link
+// from an earlier deletion cannot undo a later one.
+type accountRestoreClaims struct {
+	jwt.RegisteredClaims
+	DeletedAt int64 `json:"deleted_at"` // Unix microseconds, the precision of the database
+}
+
+type emailVerificationClaims struct {
+	jwt.RegisteredClaims
+	Email string `json:"email"`
+}
+
+func (s *AuthService) generateToken(user *repository.User, sessionID string) (string, error) {
+	jti, err := generateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	claims := accessClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			Subject:   fmt.Sprintf("%d", user.ID),
+			Audience:  jwt.ClaimStrings{audienceAccess},
+			ExpiresAt: jwt.NewNumericDate(s.now().Add(s.config.AccessTokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(s.now()),
+		},
+		EmailVerified: !user.EmailVerifiedAt.IsZero(),
+		Roles:         user.Roles,
+		SessionID:     sessionID,
+	}
+
+	return s.signToken(claims)
+}
+
+func (s *AuthService) parseAccessToken(token string) (*accessClaims, error) {
+	claims := &accessClaims{}
+	if err := s.parseToken(token, audienceAccess, claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func (s *AuthService) generateEmailVerificationToken(user *repository.User) (string, error) {
+	claims := emailVerificationClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   fmt.Sprintf("%d", user.ID),
+			Audience:  jwt.ClaimStrings{audienceEmailVerification},
+			ExpiresAt: jwt.NewNumericDate(s.now().Add(s.config.EmailVerificationTTL)),
+			IssuedAt:  jwt.NewNumericDate(s.now()),
+		},
+		Email: user.Email,
+	}
+
+	return s.signToken(claims)
+}
+
+func (s *AuthService) parseEmailVerificationToken(token string) (*emailVerificationClaims, error) {
+	claims := &emailVerificationClaims{}
+	if err := s.parseToken(token, audienceEmailVerification, claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// generateMFAToken issues the challenge that proves the password step of a login.
+func (s *AuthService) generateMFAToken(user *repository.User) (string, error) {
+	claims := jwt.RegisteredClaims{
+		Subject:   fmt.Sprintf("%d", user.ID),
+		Audience:  jwt.ClaimStrings{audienceMFA},
+		ExpiresAt: jwt.NewNumericDate(s.now().Add(s.config.MFAChallengeTTL)),
+		IssuedAt:  jwt.NewNumericDate(s.now()),
+	}
+
+	return s.signToken(claims)
+}
+
+func (s *AuthService) parseMFAToken(token string) (*jwt.RegisteredClaims, error) {
+	claims := &jwt.RegisteredClaims{}
+	if err := s.parseToken(token, audienceMFA, claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// generateAccountRestoreToken issues the link that undoes an account deletion
+// until the account is purged.
+func (s *AuthService) generateAccountRestoreToken(user *repository.User) (string, error) {
+	claims := accountRestoreClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   fmt.Sprintf("%d", user.ID),
+			Audience:  jwt.ClaimStrings{audienceAccountRestore},
+			ExpiresAt: jwt.NewNumericDate(user.PurgeAt),
+			IssuedAt:  jwt.NewNumericDate(s.now()),
+		},
+		DeletedAt: user.DeletedAt.UnixMicro(),
+	}
+
+	return s.signToken(claims)
+}
+
+func (s *AuthService) parseAccountRestoreToken(token string) (*accountRestoreClaims, error) {
+	claims := &accountRestoreClaims{}
+	if err := s.parseToken(token, audienceAccountRestore, claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func (s *AuthService) signToken(claims jwt.Claims) (string, error) {
+	return s.keyring.Sign(claims)
+}
+
+func (s *AuthService) parseToken(token, audience string, claims jwt.Claims) error {
+	parsedToken, err := jwt.ParseWithClaims(token, claims, s.keyring.Keyfunc,
+		jwt.WithValidMethods(s.keyring.ValidMethods()), jwt.WithAudience(audience), jwt.WithTimeFunc(s.now))
+
+	if err != nil || !parsedToken.Valid {
+		return fmt.Errorf("invalid token")
+	}
+	return nil
+}
+
+// JWKS returns the public keys that verify tokens issued by this service.
+func (s *AuthService) JWKS() (*jwks.Set, error) {
+	return s.keyring.JWKS()
+}
+
+func subjectUserID(claims *jwt.RegisteredClaims) (uint64, error) {
+	var userID uint64
+	if _, err := fmt.Sscanf(claims.Subject, "%d", &userID); err != nil {
+		return 0, fmt.Errorf("invalid token subject")
+	}
+	return userID, nil
+}
+
+// generateOpaqueToken returns 32 random bytes encoded as URL-safe base64.
+func generateOpaqueToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -39,6 +39,8 @@ func main() {
 	models := []interface{}{
 		(*repository.ArticleView)(nil),
 		(*repository.ArticleLike)(nil),
+		(*repository.LikeBlock)(nil),
+		(*repository.ArticleAuthor)(nil),
 	}
 	if err := sharedDB.RunMigrations(ctx, db, models, logger.Logger); err != nil {
 		log.Fatalf("failed to run migrations: %v", err)
@@ -55,7 +57,8 @@ func main() {
 
 	// Initialize repository and service
 	statsRepo := repository.NewStatsRepository(db)
-	statsService := service.NewStatsService(statsRepo, mq, logger.Logger)
+	blockRepo := repository.NewBlockRepository(db)
+	statsService := service.NewStatsService(statsRepo, blockRepo, mq, logger.Logger)
 
 	// Start event consumer
 	if err := statsService.StartEventConsumer(ctx); err != nil {
@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/uptrace/bun"
+)
+
+// LikeBlock is a copy of a block made in auth-service: BlockedID may not like
+// the articles of BlockerID.
+type LikeBlock struct {
+	bun.BaseModel `bun:"table:like_blocks,alias:lb"`
+
+	BlockerID uint64 `bun:"blocker_id,pk"`
+	BlockedID uint64 `bun:"blocked_id,pk"`
+}
+
+// ArticleAuthor maps an article to its author, learned from article events.
+type ArticleAuthor struct {
+	bun.BaseModel `bun:"table:article_authors,alias:aa"`
+
+	ArticleID uint64 `bun:"article_id,pk"`
+	AuthorID  uint64 `bun:"author_id,notnull"`
+}
+
+type BlockRepository struct {
+	db *bun.DB
+}
+
+func NewBlockRepository(db *bun.DB) *BlockRepository {
+	return &BlockRepository{db: db}
+}
+
+func (r *BlockRepository) AddBlock(blockerID, blockedID uint64) error {
+	ctx := context.Background()
+
+	_, err := r.db.NewInsert().
+		Model(&LikeBlock{BlockerID: blockerID, BlockedID: blockedID}).
+		On("CONFLICT DO NOTHING").
+		Exec(ctx)
+
+	if err != nil {
+		return fmt.Errorf("failed to save block: %w", err)
+	}
+	return nil
+}
+
+func (r *BlockRepository) RemoveBlock(blockerID, blockedID uint64) error {
+	ctx := context.Background()
+
+	_, err := r.db.NewDelete().
+		Model((*LikeBlock)(nil)).
+		Where("blocker_id = ? AND blocked_id = ?", blockerID, blockedID).
+		Exec(ctx)
+
+	if err != nil {
+		return fmt.Errorf("failed to remove block: %w", err)
+	}
+	return nil
+}
+
+// SetAuthor records who wrote the article. Articles anonymized after their
+// author was deleted have no author and are skipped.
+func (r *BlockRepository) SetAuthor(articleID, authorID uint64) error {
+	ctx := context.Background()
+
+	if authorID == 0 {
+		return r.RemoveAuthor(articleID)
+	}
+
+	_, err := r.db.NewInsert().
+		Model(&ArticleAuthor{ArticleID: articleID, AuthorID: authorID}).
+		On("CONFLICT (article_id) DO UPDATE").
+		Set("author_id = EXCLUDED.author_id").
+		Exec(ctx)
+
+	if err != nil {
+		return fmt.Errorf("failed to save article author: %w", err)
+	}
+	return nil
+}
+
+func (r *BlockRepository) RemoveAuthor(articleID uint64) error {
+	ctx := context.Background()
+
+	_, err := r.db.NewDelete().
+		Model((*ArticleAuthor)(nil)).
+		Where("article_id = ?", articleID).
+		Exec(ctx)
+
+	if err != nil {
+		return fmt.Errorf("failed to remove article author: %w", err)
+	}
+	return nil
+}
+
+// IsBlockedFromArticle reports whether the author of the article has blocked the
+// user. Articles whose author is not known yet are not blocked.
+func (r *BlockRepository) IsBlockedFromArticle(articleID, userID uint64) (bool, error) {
+	ctx := context.Background()
+
+	exists, err := r.db.NewSelect().
+		Model((*LikeBlock)(nil)).
+		Join("JOIN article_authors AS aa ON aa.author_id = lb.blocker_id").
+		Where("aa.article_id = ? AND lb.blocked_id = ?", articleID, userID).
+		Exists(ctx)
+
+	if err != nil {
+		return false, fmt.Errorf("failed to check block: %w", err)
+	}
+	return exists, nil
+}
+
+// RemoveUser drops every block of and against a deleted user.
+func (r *BlockRepository) RemoveUser(userID uint64) error {
+	ctx := context.Background()
+
+	_, err := r.db.NewDelete().
+		Model((*LikeBlock)(nil)).
+		Where("blocker_id = ? OR blocked_id = ?", userID, userID).
+		Exec(ctx)
+
+	if err != nil {
+		return fmt.Errorf("failed to remove blocks: %w", err)
+	}
+	return nil
+}
@@ -169,3 +169,89 @@ func (r *StatsRepository) GetMultipleArticleStats(articleIDs []uint64, viewerID
 
 	return stats, nil
 }
+
+// DeleteArticleStats removes the views and likes of a deleted article.
+func (r *StatsRepository) DeleteArticleStats(articleID uint64) error {
+	ctx := context.Background()
+
+	err := r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if _, err := tx.NewDelete().
+			Model((*ArticleView)(nil)).
+			Where("article_id = ?", articleID).
+			Exec(ctx); err != nil {
+			return err
+		}
+
+		_, err := tx.NewDelete().
+			Model((*ArticleLike)(nil)).
+			Where("article_id = ?", articleID).
+			Exec(ctx)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete article stats: %w", err)
+	}
+	return nil
+}
+
+// RemoveUser deletes the likes of a deleted user and keeps their views as
+// anonymous ones, so view counts do not change.
+func (r *StatsRepository) RemoveUser(userID uint64) error {
+	ctx := context.Background()
+
+	err := r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if _, err := tx.NewDelete().
+			Model((*ArticleLike)(nil)).
+			Where("user_id = ?", userID).
+			Exec(ctx); err != nil {
+			return err
+		}
+
+		_, err := tx.NewUpdate().
+			Model((*ArticleView)(nil)).
+			Set("user_id = NULL").
+			Where("user_id = ?", userID).
+			Exec(ctx)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to remove user stats: %w", err)
+	}
+	return nil
+}
+
+// ListUserLikes returns up to limit likes given by a user with IDs above afterID.
+func (r *StatsRepository) ListUserLikes(userID, afterID uint64, limit int) ([]*ArticleLike, error) {
+	ctx := context.Background()
+	var likes []*ArticleLike
+
+	err := r.db.NewSelect().
+		Model(&likes).
+		Where("user_id = ? AND id > ?", userID, afterID).
+		Order("id ASC").
+		Limit(limit).
+		Scan(ctx)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to list likes: %w", err)
+	}
+	return likes, nil
+}
+
+// ListUserViews returns up to limit views recorded for a user with IDs above afterID.
+func (r *StatsRepository) ListUserViews(userID, afterID uint64, limit int) ([]*ArticleView, error) {
+	ctx := context.Background()
+	var views []*ArticleView
+
+	err := r.db.NewSelect().
+		Model(&views).
+		Where("user_id = ? AND id > ?", userID, afterID).
+		Order("id ASC").
+		Limit(limit).
+		Scan(ctx)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to list views: %w", err)
+	}
+	return views, nil
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"log/slog"
 
+	"google.golang.org/protobuf/types/known/timestamppb"
+
 	"github.com/XRS0/blog/services/stats-service/internal/service"
 	pb "github.com/XRS0/blog/services/stats-service/proto"
 )
@@ -87,3 +89,47 @@ func (s *StatsServer) GetArticlesWithStats(ctx context.Context, req *pb.GetArtic
 
 	return &pb.GetArticlesWithStatsResponse{Stats: pbStats}, nil
 }
+
+// activityPageLimit clamps the page size of ListUserLikes and ListUserViews.
+func activityPageLimit(limit int32) int {
+	if limit <= 0 || limit > 1000 {
+		return 1000
+	}
+	return int(limit)
+}
+
+func (s *StatsServer) ListUserLikes(ctx context.Context, req *pb.ListUserLikesRequest) (*pb.ListUserLikesResponse, error) {
+	likes, err := s.statsService.ListUserLikes(req.UserId, req.AfterId, activityPageLimit(req.Limit))
+	if err != nil {
+		return &pb.ListUserLikesResponse{Error: err.Error()}, nil
+	}
+
+	pbLikes := make([]*pb.UserActivity, len(likes))
+	for i, like := range likes {
+		pbLikes[i] = &pb.UserActivity{
+			Id:        like.ID,
+			ArticleId: like.ArticleID,
+			CreatedAt: timestamppb.New(like.CreatedAt),
+		}
+	}
+
+	return &pb.ListUserLikesResponse{Likes: pbLikes}, nil
+}
+
+func (s *StatsServer) ListUserViews(ctx context.Context, req *pb.ListUserViewsRequest) (*pb.ListUserViewsResponse, error) {
+	views, err := s.statsService.ListUserViews(req.UserId, req.AfterId, activityPageLimit(req.Limit))
+	if err != nil {
+		return &pb.ListUserViewsResponse{Error: err.Error()}, nil
+	}
+
+	pbViews := make([]*pb.UserActivity, len(views))
+	for i, view := range views {
+		pbViews[i] = &pb.UserActivity{
+			Id:        view.ID,
+			ArticleId: view.ArticleID,
+			CreatedAt: timestamppb.New(view.CreatedAt),
+		}
+	}
+
+	return &pb.ListUserViewsResponse{Views: pbViews}, nil
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 
 	"github.com/XRS0/blog/services/stats-service/internal/repository"
@@ -10,13 +11,15 @@ import (
 
 type StatsService struct {
 	repo   *repository.StatsRepository
+	blocks *repository.BlockRepository
 	mq     *rabbitmq.Client
 	logger *slog.Logger
 }
 
-func NewStatsService(repo *repository.StatsRepository, mq *rabbitmq.Client, logger *slog.Logger) *StatsService {
+func NewStatsService(repo *repository.StatsRepository, blocks *repository.BlockRepository, mq *rabbitmq.Client, logger *slog.Logger) *StatsService {
 	return &StatsService{
 		repo:   repo,
+		blocks: blocks,
 		mq:     mq,
 		logger: logger,
 	}
@@ -33,6 +36,20 @@ func (s *StatsService) StartEventConsumer(ctx context.Context) error {
 		return err
 	}
 
+	// Bind to users exchange
+	if err := s.mq.DeclareExchange("users"); err != nil {
+		return err
+	}
+	for _, key := range []string{
+		rabbitmq.EventUserDeleted,
+		rabbitmq.EventUserBlocked,
+		rabbitmq.EventUserUnblocked,
+	} {
+		if err := s.mq.BindQueue("stats-events", "users", key); err != nil {
+			return err
+		}
+	}
+
 	// Start consuming
 	return s.mq.Consume("stats-events", s.handleEvent)
 }
@@ -41,33 +58,114 @@ func (s *StatsService) handleEvent(event rabbitmq.Event) error {
 	s.logger.Debug("handling event", "type", event.Type)
 
 	switch event.Type {
+	case rabbitmq.EventArticleCreated:
+		articleID, err := event.ID("article_id")
+		if err != nil {
+			return err
+		}
+		authorID, err := event.ID("user_id")
+		if err != nil {
+			return err
+		}
+		return s.blocks.SetAuthor(articleID, authorID)
+
 	case rabbitmq.EventArticleViewed:
 		articleID := uint64(event.Data["article_id"].(float64))
 		userID := uint64(0)
 		if uid, ok := event.Data["user_id"]; ok {
 			userID = uint64(uid.(float64))
 		}
+		// Views also carry the author, which fills in articles created
+		// before authors were tracked
+		if authorID, ok := event.Data["author_id"].(float64); ok {
+			if err := s.blocks.SetAuthor(articleID, uint64(authorID)); err != nil {
+				return err
+			}
+		}
 		return s.repo.RecordView(articleID, userID)
 
 	case rabbitmq.EventArticleLiked:
 		articleID := uint64(event.Data["article_id"].(float64))
 		userID := uint64(event.Data["user_id"].(float64))
+		blocked, err := s.blocks.IsBlockedFromArticle(articleID, userID)
+		if err != nil {
+			return err
+		}
+		if blocked {
+			s.logger.Warn("dropping like from blocked user", "article_id", articleID, "user_id", userID)
+			return nil
+		}
 		return s.repo.RecordLike(articleID, userID)
 
 	case rabbitmq.EventArticleUnliked:
 		articleID := uint64(event.Data["article_id"].(float64))
 		userID := uint64(event.Data["user_id"].(float64))
 		return s.repo.RemoveLike(articleID, userID)
+
+	case rabbitmq.EventArticleDeleted:
+		articleID, err := event.ID("article_id")
+		if err != nil {
+			return err
+		}
+		if err := s.blocks.RemoveAuthor(articleID); err != nil {
+			return err
+		}
+		return s.repo.DeleteArticleStats(articleID)
+
+	case rabbitmq.EventUserDeleted:
+		userID, err := event.ID("user_id")
+		if err != nil {
+			return err
+		}
+		if err := s.blocks.RemoveUser(userID); err != nil {
+			return err
+		}
+		return s.repo.RemoveUser(userID)
+
+	case rabbitmq.EventUserBlocked:
+		blockerID, blockedID, err := blockPair(event)
+		if err != nil {
+			return err
+		}
+		return s.blocks.AddBlock(blockerID, blockedID)
+
+	case rabbitmq.EventUserUnblocked:
+		blockerID, blockedID, err := blockPair(event)
+		if err != nil {
+			return err
+		}
+		return s.blocks.RemoveBlock(blockerID, blockedID)
 	}
 
 	return nil
 }
 
+// blockPair returns the users of a block event.
+func blockPair(event rabbitmq.Event) (uint64, uint64, error) {
+	blockerID, err := event.ID("blocker_id")
+	if err != nil {
+		return 0, 0, err
+	}
+	blockedID, err := event.ID("blocked_id")
+	if err != nil {
+		return 0, 0, err
+	}
+	return blockerID, blockedID, nil
+}
+
 func (s *StatsService) RecordView(articleID, userID uint64) error {
 	return s.repo.RecordView(articleID, userID)
 }
 
+// RecordLike likes the article for the user, unless its author has blocked them.
 func (s *StatsService) RecordLike(articleID, userID uint64) error {
+	blocked, err := s.blocks.IsBlockedFromArticle(articleID, userID)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return fmt.Errorf("blocked by the author")
+	}
 	return s.repo.RecordLike(articleID, userID)
 }
 
@@ -86,3 +184,11 @@ func (s *StatsService) IsLikedByUser(articleID, userID uint64) (bool, error) {
 func (s *StatsService) GetMultipleArticleStats(articleIDs []uint64, viewerID uint64) ([]repository.ArticleStats, error) {
 	return s.repo.GetMultipleArticleStats(articleIDs, viewerID)
 }
+
+func (s *StatsService) ListUserLikes(userID, afterID uint64, limit int) ([]*repository.ArticleLike, error) {
+	return s.repo.ListUserLikes(userID, afterID, limit)
+}
+
+func (s *StatsService) ListUserViews(userID, afterID uint64, limit int) ([]*repository.ArticleView, error) {
+	return s.repo.ListUserViews(userID, afterID, limit)
+}
@@ -3,6 +3,7 @@ package rabbitmq
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"time"
@@ -19,10 +20,26 @@ type Client struct {
 
 // Event types
 const (
-	EventArticleViewed  = "article.viewed"
-	EventArticleLiked   = "article.liked"
-	EventArticleUnliked = "article.unliked"
-	EventArticleCreated = "article.created"
+	EventArticleViewed    = "article.viewed"
+	EventArticleLiked     = "article.liked"
+	EventArticleUnliked   = "article.unliked"
+	EventArticleCreated   = "article.created"
+	EventArticleUpdated   = "article.updated"
+	EventArticlePublished = "article.published"
+	EventArticleDeleted   = "article.deleted"
+
+	EventUserDeleted   = "user.deleted"
+	EventUserBlocked   = "user.blocked"
+	EventUserUnblocked = "user.unblocked"
+	EventUserMuted     = "user.muted"
+	EventUserUnmuted   = "user.unmuted"
+
+	EventAuthRegistered      = "auth.registered"
+	EventAuthLoginSucceeded  = "auth.login.succeeded"
+	EventAuthLoginFailed     = "auth.login.failed"
+	EventAuthTokenRefreshed  = "auth.token.refreshed"
+	EventAuthPasswordChanged = "auth.password.changed"
+	EventAuthRolesChanged    = "auth.roles.changed"
 )
 
 // Event represents a message in the queue
@@ -32,6 +49,20 @@ type Event struct {
 	Data      map[string]interface{} `json:"data"`
 }
 
+// ErrInvalidEvent is returned by handlers for events that can never be handled,
+// such as events missing a field. These events are dropped rather than requeued.
+var ErrInvalidEvent = errors.New("invalid event")
+
+// ID returns the numeric field key of the event data. JSON numbers decode as
+// float64, so IDs arrive as such.
+func (e Event) ID(key string) (uint64, error) {
+	v, ok := e.Data[key].(float64)
+	if !ok {
+		return 0, fmt.Errorf("%w: %s without %s", ErrInvalidEvent, e.Type, key)
+	}
+	return uint64(v), nil
+}
+
 // NewClient creates a new RabbitMQ client
 func NewClient(url string, logger *slog.Logger) (*Client, error) {
 	conn, err := amqp.Dial(url)
@@ -145,7 +176,8 @@ func (c *Client) Consume(queueName string, handler func(Event) error) error {
 
 			if err := handler(event); err != nil {
 				c.logger.Error("failed to handle event", "type", event.Type, "error", err)
-				msg.Nack(false, true) // Requeue
+				// Retry unless the event itself is at fault
+				msg.Nack(false, !errors.Is(err, ErrInvalidEvent))
 				continue
 			}
 